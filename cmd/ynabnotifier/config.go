@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/cache"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/receipts"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/internal/tenant"
+)
+
+// webhookConfig returns webhook config if TELEGRAM_WEBHOOK_URL is set. Long polling is used otherwise.
+func webhookConfig() *telegram.WebhookConfig {
+	publicURL := os.Getenv("TELEGRAM_WEBHOOK_URL")
+	if publicURL == "" {
+		return nil
+	}
+
+	return &telegram.WebhookConfig{
+		Listen:      envOrDefault("TELEGRAM_WEBHOOK_LISTEN", ":8443"),
+		PublicURL:   publicURL,
+		SecretToken: os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+		TLSCert:     os.Getenv("TELEGRAM_WEBHOOK_TLS_CERT"),
+		TLSKey:      os.Getenv("TELEGRAM_WEBHOOK_TLS_KEY"),
+	}
+}
+
+// cacheConfig returns config of YNAB responses cache from YNAB_CACHE_TTL and YNAB_CACHE_STALE durations.
+func cacheConfig() (cache.Config, error) {
+	cfg := cache.Config{TTL: cache.DefaultTTL, Stale: cache.DefaultStale}
+	for env, d := range map[string]*time.Duration{"YNAB_CACHE_TTL": &cfg.TTL, "YNAB_CACHE_STALE": &cfg.Stale} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to parse %s: %w", env, err)
+		}
+		*d = parsed
+	}
+
+	return cfg, nil
+}
+
+// durationEnv parses duration from env. def is returned if it is not set.
+func durationEnv(env string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(env)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", env, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", env)
+	}
+
+	return d, nil
+}
+
+// receiptsConfig returns limits of stored receipts from RECEIPTS_MAX_AGE duration and RECEIPTS_MAX_SIZE_MB.
+func receiptsConfig() (receipts.Config, error) {
+	maxAge, err := durationEnv("RECEIPTS_MAX_AGE", receipts.DefaultMaxAge)
+	if err != nil {
+		return receipts.Config{}, err
+	}
+	cfg := receipts.Config{MaxAge: maxAge, MaxSize: receipts.DefaultMaxSize}
+	if v := os.Getenv("RECEIPTS_MAX_SIZE_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb <= 0 {
+			return cfg, fmt.Errorf("RECEIPTS_MAX_SIZE_MB must be positive number, got %q", v)
+		}
+		cfg.MaxSize = mb << 20 //nolint: gomnd // megabytes
+	}
+
+	return cfg, nil
+}
+
+// defaultLanguage returns language from DEFAULT_LANGUAGE used for chats which haven't chosen one. Ukrainian by default.
+func defaultLanguage() (i18n.Lang, error) {
+	v := envOrDefault("DEFAULT_LANGUAGE", string(i18n.Ukrainian))
+	lang, ok := i18n.ParseLang(v)
+	if !ok {
+		return "", fmt.Errorf("unsupported DEFAULT_LANGUAGE %q", v)
+	}
+
+	return lang, nil
+}
+
+func dataFile(name string) string {
+	return filepath.Join(envOrDefault("DATA_DIR", "data"), name)
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+var errNoTenants = errors.New("neither TENANTS_CONFIG nor YNAB_ACCESS_TOKEN is set")
+
+// tenantsConfig loads tenants from TENANTS_CONFIG file if it is set.
+// Otherwise, single tenant is configured from YNAB_* and TELEGRAM_CHAT_IDS environment variables.
+func tenantsConfig() (*tenant.Config, error) {
+	if path := os.Getenv("TENANTS_CONFIG"); path != "" {
+		return tenant.LoadConfig(path)
+	}
+	if os.Getenv("YNAB_ACCESS_TOKEN") == "" {
+		return nil, errNoTenants
+	}
+
+	chatIDs, err := chatIDs("TELEGRAM_CHAT_IDS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chat ids: %w", err)
+	}
+
+	cfg := &tenant.Config{
+		Tenants: []tenant.TenantConfig{
+			{
+				ID:         "default",
+				YNABToken:  os.Getenv("YNAB_ACCESS_TOKEN"),
+				BudgetID:   os.Getenv("YNAB_BUDGET_ID"),
+				CategoryID: os.Getenv("YNAB_CATEGORY_ID"),
+				ChatIDs:    chatIDs,
+			},
+		},
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func chatIDs(env string) ([]int64, error) {
+	res := make([]int64, 0)
+
+	chatIDs := os.Getenv(env)
+	if chatIDs == "" {
+		return nil, fmt.Errorf("%s environment variable is empty", env)
+	}
+
+	for _, chatID := range strings.Split(chatIDs, ",") {
+		val, err := strconv.ParseInt(strings.TrimSpace(chatID), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse chat id %q: %w", chatID, err)
+		}
+		res = append(res, val)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab/ynabtest"
+)
+
+// fakeYNAB serves fake YNAB API until interrupted and returns process exit code.
+// Point the bot to it with YNAB_API_URL to develop without real YNAB account.
+func fakeYNAB(args []string) int {
+	fs := flag.NewFlagSet("fake-ynab", flag.ContinueOnError)
+	addr := fs.String("addr", ":8081", "address to listen on")
+	fixture := fs.String("fixture", "", "fixture file with budgets, built-in fixture is served if empty")
+	token := fs.String("token", "", "access token required from clients, any token is accepted if empty")
+	latency := fs.Duration("latency", 0, "latency added to every request")
+	failStatus := fs.Int("fail-status", 0, "status code returned instead of handling requests, e.g. 429")
+	failPath := fs.String("fail-path", "", "path prefix of requests failed with -fail-status")
+	failTimes := fs.Int("fail-times", 0, "how many requests to fail with -fail-status, all if 0")
+	if err := fs.Parse(args); err != nil {
+		return 2 //nolint: gomnd // invalid usage
+	}
+
+	f := ynabtest.DefaultFixture()
+	if *fixture != "" {
+		var err error
+		if f, err = ynabtest.LoadFixture(*fixture); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load fixture: %v\n", err)
+			return 1
+		}
+	}
+
+	s := ynabtest.NewServer(f).WithToken(*token)
+	if *latency > 0 {
+		s.InjectFault(ynabtest.Fault{Latency: *latency})
+	}
+	if *failStatus != 0 {
+		s.InjectFault(ynabtest.Fault{Path: *failPath, Status: *failStatus, Times: *failTimes})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *addr, Handler: s, ReadHeaderTimeout: 10 * time.Second} //nolint: gomnd // 10 seconds
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "serving fake YNAB API on %s\n", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "fake YNAB API failed: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
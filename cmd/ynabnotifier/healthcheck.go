@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/health"
+)
+
+const healthcheckTimeout = 5 * time.Second
+
+// healthcheck probes health endpoint of running bot and returns process exit code.
+// It is meant to be used as Docker HEALTHCHECK command.
+func healthcheck(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	url := fs.String("url", "", "URL to probe (default is /healthz of HTTP_ADDR on localhost)")
+	if err := fs.Parse(args); err != nil {
+		return 2 //nolint: gomnd // invalid usage
+	}
+	if *url == "" {
+		_, port, err := net.SplitHostPort(envOrDefault("HTTP_ADDR", defaultHTTPAddr))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid HTTP_ADDR: %v\n", err)
+			return 2 //nolint: gomnd // invalid usage
+		}
+		*url = "http://" + net.JoinHostPort("localhost", port) + "/healthz"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+	if err := health.Probe(ctx, *url); err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
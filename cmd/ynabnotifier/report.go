@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/link"
+	"github.com/Roma7-7-7/ynab-notifier/internal/tenant"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	reportTimeout = time.Minute
+
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitBreached = 3
+
+	milliunits = 1000
+)
+
+// reportTarget is a category the report is made for.
+type reportTarget struct {
+	tenantID   string
+	token      string
+	budgetID   string
+	categoryID string
+}
+
+// reportRow is a statistic of the category. Amounts are in YNAB milliunits.
+type reportRow struct {
+	Tenant       string `json:"tenant"`
+	BudgetID     string `json:"budget_id"`
+	CategoryID   string `json:"category_id"`
+	Category     string `json:"category"`
+	Budgeted     int    `json:"budgeted"`
+	Activity     int    `json:"activity"`
+	Balance      int    `json:"balance"`
+	AvgSpent     int    `json:"avg_spent"`
+	AvgSpentLeft int    `json:"avg_spent_left"`
+	DaysLeft     int    `json:"days_left"`
+	Breached     bool   `json:"breached"`
+}
+
+// report prints statistic of configured categories and returns process exit code.
+// Exit code is 3 if any category is overspent or daily amount left is below -min-daily.
+func report(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	tenantID := fs.String("tenant", "", "report only categories of the tenant")
+	budgetID := fs.String("budget", "", "budget ID, overrides configured one")
+	categoryID := fs.String("category", "", "category ID, overrides configured one")
+	date := fs.String("date", "", "date to calculate statistic for as YYYY-MM-DD, today if empty")
+	format := fs.String("format", "text", "output format: text, json or csv")
+	minDaily := fs.Float64("min-daily", 0, "breach threshold of amount left per day, only overspending is checked if 0")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	write, ok := map[string]func(io.Writer, []reportRow) error{
+		"text": writeReportText,
+		"json": writeReportJSON,
+		"csv":  writeReportCSV,
+	}[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	today, monthly := time.Now(), *date != ""
+	if monthly {
+		var err error
+		if today, err = time.Parse("2006-01-02", *date); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid date %q: %v\n", *date, err)
+			return exitUsage
+		}
+	}
+
+	targets, err := reportTargets(*tenantID, *budgetID, *categoryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	threshold := int(math.Round(*minDaily * milliunits))
+	apiURL := envOrDefault("YNAB_API_URL", ynabURL)
+	rows := make([]reportRow, 0, len(targets))
+	code := exitOK
+	for _, t := range targets {
+		client := ynab.NewClient(apiURL, ynab.StaticToken(t.token), zap.NewNop().Sugar())
+
+		var cat *ynab.Category
+		if monthly {
+			cat, err = client.GetMonthCategory(ctx, t.budgetID, today, t.categoryID)
+		} else {
+			cat, err = client.GetCategory(ctx, t.budgetID, t.categoryID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get category %s of tenant %q: %v\n", t.categoryID, t.tenantID, err)
+			return exitFailure
+		}
+
+		stat := budget.CalculateStatisticAt(*cat, today)
+		row := reportRow{
+			Tenant:       t.tenantID,
+			BudgetID:     t.budgetID,
+			CategoryID:   t.categoryID,
+			Category:     cat.Name,
+			Budgeted:     stat.Budgeted,
+			Activity:     stat.Activity,
+			Balance:      stat.Balance,
+			AvgSpent:     stat.AvgSpent,
+			AvgSpentLeft: stat.AvgSpentLeft,
+			DaysLeft:     stat.DaysLeft,
+			Breached:     stat.Balance < 0 || (threshold > 0 && stat.AvgSpentLeft < threshold),
+		}
+		if row.Breached {
+			code = exitBreached
+		}
+		rows = append(rows, row)
+	}
+
+	if err = write(os.Stdout, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return exitFailure
+	}
+
+	return code
+}
+
+// reportTargets returns categories of tenants from TENANTS_CONFIG or the one configured with YNAB_* variables.
+// Budget and category flags override configured ones. Tenants without category are skipped.
+func reportTargets(tenantID, budgetID, categoryID string) ([]reportTarget, error) {
+	var targets []reportTarget
+	if path := os.Getenv("TENANTS_CONFIG"); path != "" {
+		cfg, err := tenant.LoadConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tenants config: %w", err)
+		}
+		for _, tc := range cfg.Tenants {
+			if tenantID == "" || tc.ID == tenantID {
+				targets = append(targets, reportTarget{
+					tenantID: tc.ID, token: tc.YNABToken, budgetID: tc.BudgetID, categoryID: tc.CategoryID,
+				})
+			}
+		}
+	} else {
+		if os.Getenv("YNAB_ACCESS_TOKEN") == "" {
+			return nil, errNoTenants
+		}
+		targets = append(targets, reportTarget{
+			tenantID:   "default",
+			token:      os.Getenv("YNAB_ACCESS_TOKEN"),
+			budgetID:   os.Getenv("YNAB_BUDGET_ID"),
+			categoryID: os.Getenv("YNAB_CATEGORY_ID"),
+		})
+	}
+
+	res := make([]reportTarget, 0, len(targets))
+	for _, t := range targets {
+		if budgetID != "" {
+			t.budgetID = budgetID
+		}
+		if categoryID != "" {
+			t.categoryID = categoryID
+		}
+		if t.budgetID == "" {
+			t.budgetID = link.DefaultBudgetID
+		}
+		if t.categoryID != "" {
+			res = append(res, t)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no categories to report, configure category or set -category")
+	}
+
+	return res, nil
+}
+
+func writeReportText(w io.Writer, rows []reportRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight) //nolint: gomnd // padding
+	fmt.Fprintln(tw, "TENANT\tCATEGORY\tBUDGETED\tACTIVITY\tBALANCE\tSPENT/DAY\tLEFT/DAY\tDAYS LEFT\t")
+	for _, r := range rows {
+		mark := ""
+		if r.Breached {
+			mark = " !"
+		}
+		fmt.Fprintf(tw, "%s\t%s%s\t%s\t%s\t%s\t%s\t%s\t%d\t\n",
+			r.Tenant, r.Category, mark,
+			budget.FormatMoney(r.Budgeted), budget.FormatMoney(r.Activity), budget.FormatMoney(r.Balance),
+			budget.FormatMoney(r.AvgSpent), budget.FormatMoney(r.AvgSpentLeft), r.DaysLeft,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("flushing table: %w", err)
+	}
+	return nil
+}
+
+func writeReportJSON(w io.Writer, rows []reportRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rows); err != nil {
+		return fmt.Errorf("encoding json: %w", err)
+	}
+	return nil
+}
+
+func writeReportCSV(w io.Writer, rows []reportRow) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{
+		"tenant", "budget_id", "category_id", "category", "budgeted", "activity", "balance",
+		"avg_spent", "avg_spent_left", "days_left", "breached",
+	}}
+	for _, r := range rows {
+		records = append(records, []string{
+			r.Tenant, r.BudgetID, r.CategoryID, r.Category,
+			strconv.Itoa(r.Budgeted), strconv.Itoa(r.Activity), strconv.Itoa(r.Balance),
+			strconv.Itoa(r.AvgSpent), strconv.Itoa(r.AvgSpentLeft), strconv.Itoa(r.DaysLeft),
+			strconv.FormatBool(r.Breached),
+		})
+	}
+
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	return nil
+}
@@ -1,20 +1,52 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	stdLog "log"
+	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/cache"
+	"github.com/Roma7-7-7/ynab-notifier/internal/health"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/link"
+	appMetrics "github.com/Roma7-7-7/ynab-notifier/internal/metrics"
+	"github.com/Roma7-7-7/ynab-notifier/internal/receipts"
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
 	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/internal/templates"
+	"github.com/Roma7-7-7/ynab-notifier/internal/tenant"
 	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
 )
 
+const (
+	ynabURL         = "https://api.ynab.com"
+	defaultHTTPAddr = ":8080"
+	shutdownTimeout = 30 * time.Second
+)
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "healthcheck":
+			os.Exit(healthcheck(os.Args[2:]))
+		case "fake-ynab":
+			os.Exit(fakeYNAB(os.Args[2:]))
+		case "report":
+			os.Exit(report(os.Args[2:]))
+		}
+	}
+
 	var debug = flag.Bool("debug", false, "debug mode")
 
 	flag.Parse()
@@ -31,51 +63,322 @@ func main() {
 	}
 	log := l.Sugar()
 
-	chatIDs, err := chatIDs()
+	serve(log)
+
+	_ = l.Sync()
+}
+
+// nolint: funlen // wiring of all components
+func serve(log *zap.SugaredLogger) {
+	telebot, err := telegram.NewTelebot(os.Getenv("TELEGRAM_TOKEN"), webhookConfig(), log)
 	if err != nil {
-		log.Fatalw("failed to parse chat ids", "error", err)
+		log.Fatalw("failed to create telebot", "error", err)
 	}
 
-	telebot, err := telegram.NewTelebot(os.Getenv("TELEGRAM_TOKEN"))
+	checker := health.NewChecker(func(context.Context) error {
+		_, err := telebot.Raw("getMe", nil)
+		return err
+	})
+	cacheCfg, err := cacheConfig()
 	if err != nil {
-		log.Fatalw("failed to create telebot", "error", err)
+		log.Fatalw("failed to parse cache config", "error", err)
+	}
+	metrics := appMetrics.New()
+	apiURL := envOrDefault("YNAB_API_URL", ynabURL)
+	newClient := func(tenantID string, tokens ynab.TokenSource) telegram.YNABClient {
+		client := ynab.NewClient(apiURL, tokens, log).WithObserver(checker.Tenant(tenantID)).WithObserver(metrics)
+		metrics.TrackRateLimit(tenantID, client.RateLimitRemaining)
+		return cache.New(client, cacheCfg, log)
+	}
+
+	oauthEnabled := os.Getenv("YNAB_OAUTH_CLIENT_ID") != ""
+
+	tenants := tenant.NewRegistry()
+	tenantsCfg, err := tenantsConfig()
+	switch {
+	case err != nil && !(oauthEnabled && errors.Is(err, errNoTenants)):
+		log.Fatalw("failed to load tenants config", "error", err)
+	case err == nil:
+		tenants, err = tenant.NewRegistryFromConfig(tenantsCfg, func(tenantID, token string) telegram.YNABClient {
+			return newClient(tenantID, ynab.StaticToken(token))
+		})
+		if err != nil {
+			log.Fatalw("failed to create tenants registry", "error", err)
+		}
 	}
 
-	client := ynab.NewClient("https://api.ynab.com", os.Getenv("YNAB_ACCESS_TOKEN"), log)
 	formatter, err := telegram.NewDefaultStatisticMessageFormatter()
 	if err != nil {
 		log.Fatalw("failed to create statistic message formatter", "error", err)
 	}
+	catalog := i18n.NewCatalog()
+	var userTemplates *templates.Set
+	if dir := os.Getenv("TEMPLATES_DIR"); dir != "" {
+		if userTemplates, err = templates.Load(dir, catalog, log); err != nil {
+			log.Fatalw("failed to load templates", "error", err)
+		}
+		formatter = userTemplates.Formatter(formatter)
+	}
+
+	mux := http.NewServeMux()
+	checker.Register(mux)
+	mux.Handle("/metrics", metrics.Handler())
+
+	var admins []int64
+	if os.Getenv("TELEGRAM_ADMIN_CHAT_IDS") != "" {
+		if admins, err = chatIDs("TELEGRAM_ADMIN_CHAT_IDS"); err != nil {
+			log.Fatalw("failed to parse admin chat ids", "error", err)
+		}
+	}
+	assignedRoles, err := store.OpenJSONFile[map[string]map[int64]telegram.Role](dataFile("roles.json"))
+	if err != nil {
+		log.Fatalw("failed to open roles store", "error", err)
+	}
+	roles := tenant.NewRoles(tenantsCfg, admins, assignedRoles)
+
+	approved, err := store.OpenJSONFile[map[int64]tenant.ApprovedChat](dataFile("approved_chats.json"))
+	if err != nil {
+		log.Fatalw("failed to open approved chats store", "error", err)
+	}
 
-	bot := telegram.NewBot(telegram.Dependencies{
-		AllowedChats: chatIDs,
-		YNAB: telegram.YNABDependencies{
-			BudgetID:   os.Getenv("YNAB_BUDGET_ID"),
-			CategoryID: os.Getenv("YNAB_CATEGORY_ID"),
-			Client:     client,
-		},
+	var bot *telegram.Bot
+	var linker telegram.Linker
+	if oauthEnabled {
+		linkService, err := newLinkService(tenants, roles, approved, newClient, func(chatID int64, categoryID string) {
+			bot.Linked(chatID, categoryID)
+		}, log)
+		if err != nil {
+			log.Fatalw("failed to create link service", "error", err)
+		}
+		linker = linkService
+		mux.Handle("/oauth/callback", linkService)
+	}
+
+	var access telegram.AccessManager
+	if len(admins) > 0 {
+		if access, err = tenant.NewAccess(tenants, approved, admins); err != nil {
+			log.Fatalw("failed to create access manager", "error", err)
+		}
+	}
+	languages, err := store.OpenJSONFile[map[int64]i18n.Lang](dataFile("languages.json"))
+	if err != nil {
+		log.Fatalw("failed to open languages store", "error", err)
+	}
+	defaultLang, err := defaultLanguage()
+	if err != nil {
+		log.Fatalw("failed to parse default language", "error", err)
+	}
+	dashboards, err := store.OpenJSONFile[map[int64]int](dataFile("dashboards.json"))
+	if err != nil {
+		log.Fatalw("failed to open dashboards store", "error", err)
+	}
+	dashboardSync, err := durationEnv("DASHBOARD_SYNC_INTERVAL", telegram.DefaultDashboardSyncInterval)
+	if err != nil {
+		log.Fatalw("failed to parse dashboard sync interval", "error", err)
+	}
+	alerts, err := store.OpenJSONFile[map[int64]anomaly.Sensitivity](dataFile("alerts.json"))
+	if err != nil {
+		log.Fatalw("failed to open alerts store", "error", err)
+	}
+	seen, err := store.OpenJSONFile[map[string]map[string]string](dataFile("seen_transactions.json"))
+	if err != nil {
+		log.Fatalw("failed to open seen transactions store", "error", err)
+	}
+	alertsInterval, err := durationEnv("ALERTS_INTERVAL", telegram.DefaultAlertsInterval)
+	if err != nil {
+		log.Fatalw("failed to parse alerts interval", "error", err)
+	}
+	receiptsCfg, err := receiptsConfig()
+	if err != nil {
+		log.Fatalw("failed to parse receipts config", "error", err)
+	}
+	receiptStore := receipts.New(dataFile("receipts"), receiptsCfg, log)
+
+	bot = telegram.NewBot(telegram.Dependencies{
+		Tenants:                   tenants,
+		Linker:                    linker,
+		Access:                    access,
+		Roles:                     roles,
 		StatisticMessageFormatter: formatter,
+		Metrics:                   metrics,
+		Catalog:                   catalog,
+		Languages:                 i18n.NewChatLanguages(languages),
+		DefaultLanguage:           defaultLang,
+		Dashboards:                tenant.NewDashboards(dashboards),
+		Receipts:                  receiptStore,
+		Alerts:                    tenant.NewAlerts(alerts),
+		Seen:                      tenant.NewSeenTransactions(seen),
 		Logger:                    log,
 	})
 
-	bot.Start(telebot)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Schedulers run until ctx is done, shutdown waits for them.
+	var schedulers sync.WaitGroup
+	schedule := func(run func(ctx context.Context)) {
+		schedulers.Add(1)
+		go func() {
+			defer schedulers.Done()
+			run(ctx)
+		}()
+	}
+
+	if userTemplates != nil {
+		schedule(func(ctx context.Context) {
+			userTemplates.Watch(ctx, templates.DefaultReloadInterval)
+		})
+	}
+
+	botCtx, abortHandlers := context.WithCancel(context.Background())
+	defer abortHandlers()
+	bot.Start(botCtx, telebot)
+	schedule(func(ctx context.Context) {
+		bot.RunDashboards(ctx, dashboardSync, telegram.DefaultDashboardRefreshInterval)
+	})
+	schedule(func(ctx context.Context) {
+		receiptStore.Run(ctx, receipts.DefaultCleanupInterval)
+	})
+	schedule(func(ctx context.Context) {
+		bot.RunAlerts(ctx, alertsInterval)
+	})
+
+	// HTTP server is optional unless it is required for OAuth callback.
+	var server *http.Server
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" || oauthEnabled {
+		server = startHTTP(envOrDefault("HTTP_ADDR", defaultHTTPAddr), mux, log)
+	}
+
+	<-ctx.Done()
+	log.Infow("shutting down")
+	checker.MarkStopping()
+	shutdown(bot, server, &schedulers, abortHandlers, log)
 }
 
-func chatIDs() ([]int64, error) {
-	res := make([]int64, 0)
+// shutdown stops receiving updates, waits for in-flight handlers and schedulers and stops HTTP server within
+// shutdownTimeout. Schedulers are stopped by cancelled signal context already. Handlers still running after timeout
+// are aborted. State is persisted on every change, so nothing is flushed here.
+func shutdown(
+	bot *telegram.Bot,
+	server *http.Server,
+	schedulers *sync.WaitGroup,
+	abortHandlers context.CancelFunc,
+	log *zap.SugaredLogger,
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := bot.Stop(ctx); err != nil {
+		log.Errorw("failed to stop bot gracefully", "error", err)
+		abortHandlers()
+	}
 
-	chatIDs := os.Getenv("TELEGRAM_CHAT_IDS")
-	if chatIDs == "" {
-		return nil, fmt.Errorf("TELEGRAM_CHAT_IDS environment variable is empty")
+	stopped := make(chan struct{})
+	go func() {
+		schedulers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Errorw("failed to stop schedulers gracefully", "error", ctx.Err())
 	}
 
-	for _, chatID := range strings.Split(chatIDs, ",") {
-		val, err := strconv.ParseInt(strings.TrimSpace(chatID), 10, 64)
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Errorw("failed to shutdown http server", "error", err)
+		}
+	}
+
+	log.Infow("shutdown complete")
+}
+
+// newLinkService creates OAuth link service and registers tenants of already linked chats.
+// The user who linked the chat owns its tenant. Links of configured and approved chats are skipped.
+func newLinkService(
+	tenants *tenant.Registry,
+	roles *tenant.Roles,
+	approved *store.JSONFile[map[int64]tenant.ApprovedChat],
+	newClient func(tenantID string, tokens ynab.TokenSource) telegram.YNABClient,
+	notify func(chatID int64, categoryID string),
+	log *zap.SugaredLogger,
+) (*link.Service, error) {
+	links, err := store.OpenJSONFile[map[int64]link.Link](dataFile("links.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open links store: %w", err)
+	}
+
+	oauth := ynab.NewOAuth(
+		ynab.DefaultOAuthURL,
+		os.Getenv("YNAB_OAUTH_CLIENT_ID"),
+		os.Getenv("YNAB_OAUTH_CLIENT_SECRET"),
+		os.Getenv("YNAB_OAUTH_REDIRECT_URL"),
+		log,
+	)
+
+	var svc *link.Service
+	// register replaces tenant of the linked chat. Chats of configured or approved tenants are not taken over.
+	register := func(l link.Link) error {
+		id := telegram.LinkedTenantID(l.ChatID)
+		if t, ok := tenants.ByChat(l.ChatID); ok && t.ID != id {
+			return fmt.Errorf("chat %d already belongs to tenant %q", l.ChatID, t.ID)
+		}
+		err := tenants.Set(&telegram.Tenant{
+			ID:         id,
+			BudgetID:   l.BudgetID,
+			CategoryID: l.CategoryID,
+			Client:     newClient(id, svc.TokenSource(l)),
+		}, l.ChatID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse chat id %q: %w", chatID, err)
+			return err
 		}
-		res = append(res, val)
+
+		owner := l.UserID
+		if owner == 0 {
+			// Links made before owners were stored. Chat ID of a private chat is ID of its user.
+			owner = l.ChatID
+		}
+		roles.SetOwner(id, owner)
+		return nil
 	}
+	svc = link.NewService(oauth, links, func(l link.Link) {
+		if err := register(l); err != nil {
+			log.Errorw("failed to register linked tenant", "chatID", l.ChatID, "error", err)
+			return
+		}
+		notify(l.ChatID, l.CategoryID)
+	}, log)
+
+	for _, l := range svc.Links() {
+		var isApproved bool
+		approved.View(func(chats map[int64]tenant.ApprovedChat) {
+			_, isApproved = chats[l.ChatID]
+		})
+		if isApproved {
+			log.Warnw("skipping linked tenant of approved chat", "chatID", l.ChatID)
+			continue
+		}
+		if err = register(l); err != nil {
+			log.Warnw("skipping linked tenant", "chatID", l.ChatID, "error", err)
+		}
+	}
+
+	return svc, nil
+}
+
+func startHTTP(addr string, handler http.Handler, log *zap.SugaredLogger) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second, //nolint: gomnd // 10 seconds
+	}
+
+	go func() {
+		log.Infow("starting http server", "addr", addr)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalw("http server failed", "error", err)
+		}
+	}()
 
-	return res, nil
+	return server
 }
@@ -0,0 +1,200 @@
+// Package anomaly finds unusual transactions: days when a category spends several times its typical day,
+// payees which haven't been used before and transactions which look like duplicates.
+package anomaly
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	day        = 24 * time.Hour
+)
+
+// Sensitivity is a preset of Config. The higher sensitivity is, the more anomalies are reported.
+type Sensitivity string
+
+const (
+	Low    Sensitivity = "low"
+	Medium Sensitivity = "medium"
+	High   Sensitivity = "high"
+)
+
+// Kind is kind of anomaly.
+type Kind string
+
+const (
+	// Spike is a day when spending of a category is several times its typical day.
+	Spike Kind = "spike"
+	// NewPayee is an outflow to payee which isn't in the history.
+	NewPayee Kind = "new_payee"
+	// Duplicate is a transaction with the same amount and payee as another one made around the same day.
+	Duplicate Kind = "duplicate"
+)
+
+// Config tunes what is reported as anomaly.
+type Config struct {
+	// SpikeFactor is how many times spending of a category in a day must exceed its typical day to be a spike.
+	SpikeFactor float64
+	// MinDays is how many days with spending a category needs in the history before its typical day is known.
+	MinDays int
+	// NewPayeeMin is the smallest outflow in milliunits reported for a new payee.
+	NewPayeeMin int
+	// DuplicateDays is how many days apart transactions may be to be duplicates. YNAB transactions have dates only,
+	// so 0 means the same day.
+	DuplicateDays int
+}
+
+// Anomaly is an unusual transaction.
+type Anomaly struct {
+	Kind        Kind
+	Transaction ynab.Transaction
+	// Original is the earlier transaction Transaction duplicates. It is set for Duplicate.
+	Original ynab.Transaction
+	// DaySpent and TypicalDay are spending of the category on the day of Transaction and on its typical day
+	// in positive milliunits. They are set for Spike.
+	DaySpent   int
+	TypicalDay int
+}
+
+// ParseSensitivity parses "low", "medium" or "high".
+func ParseSensitivity(s string) (Sensitivity, bool) {
+	sensitivity := Sensitivity(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := presets()[sensitivity]
+	return sensitivity, ok
+}
+
+// Config returns config of the preset. Unknown sensitivity is Medium.
+func (s Sensitivity) Config() Config {
+	if cfg, ok := presets()[s]; ok {
+		return cfg
+	}
+	return presets()[Medium]
+}
+
+func presets() map[Sensitivity]Config {
+	return map[Sensitivity]Config{
+		Low:    {SpikeFactor: 5, MinDays: 10, NewPayeeMin: 1000000, DuplicateDays: 0},
+		Medium: {SpikeFactor: 3, MinDays: 7, NewPayeeMin: 200000, DuplicateDays: 0},
+		High:   {SpikeFactor: 2, MinDays: 5, NewPayeeMin: 0, DuplicateDays: 1},
+	}
+}
+
+// detector keeps what's known about the budget while new transactions are checked one by one.
+type detector struct {
+	cfg Config
+	// daily is spending of categories by date.
+	daily  map[string]map[string]int
+	payees map[string]bool
+	known  []ynab.Transaction
+}
+
+// Detect returns anomalies of new transactions txs compared to the history of the budget, which must not contain
+// them. Only outflows are checked, deleted transactions and transfers are skipped.
+// A transaction may have several anomalies, spike is reported only by the transaction which made the day a spike.
+func Detect(cfg Config, history, txs []ynab.Transaction) []Anomaly {
+	d := &detector{cfg: cfg, daily: make(map[string]map[string]int), payees: make(map[string]bool)}
+	for _, tx := range history {
+		if isOutflow(tx) {
+			d.add(tx)
+		}
+	}
+
+	sorted := make([]ynab.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if isOutflow(tx) {
+			sorted = append(sorted, tx)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date < sorted[j].Date
+	})
+
+	var res []Anomaly
+	for _, tx := range sorted {
+		if original, ok := d.duplicateOf(tx); ok {
+			res = append(res, Anomaly{Kind: Duplicate, Transaction: tx, Original: original})
+		} else if payee := normalize(tx.PayeeName); payee != "" && !d.payees[payee] && -tx.Amount >= cfg.NewPayeeMin {
+			res = append(res, Anomaly{Kind: NewPayee, Transaction: tx})
+		}
+		if spent, typical, ok := d.spike(tx); ok {
+			res = append(res, Anomaly{Kind: Spike, Transaction: tx, DaySpent: spent, TypicalDay: typical})
+		}
+		d.add(tx)
+	}
+
+	return res
+}
+
+func (d *detector) add(tx ynab.Transaction) {
+	if tx.CategoryID != "" {
+		if d.daily[tx.CategoryID] == nil {
+			d.daily[tx.CategoryID] = make(map[string]int)
+		}
+		d.daily[tx.CategoryID][tx.Date] -= tx.Amount
+	}
+	if payee := normalize(tx.PayeeName); payee != "" {
+		d.payees[payee] = true
+	}
+	d.known = append(d.known, tx)
+}
+
+// duplicateOf returns known transaction with the same amount and payee within DuplicateDays from tx.
+func (d *detector) duplicateOf(tx ynab.Transaction) (ynab.Transaction, bool) {
+	payee := normalize(tx.PayeeName)
+	if payee == "" {
+		return ynab.Transaction{}, false
+	}
+	for _, other := range d.known {
+		if other.Amount != tx.Amount || normalize(other.PayeeName) != payee {
+			continue
+		}
+		if days, ok := daysBetween(tx.Date, other.Date); ok && days <= d.cfg.DuplicateDays {
+			return other, true
+		}
+	}
+
+	return ynab.Transaction{}, false
+}
+
+// spike reports whether tx makes spending of its category on its day at least SpikeFactor times the typical day.
+// Typical day is the median spending of the other days of the category with spending.
+func (d *detector) spike(tx ynab.Transaction) (spent, typical int, ok bool) {
+	if tx.CategoryID == "" {
+		return 0, 0, false
+	}
+	days := d.daily[tx.CategoryID]
+	amounts := make([]int, 0, len(days))
+	for date, amount := range days {
+		if date != tx.Date && amount > 0 {
+			amounts = append(amounts, amount)
+		}
+	}
+	if len(amounts) == 0 || len(amounts) < d.cfg.MinDays {
+		return 0, 0, false
+	}
+	sort.Ints(amounts)
+	typical = amounts[len(amounts)/2]
+
+	threshold := d.cfg.SpikeFactor * float64(typical)
+	before := days[tx.Date]
+	spent = before - tx.Amount
+	if float64(spent) < threshold || float64(before) >= threshold {
+		return 0, 0, false
+	}
+
+	return spent, typical, true
+}
+
+// isOutflow reports whether tx spends money. Transfers between accounts are not spending.
+func isOutflow(tx ynab.Transaction) bool {
+	return tx.Amount < 0 && !tx.Deleted && !tx.IsTransfer()
+}
+
+func normalize(payee string) string {
+	return strings.ToLower(strings.Join(strings.Fields(payee), " "))
+}
@@ -0,0 +1,100 @@
+package anomaly_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestDetect(t *testing.T) {
+	var history []ynab.Transaction
+	for day := 1; day <= 10; day++ {
+		history = append(history, ynab.Transaction{
+			ID: fmt.Sprintf("h%d", day), Date: fmt.Sprintf("2024-05-%02d", day), Amount: -100000,
+			PayeeName: "Silpo", CategoryID: "c-groceries",
+		})
+	}
+	history = append(history,
+		ynab.Transaction{ID: "h-taxi", Date: "2024-05-11", Amount: -150000, PayeeName: "Uber", CategoryID: "c-transport"},
+		ynab.Transaction{ID: "h-today", Date: "2024-05-12", Amount: -150000, PayeeName: "Silpo", CategoryID: "c-groceries"},
+	)
+	cfg := anomaly.Medium.Config()
+
+	t.Run("spike", func(t *testing.T) {
+		got := anomaly.Detect(cfg, history, []ynab.Transaction{
+			{ID: "n1", Date: "2024-05-12", Amount: -100000, PayeeName: "Silpo", CategoryID: "c-groceries"},
+			{ID: "n2", Date: "2024-05-12", Amount: -60000, PayeeName: "silpo", CategoryID: "c-groceries"},
+			{ID: "n3", Date: "2024-05-12", Amount: -20000, PayeeName: "Silpo", CategoryID: "c-groceries"},
+		})
+		require.Len(t, got, 1, "spike is reported once, by transaction which made it")
+		assert.Equal(t, anomaly.Anomaly{
+			Kind: anomaly.Spike, Transaction: ynab.Transaction{
+				ID: "n2", Date: "2024-05-12", Amount: -60000, PayeeName: "silpo", CategoryID: "c-groceries",
+			},
+			DaySpent: 310000, TypicalDay: 100000,
+		}, got[0])
+	})
+
+	t.Run("too few days to know typical day", func(t *testing.T) {
+		assert.Empty(t, anomaly.Detect(cfg, history, []ynab.Transaction{
+			{ID: "n1", Date: "2024-05-12", Amount: -900000, PayeeName: "Uber", CategoryID: "c-transport"},
+		}))
+	})
+
+	t.Run("new payee", func(t *testing.T) {
+		got := anomaly.Detect(cfg, history, []ynab.Transaction{
+			{ID: "n1", Date: "2024-05-12", Amount: -250000, PayeeName: "Rozetka"},
+			{ID: "n2", Date: "2024-05-12", Amount: -50000, PayeeName: "Kiosk"},
+			{ID: "n3", Date: "2024-05-13", Amount: -300000, PayeeName: "rozetka"},
+			{ID: "n4", Date: "2024-05-13", Amount: 500000, PayeeName: "Employer"},
+		})
+		require.Len(t, got, 1, "small outflows, inflows and payees reported once are skipped")
+		assert.Equal(t, anomaly.NewPayee, got[0].Kind)
+		assert.Equal(t, "n1", got[0].Transaction.ID)
+
+		got = anomaly.Detect(anomaly.High.Config(), history, []ynab.Transaction{
+			{ID: "n2", Date: "2024-05-12", Amount: -50000, PayeeName: "Kiosk"},
+		})
+		require.Len(t, got, 1, "high sensitivity reports any amount")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		got := anomaly.Detect(cfg, history, []ynab.Transaction{
+			{ID: "n1", Date: "2024-05-11", Amount: -150000, PayeeName: "UBER", CategoryID: "c-transport"},
+			{ID: "n2", Date: "2024-05-13", Amount: -150000, PayeeName: "Uber"},
+			{ID: "n3", Date: "2024-05-13", Amount: -150000, PayeeName: "Transfer : Cash", TransferAccountID: "a-cash"},
+			{ID: "n4", Date: "2024-05-13", Amount: -150000, PayeeName: "Transfer : Cash", TransferAccountID: "a-cash"},
+		})
+		require.Len(t, got, 1, "transactions of other days and transfers are not duplicates")
+		assert.Equal(t, anomaly.Duplicate, got[0].Kind)
+		assert.Equal(t, "n1", got[0].Transaction.ID)
+		assert.Equal(t, "h-taxi", got[0].Original.ID)
+
+		got = anomaly.Detect(anomaly.High.Config(), history, []ynab.Transaction{
+			{ID: "n2", Date: "2024-05-12", Amount: -150000, PayeeName: "Uber"},
+		})
+		require.Len(t, got, 1, "high sensitivity reports the next day too")
+		assert.Equal(t, "h-taxi", got[0].Original.ID)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		assert.Empty(t, anomaly.Detect(cfg, history, []ynab.Transaction{
+			{ID: "n1", Date: "2024-05-12", Amount: -900000, PayeeName: "Rozetka", CategoryID: "c-groceries", Deleted: true},
+		}))
+	})
+}
+
+func TestParseSensitivity(t *testing.T) {
+	s, ok := anomaly.ParseSensitivity(" High ")
+	assert.True(t, ok)
+	assert.Equal(t, anomaly.High, s)
+
+	_, ok = anomaly.ParseSensitivity("extreme")
+	assert.False(t, ok)
+	assert.Equal(t, anomaly.Medium.Config(), anomaly.Sensitivity("extreme").Config())
+}
@@ -0,0 +1,142 @@
+package anomaly
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	// importDelayDays is how many days after manual entry bank may post the same transaction.
+	importDelayDays = 3
+	// minPayeeWordLen is the shortest word payees may share to be similar.
+	minPayeeWordLen = 3
+)
+
+// DuplicatePair is a transaction entered manually and a transaction imported from bank which are likely the same.
+type DuplicatePair struct {
+	Manual   ynab.Transaction
+	Imported ynab.Transaction
+}
+
+// FindDuplicates returns pairs of manual and imported transactions of the same account with the same amount
+// made within importDelayDays of each other, whose payees are similar or which have the same date.
+// Each transaction is in one pair at most, the closest dates are paired first. Only pairs with at least one
+// of new transactions txs are returned. Deleted transactions and transfers are skipped.
+func FindDuplicates(history, txs []ynab.Transaction) []DuplicatePair {
+	isNew := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		isNew[tx.ID] = true
+	}
+
+	var manual, imported []ynab.Transaction
+	for _, tx := range append(append([]ynab.Transaction(nil), history...), txs...) {
+		if tx.Deleted || tx.Amount == 0 || tx.IsTransfer() {
+			continue
+		}
+		if tx.ImportID == "" || strings.HasPrefix(tx.ImportID, expense.ImportIDPrefix) {
+			manual = append(manual, tx)
+		} else {
+			imported = append(imported, tx)
+		}
+	}
+
+	type candidate struct {
+		manual, imported int
+		days             int
+	}
+	var candidates []candidate
+	for i, m := range manual {
+		for j, im := range imported {
+			if m.AccountID != im.AccountID || m.Amount != im.Amount || !isNew[m.ID] && !isNew[im.ID] {
+				continue
+			}
+			days, ok := daysBetween(m.Date, im.Date)
+			if !ok || days > importDelayDays || days > 0 && !similarPayees(m.PayeeName, im.PayeeName) {
+				continue
+			}
+			candidates = append(candidates, candidate{manual: i, imported: j, days: days})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].days < candidates[j].days
+	})
+
+	var res []DuplicatePair
+	pairedManual, pairedImported := make(map[int]bool), make(map[int]bool)
+	for _, c := range candidates {
+		if pairedManual[c.manual] || pairedImported[c.imported] {
+			continue
+		}
+		pairedManual[c.manual], pairedImported[c.imported] = true, true
+		res = append(res, DuplicatePair{Manual: manual[c.manual], Imported: imported[c.imported]})
+	}
+
+	return res
+}
+
+// daysBetween returns number of days between YNAB dates.
+func daysBetween(a, b string) (int, bool) {
+	dateA, err := time.Parse(dateLayout, a)
+	if err != nil {
+		return 0, false
+	}
+	dateB, err := time.Parse(dateLayout, b)
+	if err != nil {
+		return 0, false
+	}
+	days := int(dateA.Sub(dateB) / day)
+	if days < 0 {
+		days = -days
+	}
+
+	return days, true
+}
+
+// similarPayees reports whether payees are the same, one contains the other or they share a word,
+// e.g. "Silpo" and "SILPO KYIV UA".
+func similarPayees(a, b string) bool {
+	a, b = normalize(a), normalize(b)
+	if a == "" || b == "" {
+		return false
+	}
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		return true
+	}
+
+	words := make(map[string]bool)
+	for _, w := range payeeWords(a) {
+		words[w] = true
+	}
+	for _, w := range payeeWords(b) {
+		if words[w] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payeeWords returns words of payee which have at least minPayeeWordLen letters.
+func payeeWords(payee string) []string {
+	var res []string
+	for _, w := range strings.FieldsFunc(payee, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		letters := 0
+		for _, r := range w {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		if letters >= minPayeeWordLen {
+			res = append(res, w)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,104 @@
+package anomaly_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	manual := ynab.Transaction{ID: "m1", Date: "2024-05-07", Amount: -65000, AccountID: "card", PayeeName: "Silpo"}
+	history := []ynab.Transaction{
+		manual,
+		{ID: "m2", Date: "2024-05-07", Amount: -20000, AccountID: "card", PayeeName: "Kiosk"},
+		{ID: "m3", Date: "2024-05-01", Amount: -65000, AccountID: "card", PayeeName: "Silpo"},
+		{ID: "m4", Date: "2024-05-08", Amount: -65000, AccountID: "card", PayeeName: "Silpo", Deleted: true},
+	}
+
+	tests := []struct {
+		name string
+		txs  []ynab.Transaction
+		want []anomaly.DuplicatePair
+	}{
+		{
+			name: "similar payee a few days later",
+			txs: []ynab.Transaction{
+				{ID: "i1", Date: "2024-05-09", Amount: -65000, AccountID: "card", PayeeName: "SILPO KYIV UA", ImportID: "x"},
+			},
+			want: []anomaly.DuplicatePair{{Manual: manual, Imported: ynab.Transaction{
+				ID: "i1", Date: "2024-05-09", Amount: -65000, AccountID: "card", PayeeName: "SILPO KYIV UA", ImportID: "x",
+			}}},
+		},
+		{
+			name: "other payee on the same day",
+			txs: []ynab.Transaction{
+				{ID: "i2", Date: "2024-05-07", Amount: -20000, AccountID: "card", PayeeName: "LIQPAY*2831", ImportID: "x"},
+			},
+			want: []anomaly.DuplicatePair{{Manual: history[1], Imported: ynab.Transaction{
+				ID: "i2", Date: "2024-05-07", Amount: -20000, AccountID: "card", PayeeName: "LIQPAY*2831", ImportID: "x",
+			}}},
+		},
+		{
+			name: "other payee on another day",
+			txs: []ynab.Transaction{
+				{ID: "i3", Date: "2024-05-08", Amount: -20000, AccountID: "card", PayeeName: "LIQPAY*2831", ImportID: "x"},
+			},
+		},
+		{
+			name: "other account, amount or too late",
+			txs: []ynab.Transaction{
+				{ID: "i4", Date: "2024-05-07", Amount: -65000, AccountID: "cash", PayeeName: "Silpo", ImportID: "x"},
+				{ID: "i5", Date: "2024-05-07", Amount: -65001, AccountID: "card", PayeeName: "Silpo", ImportID: "x"},
+				{ID: "i6", Date: "2024-05-12", Amount: -65000, AccountID: "card", PayeeName: "Silpo", ImportID: "x"},
+			},
+		},
+		{
+			name: "transaction created by the bot is manual",
+			txs: []ynab.Transaction{
+				{ID: "m6", Date: "2024-05-09", Amount: -30000, AccountID: "card", PayeeName: "Kiosk", ImportID: "bot:1:2"},
+				{ID: "i7", Date: "2024-05-09", Amount: -30000, AccountID: "card", PayeeName: "KIOSK", ImportID: "x"},
+			},
+			want: []anomaly.DuplicatePair{{
+				Manual: ynab.Transaction{
+					ID: "m6", Date: "2024-05-09", Amount: -30000, AccountID: "card", PayeeName: "Kiosk", ImportID: "bot:1:2",
+				},
+				Imported: ynab.Transaction{
+					ID: "i7", Date: "2024-05-09", Amount: -30000, AccountID: "card", PayeeName: "KIOSK", ImportID: "x",
+				},
+			}},
+		},
+		{
+			name: "manual entries are not duplicates of each other",
+			txs: []ynab.Transaction{
+				{ID: "m5", Date: "2024-05-07", Amount: -65000, AccountID: "card", PayeeName: "Silpo"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, anomaly.FindDuplicates(history, tt.txs))
+		})
+	}
+
+	t.Run("closest dates are paired first", func(t *testing.T) {
+		imported := []ynab.Transaction{
+			{ID: "i1", Date: "2024-05-04", Amount: -65000, AccountID: "card", PayeeName: "Silpo", ImportID: "x"},
+			{ID: "i2", Date: "2024-05-02", Amount: -65000, AccountID: "card", PayeeName: "Silpo", ImportID: "y"},
+		}
+		got := anomaly.FindDuplicates(history, imported)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "m3", got[0].Manual.ID)
+		assert.Equal(t, "i2", got[0].Imported.ID)
+		assert.Equal(t, "m1", got[1].Manual.ID)
+		assert.Equal(t, "i1", got[1].Imported.ID)
+	})
+
+	t.Run("pairs without new transactions are skipped", func(t *testing.T) {
+		assert.Empty(t, anomaly.FindDuplicates(append(history, ynab.Transaction{
+			ID: "i1", Date: "2024-05-07", Amount: -65000, AccountID: "card", PayeeName: "Silpo", ImportID: "x",
+		}), nil))
+	})
+}
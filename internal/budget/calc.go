@@ -43,7 +43,11 @@ func (s GeneralCategoryStatistic) AvgSpentLeftS() string {
 }
 
 func CalculateStatistic(c ynab.Category) GeneralCategoryStatistic {
-	today := time.Now()
+	return CalculateStatisticAt(c, time.Now())
+}
+
+// CalculateStatisticAt calculates statistic of the category as if today was the given date.
+func CalculateStatisticAt(c ynab.Category, today time.Time) GeneralCategoryStatistic {
 	return GeneralCategoryStatistic{
 		Budgeted:     c.Budgeted,
 		Activity:     c.Activity,
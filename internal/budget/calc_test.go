@@ -352,3 +352,21 @@ func TestDaysLeft(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateStatisticAt(t *testing.T) {
+	got := budget.CalculateStatisticAt(
+		ynab.Category{Budgeted: 3100000, Activity: -1000000, Balance: 2100000},
+		time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC),
+	)
+	want := budget.GeneralCategoryStatistic{
+		Budgeted:     3100000,
+		Activity:     -1000000,
+		Balance:      2100000,
+		AvgSpent:     -100000,
+		AvgSpentLeft: 95454,
+		DaysLeft:     21,
+	}
+	if got != want {
+		t.Errorf("CalculateStatisticAt() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	DefaultTTL   = 1 * time.Minute
+	DefaultStale = 5 * time.Minute
+
+	// fetchTimeout limits requests shared by concurrent callers, which are not bound to context of any of them.
+	fetchTimeout = 30 * time.Second
+)
+
+type Logger interface {
+	Warnw(msg string, keysAndValues ...interface{})
+}
+
+type Config struct {
+	// TTL is how long responses are served without asking YNAB.
+	TTL time.Duration
+	// Stale is how long after TTL responses are still served while they are refreshed in background.
+	Stale time.Duration
+}
+
+type entry struct {
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// Client is a telegram.YNABClient caching responses of the next client.
+// Concurrent requests for the same data are de-duplicated, errors are not cached.
+type Client struct {
+	next telegram.YNABClient
+	cfg  Config
+	now  func() time.Time
+	log  Logger
+
+	group   singleflight.Group
+	mu      sync.RWMutex
+	entries map[string]entry
+	// generation is incremented on invalidation. Responses fetched before it are not cached
+	// and new requests don't join requests in flight, which may return data read before the write.
+	generation uint64
+}
+
+func New(next telegram.YNABClient, cfg Config, log Logger) *Client {
+	return &Client{
+		next:    next,
+		cfg:     cfg,
+		now:     time.Now,
+		log:     log,
+		entries: make(map[string]entry),
+	}
+}
+
+// WithClock replaces the clock used to expire entries.
+func (c *Client) WithClock(now func() time.Time) *Client {
+	c.now = now
+	return c
+}
+
+func (c *Client) GetCategory(ctx context.Context, budgetID, categoryID string) (*ynab.Category, error) {
+	res, err := c.get(ctx, categoryKey(budgetID, categoryID), func(ctx context.Context) (interface{}, error) {
+		return c.next.GetCategory(ctx, budgetID, categoryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cat, _ := res.(*ynab.Category)
+	return cat, nil
+}
+
+func (c *Client) GetCategories(ctx context.Context, budgetID string) ([]ynab.CategoryGroup, error) {
+	res, err := c.get(ctx, categoriesKey(budgetID), func(ctx context.Context) (interface{}, error) {
+		return c.next.GetCategories(ctx, budgetID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups, _ := res.([]ynab.CategoryGroup)
+	return groups, nil
+}
+
+// GetCategoryTransactions is not cached, transactions are only requested on demand.
+func (c *Client) GetCategoryTransactions(
+	ctx context.Context, budgetID, categoryID string, since time.Time,
+) ([]ynab.Transaction, error) {
+	return c.next.GetCategoryTransactions(ctx, budgetID, categoryID, since)
+}
+
+// GetCategoriesDelta is not cached. Changed categories are dropped from cache, so they are fetched again.
+func (c *Client) GetCategoriesDelta(
+	ctx context.Context, budgetID string, lastKnowledge int64,
+) ([]ynab.CategoryGroup, int64, error) {
+	groups, knowledge, err := c.next.GetCategoriesDelta(ctx, budgetID, lastKnowledge)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, g := range groups {
+		for _, cat := range g.Categories {
+			c.Invalidate(budgetID, cat.ID)
+		}
+	}
+
+	return groups, knowledge, nil
+}
+
+// GetCurrentMonthCategory is not cached, it is read before changing the budget.
+func (c *Client) GetCurrentMonthCategory(ctx context.Context, budgetID, categoryID string) (*ynab.Category, error) {
+	return c.next.GetCurrentMonthCategory(ctx, budgetID, categoryID)
+}
+
+// SetCurrentMonthBudgeted invalidates the category and the list of categories of the budget.
+func (c *Client) SetCurrentMonthBudgeted(
+	ctx context.Context, budgetID, categoryID string, budgeted int,
+) (*ynab.Category, error) {
+	cat, err := c.next.SetCurrentMonthBudgeted(ctx, budgetID, categoryID, budgeted)
+	c.Invalidate(budgetID, categoryID)
+
+	return cat, err
+}
+
+// GetTransactions is not cached, transactions are only requested on demand.
+func (c *Client) GetTransactions(ctx context.Context, budgetID string, since time.Time) ([]ynab.Transaction, error) {
+	return c.next.GetTransactions(ctx, budgetID, since)
+}
+
+// GetTransactionsDelta is not cached, delta of transactions is only requested by alerts.
+func (c *Client) GetTransactionsDelta(
+	ctx context.Context, budgetID string, since time.Time, lastKnowledge int64,
+) ([]ynab.Transaction, int64, error) {
+	return c.next.GetTransactionsDelta(ctx, budgetID, since, lastKnowledge)
+}
+
+// GetTransaction is not cached, transactions are only requested on demand.
+func (c *Client) GetTransaction(ctx context.Context, budgetID, transactionID string) (*ynab.Transaction, error) {
+	return c.next.GetTransaction(ctx, budgetID, transactionID)
+}
+
+// CreateTransaction invalidates category of the transaction and the list of categories of the budget.
+func (c *Client) CreateTransaction(
+	ctx context.Context, budgetID string, tx ynab.NewTransaction,
+) (*ynab.Transaction, error) {
+	created, err := c.next.CreateTransaction(ctx, budgetID, tx)
+	c.Invalidate(budgetID, tx.CategoryID)
+
+	return created, err
+}
+
+// UpdateTransaction invalidates the budget, previous category of the transaction is not known.
+func (c *Client) UpdateTransaction(
+	ctx context.Context, budgetID, transactionID string, tx ynab.NewTransaction,
+) (*ynab.Transaction, error) {
+	updated, err := c.next.UpdateTransaction(ctx, budgetID, transactionID, tx)
+	c.InvalidateBudget(budgetID)
+
+	return updated, err
+}
+
+// UpdateTransactionFields invalidates the budget, previous category of the transaction is not known.
+func (c *Client) UpdateTransactionFields(
+	ctx context.Context, budgetID, transactionID string, upd ynab.TransactionUpdate,
+) (*ynab.Transaction, error) {
+	updated, err := c.next.UpdateTransactionFields(ctx, budgetID, transactionID, upd)
+	c.InvalidateBudget(budgetID)
+
+	return updated, err
+}
+
+// DeleteTransaction invalidates the budget, category of the transaction is not known.
+func (c *Client) DeleteTransaction(ctx context.Context, budgetID, transactionID string) error {
+	err := c.next.DeleteTransaction(ctx, budgetID, transactionID)
+	c.InvalidateBudget(budgetID)
+
+	return err
+}
+
+func (c *Client) GetAccounts(ctx context.Context, budgetID string) ([]ynab.Account, error) {
+	res, err := c.get(ctx, accountsKey(budgetID), func(ctx context.Context) (interface{}, error) {
+		return c.next.GetAccounts(ctx, budgetID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, _ := res.([]ynab.Account)
+	return accounts, nil
+}
+
+// Invalidate drops cached category and the list of categories of the budget.
+// It must be called after write operations changing the category.
+func (c *Client) Invalidate(budgetID, categoryID string) {
+	c.invalidate(func(key string) bool {
+		return key == categoryKey(budgetID, categoryID) || key == categoriesKey(budgetID)
+	})
+}
+
+// InvalidateBudget drops all cached data of the budget.
+func (c *Client) InvalidateBudget(budgetID string) {
+	c.invalidate(func(key string) bool {
+		return strings.HasPrefix(key, budgetID+"/")
+	})
+}
+
+func (c *Client) invalidate(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key := range c.entries {
+		if match(key) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// get returns fresh cached value or fetches it. Stale value is returned right away and refreshed in background.
+func (c *Client) get(
+	ctx context.Context, key string, fetch func(context.Context) (interface{}, error),
+) (interface{}, error) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if ok {
+		age := c.now().Sub(e.fetchedAt)
+		switch {
+		case age < c.cfg.TTL:
+			return e.value, nil
+		case age < c.cfg.TTL+c.cfg.Stale:
+			go c.refresh(key, generation, fetch)
+			return e.value, nil
+		}
+	}
+
+	return c.fetch(ctx, key, generation, fetch)
+}
+
+func (c *Client) refresh(key string, generation uint64, fetch func(context.Context) (interface{}, error)) {
+	if _, err := c.fetch(context.Background(), key, generation, fetch); err != nil {
+		c.log.Warnw("failed to refresh cached value", "key", key, "error", err)
+	}
+}
+
+// fetch calls the next client once for all concurrent requests of the same key and generation.
+// Shared request doesn't use ctx of the caller which started it, so cancelling one caller doesn't fail the others.
+// Every caller stops waiting when its own ctx is done.
+func (c *Client) fetch(
+	ctx context.Context, key string, generation uint64, fetch func(context.Context) (interface{}, error),
+) (interface{}, error) {
+	ch := c.group.DoChan(strconv.FormatUint(generation, 10)+":"+key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+
+		v, fetchErr := fetch(fetchCtx)
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+
+		c.mu.Lock()
+		if c.generation == generation {
+			c.entries[key] = entry{value: v, fetchedAt: c.now()}
+		}
+		c.mu.Unlock()
+
+		return v, nil
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func categoryKey(budgetID, categoryID string) string {
+	return budgetID + "/category/" + categoryID
+}
+
+func categoriesKey(budgetID string) string {
+	return budgetID + "/categories"
+}
+
+func accountsKey(budgetID string) string {
+	return budgetID + "/accounts"
+}
@@ -0,0 +1,296 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/cache"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+type countingClient struct {
+	calls   atomic.Int32
+	balance atomic.Int64
+	err     error
+	release chan struct{}
+}
+
+func (c *countingClient) GetCategory(ctx context.Context, _, categoryID string) (*ynab.Category, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &ynab.Category{ID: categoryID, Balance: int(c.balance.Load())}, nil
+}
+
+func (c *countingClient) GetCategories(context.Context, string) ([]ynab.CategoryGroup, error) {
+	c.calls.Add(1)
+	return []ynab.CategoryGroup{{ID: "g"}}, nil
+}
+
+func (c *countingClient) GetCategoryTransactions(
+	context.Context, string, string, time.Time,
+) ([]ynab.Transaction, error) {
+	return nil, nil
+}
+
+func (c *countingClient) GetCategoriesDelta(
+	_ context.Context, _ string, lastKnowledge int64,
+) ([]ynab.CategoryGroup, int64, error) {
+	return []ynab.CategoryGroup{{ID: "g", Categories: []ynab.Category{{ID: "c"}}}}, lastKnowledge + 1, nil
+}
+
+func (c *countingClient) GetCurrentMonthCategory(_ context.Context, _, categoryID string) (*ynab.Category, error) {
+	return &ynab.Category{ID: categoryID, Balance: int(c.balance.Load())}, nil
+}
+
+func (c *countingClient) SetCurrentMonthBudgeted(
+	_ context.Context, _, categoryID string, budgeted int,
+) (*ynab.Category, error) {
+	c.balance.Store(int64(budgeted))
+	return &ynab.Category{ID: categoryID, Budgeted: budgeted, Balance: budgeted}, nil
+}
+
+func (c *countingClient) GetTransactions(context.Context, string, time.Time) ([]ynab.Transaction, error) {
+	return nil, nil
+}
+
+func (c *countingClient) GetTransactionsDelta(
+	_ context.Context, _ string, _ time.Time, lastKnowledge int64,
+) ([]ynab.Transaction, int64, error) {
+	return nil, lastKnowledge, nil
+}
+
+func (c *countingClient) GetTransaction(context.Context, string, string) (*ynab.Transaction, error) {
+	return nil, nil
+}
+
+func (c *countingClient) CreateTransaction(
+	_ context.Context, _ string, tx ynab.NewTransaction,
+) (*ynab.Transaction, error) {
+	c.balance.Add(int64(tx.Amount))
+	return &ynab.Transaction{ID: "t", Amount: tx.Amount, CategoryID: tx.CategoryID}, nil
+}
+
+func (c *countingClient) UpdateTransaction(
+	_ context.Context, _, transactionID string, tx ynab.NewTransaction,
+) (*ynab.Transaction, error) {
+	return &ynab.Transaction{ID: transactionID, Amount: tx.Amount, CategoryID: tx.CategoryID}, nil
+}
+
+func (c *countingClient) UpdateTransactionFields(
+	_ context.Context, _, transactionID string, upd ynab.TransactionUpdate,
+) (*ynab.Transaction, error) {
+	return &ynab.Transaction{ID: transactionID, CategoryID: upd.CategoryID, Memo: upd.Memo}, nil
+}
+
+func (c *countingClient) DeleteTransaction(context.Context, string, string) error {
+	c.balance.Add(30)
+	return nil
+}
+
+func (c *countingClient) GetAccounts(context.Context, string) ([]ynab.Account, error) {
+	c.calls.Add(1)
+	return []ynab.Account{{ID: "a"}}, nil
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	next := &countingClient{}
+	clk := &clock{now: time.Now()}
+	c := cache.New(next, cache.Config{TTL: time.Minute, Stale: time.Minute}, zap.NewNop().Sugar()).WithClock(clk.Now)
+
+	next.balance.Store(100)
+	cat, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 100, cat.Balance)
+
+	next.balance.Store(200)
+	cat, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 100, cat.Balance, "fresh value is cached")
+	assert.Equal(t, int32(1), next.calls.Load())
+
+	clk.Add(90 * time.Second)
+	cat, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 100, cat.Balance, "stale value is served")
+	assert.Eventually(t, func() bool {
+		cat, err = c.GetCategory(ctx, "b", "c")
+		return err == nil && cat.Balance == 200
+	}, time.Second, time.Millisecond, "stale value is refreshed in background")
+
+	next.balance.Store(300)
+	clk.Add(3 * time.Minute)
+	cat, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 300, cat.Balance, "expired value is fetched")
+
+	next.balance.Store(400)
+	c.Invalidate("b", "c")
+	cat, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 400, cat.Balance, "invalidated value is fetched")
+
+	groups, err := c.GetCategories(ctx, "b")
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
+	calls := next.calls.Load()
+	c.InvalidateBudget("b")
+	_, err = c.GetCategories(ctx, "b")
+	require.NoError(t, err)
+	_, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, calls+2, next.calls.Load(), "all data of the budget is invalidated")
+}
+
+func TestClient_GetCategoriesDelta(t *testing.T) {
+	ctx := context.Background()
+	next := &countingClient{}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	next.balance.Store(100)
+	_, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+
+	next.balance.Store(200)
+	_, knowledge, err := c.GetCategoriesDelta(ctx, "b", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), knowledge)
+
+	cat, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 200, cat.Balance, "changed category is fetched")
+}
+
+func TestClient_SetCurrentMonthBudgeted(t *testing.T) {
+	ctx := context.Background()
+	next := &countingClient{}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	next.balance.Store(100)
+	_, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+
+	_, err = c.SetCurrentMonthBudgeted(ctx, "b", "c", 300)
+	require.NoError(t, err)
+	cat, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 300, cat.Balance, "changed category is fetched")
+}
+
+func TestClient_CreateTransaction(t *testing.T) {
+	ctx := context.Background()
+	next := &countingClient{}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	next.balance.Store(100)
+	_, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	_, err = c.GetAccounts(ctx, "b")
+	require.NoError(t, err)
+	_, err = c.GetAccounts(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), next.calls.Load(), "accounts are cached")
+
+	_, err = c.CreateTransaction(ctx, "b", ynab.NewTransaction{CategoryID: "c", Amount: -30})
+	require.NoError(t, err)
+	cat, err := c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 70, cat.Balance, "category of the transaction is fetched")
+
+	require.NoError(t, c.DeleteTransaction(ctx, "b", "t"))
+	cat, err = c.GetCategory(ctx, "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 100, cat.Balance, "budget is fetched again after delete")
+}
+
+func TestClient_SingleFlight(t *testing.T) {
+	next := &countingClient{release: make(chan struct{})}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetCategory(context.Background(), "b", "c")
+			assert.NoError(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestClient_SingleFlightCancel(t *testing.T) {
+	next := &countingClient{release: make(chan struct{})}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.GetCategory(ctx, "b", "c")
+		first <- err
+	}()
+	assert.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan error)
+	go func() {
+		_, err := c.GetCategory(context.Background(), "b", "c")
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled, "caller gives up on its own context")
+	close(next.release)
+	assert.NoError(t, <-second, "joined caller is not failed by cancelled one")
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestClient_ErrorsAreNotCached(t *testing.T) {
+	next := &countingClient{err: errors.New("boom")}
+	c := cache.New(next, cache.Config{TTL: time.Minute}, zap.NewNop().Sugar())
+
+	_, err := c.GetCategory(context.Background(), "b", "c")
+	assert.ErrorIs(t, err, next.err)
+	_, err = c.GetCategory(context.Background(), "b", "c")
+	assert.ErrorIs(t, err, next.err)
+	assert.Equal(t, int32(2), next.calls.Load())
+}
@@ -0,0 +1,270 @@
+// Package chart renders spending charts as PNG images.
+//
+// Only ASCII text can be drawn with the built-in font, so charts are labeled with numbers
+// and names are expected to be listed in the message the chart is sent with.
+package chart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	width  = 800
+	height = 600
+
+	marginLeft  = 70
+	marginRight = 20
+	lineTop     = 20
+	lineBottom  = 330
+	barsTop     = 380
+	barsBottom  = 560
+
+	yTicks      = 5
+	dayTicks    = 5
+	headroom    = 1.1
+	lineWidth   = 3
+	dashLength  = 8
+	textHeight  = 13
+	charWidth   = 7
+	labelMargin = 6
+
+	milliunitsPerUnit = 1000
+	dateLayout        = "2006-01-02"
+)
+
+// Month is spending of a category during the month.
+type Month struct {
+	// Days is the number of days in the month.
+	Days int
+	// Available is the amount available for the month in YNAB milliunits.
+	Available int
+	// Spent holds amounts spent on every day of the month so far, Spent[0] is the first day.
+	Spent []int
+}
+
+// Bar is a bar of the breakdown chart. Label must be ASCII.
+type Bar struct {
+	Label string
+	Value int
+}
+
+// MonthOf builds month of the category from its statistic and transactions. Outflows are spent,
+// inflows reduce spending of the day. Transactions made outside of the month of today or after today are ignored.
+func MonthOf(s budget.GeneralCategoryStatistic, txs []ynab.Transaction, today time.Time) Month {
+	first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	m := Month{
+		Days:      first.AddDate(0, 1, -1).Day(),
+		Available: s.Balance - s.Activity,
+		Spent:     make([]int, today.Day()),
+	}
+	for _, tx := range txs {
+		date, err := time.Parse(dateLayout, tx.Date)
+		if err != nil || tx.Deleted || date.Before(first) || date.Day() > today.Day() || date.Month() != first.Month() {
+			continue
+		}
+		m.Spent[date.Day()-1] -= tx.Amount
+	}
+
+	return m
+}
+
+// Render writes PNG image with cumulative spending of the month against the ideal line reaching available amount
+// at the end of the month. Bars of breakdown are drawn below it if there are any.
+func Render(w io.Writer, m Month, bars []Bar) error {
+	c := newCanvas()
+	c.month(m)
+	if len(bars) > 0 {
+		c.bars(bars)
+	}
+
+	if err := png.Encode(w, c.img); err != nil {
+		return fmt.Errorf("encoding png: %w", err)
+	}
+	return nil
+}
+
+type canvas struct {
+	img *image.RGBA
+
+	axis   color.Color
+	grid   color.Color
+	text   color.Color
+	spent  color.Color
+	ideal  color.Color
+	over   color.Color
+	barCol color.Color
+}
+
+func newCanvas() *canvas {
+	c := &canvas{
+		img:    image.NewRGBA(image.Rect(0, 0, width, height)),
+		axis:   color.RGBA{R: 0x66, G: 0x66, B: 0x66, A: 0xff},
+		grid:   color.RGBA{R: 0xe5, G: 0xe5, B: 0xe5, A: 0xff},
+		text:   color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff},
+		spent:  color.RGBA{R: 0x2e, G: 0x86, B: 0xde, A: 0xff},
+		ideal:  color.RGBA{R: 0x27, G: 0xae, B: 0x60, A: 0xff},
+		over:   color.RGBA{R: 0xe7, G: 0x4c, B: 0x3c, A: 0xff},
+		barCol: color.RGBA{R: 0xf3, G: 0x9c, B: 0x12, A: 0xff},
+	}
+	draw.Draw(c.img, c.img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	return c
+}
+
+// month draws cumulative spending line chart. X axis is the time from the start to the end of the month.
+func (c *canvas) month(m Month) {
+	days := m.Days
+	if days < 1 {
+		days = 1
+	}
+	cumulative := make([]int, len(m.Spent)+1)
+	for i, spent := range m.Spent {
+		cumulative[i+1] = cumulative[i] + spent
+	}
+
+	top := m.Available
+	for _, v := range cumulative {
+		top = maxInt(top, v)
+	}
+	top = maxInt(int(float64(top)*headroom), 1)
+
+	x := func(day int) int {
+		return marginLeft + day*(width-marginLeft-marginRight)/days
+	}
+	y := func(amount int) int {
+		return lineBottom - int(int64(amount)*(lineBottom-lineTop)/int64(top))
+	}
+
+	for i := 0; i <= yTicks; i++ {
+		amount := top * i / yTicks
+		c.line(marginLeft, y(amount), width-marginRight, y(amount), 1, c.grid, false)
+		label := formatAmount(amount)
+		c.label(marginLeft-labelMargin-len(label)*charWidth, y(amount)+textHeight/3, label)
+	}
+	for day := 1; day <= days; day++ {
+		if day == 1 || day%dayTicks == 0 && days-day > 1 || day == days {
+			label := strconv.Itoa(day)
+			c.label(x(day)-len(label)*charWidth/2, lineBottom+textHeight+labelMargin, label)
+		}
+	}
+	c.line(marginLeft, lineTop, marginLeft, lineBottom, 1, c.axis, false)
+	c.line(marginLeft, lineBottom, width-marginRight, lineBottom, 1, c.axis, false)
+
+	if m.Available > 0 {
+		c.line(x(0), y(0), x(days), y(m.Available), lineWidth, c.ideal, true)
+	}
+	for day := 1; day < len(cumulative); day++ {
+		col := c.spent
+		if cumulative[day] > m.Available*day/days {
+			col = c.over
+		}
+		c.line(x(day-1), y(cumulative[day-1]), x(day), y(cumulative[day]), lineWidth, col, false)
+	}
+}
+
+// bars draws vertical bars with values above them and labels below.
+func (c *canvas) bars(bars []Bar) {
+	top := 1
+	for _, b := range bars {
+		top = maxInt(top, b.Value)
+	}
+
+	slot := (width - marginLeft - marginRight) / len(bars)
+	barWidth := slot * 2 / 3 //nolint: gomnd // bar takes 2/3 of the slot
+	chartHeight := barsBottom - barsTop - textHeight - labelMargin
+	for i, b := range bars {
+		h := 0
+		if b.Value > 0 {
+			h = int(int64(b.Value) * int64(chartHeight) / int64(top))
+		}
+		left := marginLeft + i*slot + (slot-barWidth)/2
+		rect := image.Rect(left, barsBottom-h, left+barWidth, barsBottom)
+		draw.Draw(c.img, rect, image.NewUniform(c.barCol), image.Point{}, draw.Src)
+
+		value := formatAmount(b.Value)
+		c.label(left+barWidth/2-len(value)*charWidth/2, barsBottom-h-labelMargin, value)
+		c.label(left+barWidth/2-len(b.Label)*charWidth/2, barsBottom+textHeight+labelMargin, b.Label)
+	}
+	c.line(marginLeft, barsBottom, width-marginRight, barsBottom, 1, c.axis, false)
+}
+
+// line draws line of the thickness. Dashed line skips every other dash.
+func (c *canvas) line(x0, y0, x1, y1, thickness int, col color.Color, dashed bool) {
+	steps := maxInt(absInt(x1-x0), absInt(y1-y0))
+	for i := 0; i <= steps; i++ {
+		if dashed && (i/dashLength)%2 == 1 {
+			continue
+		}
+		x, y := x0, y0
+		if steps > 0 {
+			x = x0 + (x1-x0)*i/steps
+			y = y0 + (y1-y0)*i/steps
+		}
+		for dx := 0; dx < thickness; dx++ {
+			for dy := 0; dy < thickness; dy++ {
+				c.img.Set(x+dx-thickness/2, y+dy-thickness/2, col)
+			}
+		}
+	}
+}
+
+// label draws text with baseline at y.
+func (c *canvas) label(x, y int, text string) {
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(c.text),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// formatAmount formats milliunits as whole units with thousands separated by commas, e.g. "1,235".
+func formatAmount(milliunits int) string {
+	const groupSize = 3
+
+	units := int(math.Round(float64(milliunits) / milliunitsPerUnit))
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	digits := strconv.Itoa(units)
+	res := ""
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%groupSize == 0 {
+			res += ","
+		}
+		res += string(digits[i])
+	}
+
+	return sign + res
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
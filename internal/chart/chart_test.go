@@ -0,0 +1,55 @@
+package chart_test
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/chart"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestMonthOf(t *testing.T) {
+	s := budget.GeneralCategoryStatistic{Budgeted: 1000000, Activity: -420000, Balance: 580000}
+	txs := []ynab.Transaction{
+		{Date: "2024-02-01", Amount: -100000},
+		{Date: "2024-02-03", Amount: -350000},
+		{Date: "2024-02-03", Amount: 30000},
+		{Date: "2024-02-04", Amount: -10000, Deleted: true},
+		{Date: "2024-01-31", Amount: -10000},
+		{Date: "2024-02-05", Amount: -10000},
+		{Date: "invalid", Amount: -10000},
+	}
+
+	m := chart.MonthOf(s, txs, time.Date(2024, 2, 4, 15, 0, 0, 0, time.UTC))
+	assert.Equal(t, chart.Month{Days: 29, Available: 1000000, Spent: []int{100000, 0, 320000, 0}}, m)
+}
+
+func TestRender(t *testing.T) {
+	months := map[string]chart.Month{
+		"spending":  {Days: 30, Available: 1000000, Spent: []int{100000, 0, 320000, 50000}},
+		"overspent": {Days: 31, Available: 100000, Spent: []int{500000, -20000}},
+		"empty":     {},
+	}
+	bars := []chart.Bar{{Label: "1", Value: 420000}, {Label: "2", Value: 120000}, {Label: "3", Value: 0}}
+
+	for name, m := range months {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, chart.Render(&buf, m, bars))
+
+			img, err := png.Decode(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, 800, img.Bounds().Dx())
+			assert.Equal(t, 600, img.Bounds().Dy())
+		})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, chart.Render(&buf, months["spending"], nil), "breakdown is optional")
+}
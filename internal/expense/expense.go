@@ -0,0 +1,349 @@
+package expense
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/suggest"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// ImportIDPrefix starts import IDs of transactions created from drafts. YNAB treats transactions with import ID
+// as imported, the prefix tells them from transactions imported from bank.
+const ImportIDPrefix = "bot:"
+
+const (
+	milliunitsInUnit = 1000
+	// minPrefix is the shortest word matched with beginning of account or category name.
+	minPrefix = 3
+)
+
+// dayWords returns words meaning a day relative to today with number of days back.
+func dayWords() map[string]int {
+	return map[string]int{
+		"today":        0,
+		"сьогодні":     0,
+		"dziś":         0,
+		"dzisiaj":      0,
+		"yesterday":    1,
+		"вчора":        1,
+		"wczoraj":      1,
+		"позавчора":    2, //nolint: gomnd // the day before yesterday
+		"przedwczoraj": 2, //nolint: gomnd // the day before yesterday
+	}
+}
+
+// dateLayouts returns layouts of dates written explicitly. Dates without year are in the last 12 months.
+func dateLayouts() []string {
+	return []string{"2.1.2006", "2/1/2006", "2.1", "2/1"}
+}
+
+// Expense is parsed from free text like "145.50 АТБ продукти вчора".
+type Expense struct {
+	// Amount is positive amount in milliunits.
+	Amount int
+	Date   time.Time
+	// Words are the rest of the text in original order. They name payee, category and account.
+	Words []string
+}
+
+// Draft is an expense matched with the budget. IDs are empty if nothing matches.
+type Draft struct {
+	Amount       int
+	Date         time.Time
+	Payee        string
+	CategoryID   string
+	CategoryName string
+	// Confidence is confidence of suggested category. It is zero if category is named explicitly.
+	Confidence  float64
+	AccountID   string
+	AccountName string
+}
+
+// ImportID returns import ID of the transaction created from the draft shown in the message, so the draft
+// confirmed twice doesn't create the transaction twice.
+func ImportID(chatID int64, messageID int) string {
+	return ImportIDPrefix + strconv.FormatInt(chatID, 10) + ":" + strconv.Itoa(messageID)
+}
+
+// ImportChatID returns ID of the chat the transaction with the import ID was created from and false
+// if the transaction wasn't created from a draft.
+func ImportChatID(importID string) (int64, bool) {
+	rest, ok := strings.CutPrefix(importID, ImportIDPrefix)
+	if !ok {
+		return 0, false
+	}
+	chat, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, false
+	}
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return chatID, true
+}
+
+// Resolver matches expenses with categories, accounts and recent transactions of the budget.
+type Resolver struct {
+	// Categories are categories money can be spent from.
+	Categories []ynab.Category
+	Accounts   []ynab.Account
+	// History is recent transactions used to guess account of the payee.
+	History []ynab.Transaction
+	// Suggester suggests category of the payee. It is learned from Categories and History if nil.
+	Suggester *suggest.Suggester
+}
+
+// Parse parses text with amount in any place. The first number is the amount, words like "yesterday"
+// or dates like "17.05" set the date. Date is today by default. It returns false if there is no amount.
+func Parse(text string, today time.Time) (Expense, bool) {
+	e := Expense{Date: dateOf(today)}
+	amountFound, dateFound := false, false
+	for _, word := range strings.Fields(text) {
+		if !amountFound {
+			if amount, ok := ParseAmount(word); ok {
+				e.Amount, amountFound = amount, true
+				continue
+			}
+		}
+		if !dateFound {
+			if date, ok := parseDate(word, today); ok {
+				e.Date, dateFound = date, true
+				continue
+			}
+		}
+		e.Words = append(e.Words, word)
+	}
+
+	return e, amountFound
+}
+
+// ParseAmount parses positive amount like "500" or "12,50" to milliunits. Only digits with up to 3 decimal places
+// are accepted, so words like "nan" or "1e3" which strconv.ParseFloat understands are not amounts.
+func ParseAmount(s string) (int, bool) {
+	if !isDecimal(s) {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, false
+	}
+	milliunits := math.Round(v * milliunitsInUnit)
+	if milliunits > math.MaxInt32 {
+		return 0, false
+	}
+
+	return int(milliunits), true
+}
+
+// isDecimal reports whether s is digits optionally followed by "." or "," and 1 to 3 digits.
+func isDecimal(s string) bool {
+	whole, fraction, hasFraction := strings.Cut(strings.ReplaceAll(s, ",", "."), ".")
+	if whole == "" || !isDigits(whole) {
+		return false
+	}
+	if !hasFraction {
+		return true
+	}
+
+	return fraction != "" && len(fraction) <= 3 && isDigits(fraction) //nolint: gomnd // milliunits
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Resolve picks account and category named by words of the expense. The rest of the words is the payee.
+// Category not named explicitly is suggested by payee, account is the one used for the payee last
+// or the most used one.
+func (r Resolver) Resolve(e Expense) Draft {
+	d := Draft{Amount: e.Amount, Date: e.Date}
+
+	var payee []string
+	var categoryWord string
+	for _, word := range e.Words {
+		if d.AccountID == "" {
+			if a := r.account(word); a != nil {
+				d.AccountID, d.AccountName = a.ID, a.Name
+				continue
+			}
+		}
+		if d.CategoryID == "" {
+			if c := r.category(word); c != nil {
+				d.CategoryID, d.CategoryName, categoryWord = c.ID, c.Name, word
+				continue
+			}
+		}
+		payee = append(payee, word)
+	}
+	d.Payee = strings.Join(payee, " ")
+	if d.Payee == "" {
+		d.Payee = categoryWord
+	}
+
+	txs := r.payeeHistory(d.Payee)
+	if len(txs) > 0 && strings.EqualFold(txs[0].PayeeName, d.Payee) {
+		d.Payee = txs[0].PayeeName
+	}
+	if d.CategoryID == "" {
+		suggester := r.Suggester
+		if suggester == nil {
+			suggester = suggest.New(r.Categories, r.History)
+		}
+		if best, ok := suggester.Best(d.Payee, ""); ok {
+			d.CategoryID, d.CategoryName, d.Confidence = best.CategoryID, best.CategoryName, best.Confidence
+		}
+	}
+	if d.AccountID == "" {
+		d.AccountID, d.AccountName = r.guessAccount(txs)
+	}
+
+	return d
+}
+
+func (r Resolver) account(word string) *ynab.Account {
+	var prefixed *ynab.Account
+	for i, a := range r.Accounts {
+		if a.Closed || a.Deleted {
+			continue
+		}
+		if strings.EqualFold(a.Name, word) {
+			return &r.Accounts[i]
+		}
+		if prefixed == nil && hasPrefixFold(a.Name, word) {
+			prefixed = &r.Accounts[i]
+		}
+	}
+
+	return prefixed
+}
+
+func (r Resolver) category(word string) *ynab.Category {
+	var prefixed *ynab.Category
+	for i, c := range r.Categories {
+		if strings.EqualFold(c.Name, word) {
+			return &r.Categories[i]
+		}
+		if prefixed == nil && hasPrefixFold(c.Name, word) {
+			prefixed = &r.Categories[i]
+		}
+	}
+
+	return prefixed
+}
+
+// payeeHistory returns transactions of the payee, the newest first. Transactions of payees which names contain
+// the payee (or vice versa) are returned if there are no transactions with exactly the same payee.
+func (r Resolver) payeeHistory(payee string) []ynab.Transaction {
+	if payee == "" {
+		return nil
+	}
+
+	var same, similar []ynab.Transaction
+	name := strings.ToLower(payee)
+	for _, tx := range r.History {
+		if tx.Deleted || tx.PayeeName == "" {
+			continue
+		}
+		other := strings.ToLower(tx.PayeeName)
+		switch {
+		case other == name:
+			same = append(same, tx)
+		case len([]rune(name)) >= minPrefix && (strings.Contains(other, name) || strings.Contains(name, other)):
+			similar = append(similar, tx)
+		}
+	}
+	if len(same) == 0 {
+		same = similar
+	}
+	// Dates are formatted as "2006-01-02", so they are sorted as strings.
+	sort.SliceStable(same, func(i, j int) bool {
+		return same[i].Date > same[j].Date
+	})
+
+	return same
+}
+
+// guessAccount returns account of the newest transaction or the most used account if there are no transactions.
+// The first open account is returned if there is no history at all.
+func (r Resolver) guessAccount(txs []ynab.Transaction) (string, string) {
+	for _, tx := range txs {
+		if a := r.openAccount(tx.AccountID); a != nil {
+			return a.ID, a.Name
+		}
+	}
+
+	count := make(map[string]int)
+	var best *ynab.Account
+	for _, tx := range r.History {
+		a := r.openAccount(tx.AccountID)
+		if a == nil || tx.Deleted {
+			continue
+		}
+		count[a.ID]++
+		if best == nil || count[a.ID] > count[best.ID] {
+			best = a
+		}
+	}
+	if best != nil {
+		return best.ID, best.Name
+	}
+	for _, a := range r.Accounts {
+		if !a.Closed && !a.Deleted {
+			return a.ID, a.Name
+		}
+	}
+
+	return "", ""
+}
+
+func (r Resolver) openAccount(id string) *ynab.Account {
+	for i, a := range r.Accounts {
+		if a.ID == id && !a.Closed && !a.Deleted {
+			return &r.Accounts[i]
+		}
+	}
+
+	return nil
+}
+
+func parseDate(word string, today time.Time) (time.Time, bool) {
+	if days, ok := dayWords()[strings.ToLower(word)]; ok {
+		return dateOf(today).AddDate(0, 0, -days), true
+	}
+
+	for _, layout := range dateLayouts() {
+		d, err := time.ParseInLocation(layout, word, today.Location())
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			d = d.AddDate(today.Year(), 0, 0)
+			if d.After(today) {
+				d = d.AddDate(-1, 0, 0)
+			}
+		}
+		return d, true
+	}
+
+	return time.Time{}, false
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len([]rune(prefix)) >= minPrefix && strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
@@ -0,0 +1,197 @@
+package expense_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestParse(t *testing.T) {
+	today := time.Date(2024, time.May, 10, 15, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		want   expense.Expense
+		wantOK bool
+	}{
+		{
+			name:   "amount after payee",
+			text:   "кава 65",
+			want:   expense.Expense{Amount: 65000, Date: day(time.May, 10), Words: []string{"кава"}},
+			wantOK: true,
+		},
+		{
+			name: "decimal amount and day word",
+			text: "145.50 АТБ продукти вчора",
+			want: expense.Expense{
+				Amount: 145500, Date: day(time.May, 9), Words: []string{"АТБ", "продукти"},
+			},
+			wantOK: true,
+		},
+		{
+			name:   "comma and date",
+			text:   "taxi 230,4 card 07.05",
+			want:   expense.Expense{Amount: 230400, Date: day(time.May, 7), Words: []string{"taxi", "card"}},
+			wantOK: true,
+		},
+		{
+			name:   "date without year is in the past",
+			text:   "12 gift 24.12",
+			want:   expense.Expense{Amount: 12000, Date: time.Date(2023, time.December, 24, 0, 0, 0, 0, time.UTC), Words: []string{"gift"}},
+			wantOK: true,
+		},
+		{
+			name:   "only the first number is amount",
+			text:   "Wczoraj 20 bus 2",
+			want:   expense.Expense{Amount: 20000, Date: day(time.May, 9), Words: []string{"bus", "2"}},
+			wantOK: true,
+		},
+		{
+			name:   "float syntax is not amount",
+			text:   "nan 1e3 0x10 1_000 coffee 65",
+			want:   expense.Expense{Amount: 65000, Date: day(time.May, 10), Words: []string{"nan", "1e3", "0x10", "1_000", "coffee"}},
+			wantOK: true,
+		},
+		{
+			name: "no amount",
+			text: "hello there",
+		},
+		{
+			name: "negative amount",
+			text: "refund -20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := expense.Parse(tt.text, today)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{in: "500", want: 500000, ok: true},
+		{in: "12,50", want: 12500, ok: true},
+		{in: "0.005", want: 5, ok: true},
+		{in: "0", ok: false},
+		{in: "nan", ok: false},
+		{in: "NaN", ok: false},
+		{in: "inf", ok: false},
+		{in: "1e3", ok: false},
+		{in: "0x10", ok: false},
+		{in: "0x1p4", ok: false},
+		{in: "1_000", ok: false},
+		{in: "-5", ok: false},
+		{in: "+5", ok: false},
+		{in: "5.", ok: false},
+		{in: ".5", ok: false},
+		{in: "1.2345", ok: false},
+		{in: "1,2,3", ok: false},
+		{in: "99999999", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := expense.ParseAmount(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestImportChatID(t *testing.T) {
+	chatID, ok := expense.ImportChatID(expense.ImportID(-100123, 42))
+	assert.True(t, ok)
+	assert.Equal(t, int64(-100123), chatID)
+
+	for _, importID := range []string{"", "YNAB:-65000:2024-05-07:1", "bot:", "bot:chat:42"} {
+		_, ok = expense.ImportChatID(importID)
+		assert.False(t, ok, importID)
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	r := expense.Resolver{
+		Categories: []ynab.Category{
+			{ID: "c-groceries", Name: "Продукти"},
+			{ID: "c-cafe", Name: "Cafe"},
+			{ID: "c-transport", Name: "Transport"},
+		},
+		Accounts: []ynab.Account{
+			{ID: "a-card", Name: "Card"},
+			{ID: "a-cash", Name: "Cash"},
+			{ID: "a-old", Name: "Cardiff", Closed: true},
+		},
+		History: []ynab.Transaction{
+			{Date: "2024-05-01", PayeeName: "Кава", CategoryID: "c-cafe", CategoryName: "Cafe", AccountID: "a-cash"},
+			{Date: "2024-05-03", PayeeName: "кава", CategoryID: "c-groceries", CategoryName: "Продукти", AccountID: "a-card"},
+			{Date: "2024-05-04", PayeeName: "Кава", CategoryID: "c-cafe", CategoryName: "Cafe", AccountID: "a-card"},
+			{Date: "2024-05-05", PayeeName: "Uber Taxi", CategoryID: "c-transport", CategoryName: "Transport", AccountID: "a-card"},
+			{Date: "2024-05-06", PayeeName: "АТБ", CategoryID: "c-groceries", CategoryName: "Продукти", AccountID: "a-cash"},
+		},
+	}
+	date := time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		words []string
+		want  expense.Draft
+	}{
+		{
+			name:  "category is suggested and account is guessed from payee",
+			words: []string{"КАВА"},
+			want: expense.Draft{
+				Payee: "Кава", CategoryID: "c-cafe", CategoryName: "Cafe", Confidence: 0.5,
+				AccountID: "a-card", AccountName: "Card",
+			},
+		},
+		{
+			name:  "category is named",
+			words: []string{"АТБ", "продукти"},
+			want: expense.Draft{
+				Payee: "АТБ", CategoryID: "c-groceries", CategoryName: "Продукти", AccountID: "a-cash", AccountName: "Cash",
+			},
+		},
+		{
+			name:  "account is named and payee is similar",
+			words: []string{"taxi", "card"},
+			want: expense.Draft{
+				Payee: "taxi", CategoryID: "c-transport", CategoryName: "Transport", Confidence: 0.5,
+				AccountID: "a-card", AccountName: "Card",
+			},
+		},
+		{
+			name:  "unknown payee",
+			words: []string{"Gift", "shop"},
+			want:  expense.Draft{Payee: "Gift shop", AccountID: "a-card", AccountName: "Card"},
+		},
+		{
+			name:  "category is payee if nothing else is given",
+			words: []string{"transport"},
+			want: expense.Draft{
+				Payee: "transport", CategoryID: "c-transport", CategoryName: "Transport", AccountID: "a-card", AccountName: "Card",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Amount, tt.want.Date = 1000, date
+			assert.Equal(t, tt.want, r.Resolve(expense.Expense{Amount: 1000, Date: date, Words: tt.words}))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// telegramCheckInterval limits how often Telegram is asked for getMe, so frequent probes don't hit its API.
+const telegramCheckInterval = time.Minute
+
+// Checker reports liveness and readiness of the bot.
+//
+// Bot is ready when Telegram getMe succeeds and bot is not stopping. Tenants whose last YNAB request was
+// unauthorized are reported in details of readiness, but don't make the bot not ready: their token is revoked
+// or expired, and restarting the bot doesn't help other tenants.
+type Checker struct {
+	checkTelegram func(ctx context.Context) error
+	now           func() time.Time
+
+	mu                sync.Mutex
+	telegramCheckedAt time.Time
+	telegramErr       error
+	// unauthorized are IDs of tenants whose last YNAB request was unauthorized.
+	unauthorized map[string]bool
+	stopping     bool
+}
+
+func NewChecker(checkTelegram func(ctx context.Context) error) *Checker {
+	return &Checker{
+		checkTelegram: checkTelegram,
+		now:           time.Now,
+		unauthorized:  make(map[string]bool),
+	}
+}
+
+// Tenant returns observer of YNAB requests of the tenant.
+func (c *Checker) Tenant(tenantID string) ynab.Observer {
+	return tenantObserver{checker: c, tenantID: tenantID}
+}
+
+// UnauthorizedTenants returns sorted IDs of tenants whose last YNAB request was unauthorized.
+func (c *Checker) UnauthorizedTenants() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := make([]string, 0, len(c.unauthorized))
+	for tenantID := range c.unauthorized {
+		res = append(res, tenantID)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+type tenantObserver struct {
+	checker  *Checker
+	tenantID string
+}
+
+// ObserveRequest implements ynab.Observer.
+func (o tenantObserver) ObserveRequest(_ string, _ int, _ time.Duration, err error) {
+	o.checker.mu.Lock()
+	defer o.checker.mu.Unlock()
+
+	if errors.Is(err, ynab.ErrUnauthorized) {
+		o.checker.unauthorized[o.tenantID] = true
+	} else {
+		delete(o.checker.unauthorized, o.tenantID)
+	}
+}
+
+// MarkStopping makes bot not ready, so no new traffic is routed to it while it shuts down.
+func (c *Checker) MarkStopping() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopping = true
+}
+
+// Ready returns nil if bot is ready to serve requests.
+// Lock isn't held during getMe, so slow Telegram doesn't block observing YNAB requests.
+func (c *Checker) Ready(ctx context.Context) error {
+	c.mu.Lock()
+	stopping := c.stopping
+	checkedAt, telegramErr := c.telegramCheckedAt, c.telegramErr
+	c.mu.Unlock()
+
+	if stopping {
+		return fmt.Errorf("bot is stopping")
+	}
+
+	if now := c.now(); now.Sub(checkedAt) >= telegramCheckInterval || telegramErr != nil {
+		telegramErr = c.checkTelegram(ctx)
+
+		c.mu.Lock()
+		c.telegramErr, c.telegramCheckedAt = telegramErr, now
+		c.mu.Unlock()
+	}
+	if telegramErr != nil {
+		return fmt.Errorf("telegram getMe failed: %w", telegramErr)
+	}
+
+	return nil
+}
+
+// Register adds /healthz and /readyz endpoints to mux. /readyz lists tenants with unauthorized YNAB access.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := c.Ready(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		body := "ok"
+		if tenants := c.UnauthorizedTenants(); len(tenants) > 0 {
+			body += "\nYNAB access is unauthorized for tenants: " + strings.Join(tenants, ", ")
+		}
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+// Probe requests url and returns error unless it responds with 200 OK.
+// It is used by healthcheck mode of the binary, so Docker HEALTHCHECK works without curl in the image.
+func Probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("doing request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
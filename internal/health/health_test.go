@@ -0,0 +1,104 @@
+package health_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/health"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestChecker(t *testing.T) {
+	telegramCalls := 0
+	c := health.NewChecker(func(context.Context) error {
+		telegramCalls++
+		return nil
+	})
+
+	mux := http.NewServeMux()
+	c.Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	probe := func(path string) error {
+		return health.Probe(context.Background(), server.URL+path)
+	}
+
+	assert.NoError(t, probe("/healthz"))
+	assert.NoError(t, probe("/readyz"))
+	assert.NoError(t, probe("/readyz"))
+	assert.Equal(t, 1, telegramCalls, "telegram check is cached")
+
+	readyz := func() string {
+		resp, err := http.Get(server.URL + "/readyz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return string(body)
+	}
+	unauthorized := fmt.Errorf("wrapped: %w", ynab.ErrUnauthorized)
+	c.Tenant("b").ObserveRequest("GetCategory", http.StatusUnauthorized, 0, unauthorized)
+	c.Tenant("a").ObserveRequest("GetCategory", http.StatusUnauthorized, 0, unauthorized)
+	c.Tenant("c").ObserveRequest("GetCategory", http.StatusOK, 0, nil)
+	assert.Equal(t, []string{"a", "b"}, c.UnauthorizedTenants())
+	assert.Equal(t, "ok\nYNAB access is unauthorized for tenants: a, b", readyz(),
+		"unauthorized tenants don't make the bot not ready")
+
+	c.Tenant("a").ObserveRequest("GetCategory", http.StatusOK, 0, nil)
+	c.Tenant("b").ObserveRequest("GetCategory", http.StatusOK, 0, nil)
+	assert.Empty(t, c.UnauthorizedTenants())
+	assert.Equal(t, "ok", readyz())
+
+	c.MarkStopping()
+	assert.Error(t, probe("/readyz"))
+	assert.NoError(t, probe("/healthz"))
+}
+
+func TestChecker_TelegramFailure(t *testing.T) {
+	telegramErr := fmt.Errorf("boom")
+	c := health.NewChecker(func(context.Context) error {
+		return telegramErr
+	})
+
+	assert.EqualError(t, c.Ready(context.Background()), "telegram getMe failed: boom")
+
+	telegramErr = nil
+	assert.NoError(t, c.Ready(context.Background()), "failed check is retried")
+}
+
+func TestChecker_SlowTelegram(t *testing.T) {
+	release := make(chan struct{})
+	c := health.NewChecker(func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	ready := make(chan error)
+	go func() {
+		ready <- c.Ready(context.Background())
+	}()
+
+	observed := make(chan struct{})
+	go func() {
+		c.Tenant("a").ObserveRequest("GetCategory", http.StatusOK, 0, nil)
+		close(observed)
+	}()
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Fatal("YNAB request is blocked by Telegram check")
+	}
+
+	close(release)
+	assert.NoError(t, <-ready)
+}
@@ -0,0 +1,226 @@
+// Package i18n translates bot messages and formats numbers according to the language of the chat.
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	Ukrainian Lang = "uk"
+	English   Lang = "en"
+	Polish    Lang = "pl"
+
+	// fallback is used for messages missing in the catalog of the language.
+	fallback = English
+)
+
+// ParseLang parses language code like "uk" or IETF tag like "en-US". It returns false for unsupported languages.
+func ParseLang(code string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(code), "-")
+	switch Lang(base) {
+	case Ukrainian, English, Polish:
+		return Lang(base), true
+	default:
+		return "", false
+	}
+}
+
+// Plural is a message having different forms for different numbers. Forms are named after CLDR plural categories.
+type Plural struct {
+	One   string
+	Few   string
+	Many  string
+	Other string
+}
+
+// Catalog holds messages of all supported languages.
+type Catalog struct {
+	messages map[Lang]map[string]string
+	plurals  map[Lang]map[string]Plural
+}
+
+// NewCatalog creates catalog with built-in messages.
+func NewCatalog() *Catalog {
+	return &Catalog{messages: messages(), plurals: plurals()}
+}
+
+// Languages returns supported languages in the order they are offered to users.
+func (c *Catalog) Languages() []Lang {
+	return []Lang{Ukrainian, English, Polish}
+}
+
+// Printer returns printer of the language. Unsupported languages are printed in English.
+func (c *Catalog) Printer(lang Lang) *Printer {
+	if _, ok := c.messages[lang]; !ok {
+		lang = fallback
+	}
+
+	return &Printer{lang: lang, catalog: c, number: numberFormats()[lang]}
+}
+
+// Printer translates messages to one language.
+type Printer struct {
+	lang    Lang
+	catalog *Catalog
+	number  numberFormat
+}
+
+func (p *Printer) Lang() Lang {
+	return p.lang
+}
+
+// T returns translated message formatted with args like fmt.Sprintf does.
+// Message of the fallback language or the key itself is returned if message is not translated.
+func (p *Printer) T(key string, args ...interface{}) string {
+	msg, ok := p.catalog.messages[p.lang][key]
+	if !ok {
+		if msg, ok = p.catalog.messages[fallback][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+// form returns message of the plural category, Other is used for missing forms.
+func (p *Plural) form(f PluralForm) string {
+	switch {
+	case f == FormOne && p.One != "":
+		return p.One
+	case f == FormFew && p.Few != "":
+		return p.Few
+	case f == FormMany && p.Many != "":
+		return p.Many
+	default:
+		return p.Other
+	}
+}
+
+// N returns plural form of the message for n with n formatted in place of %s, e.g. "21 день" or "5 днів".
+func (p *Printer) N(key string, n int) string {
+	msg, ok := p.catalog.plurals[p.lang][key]
+	if !ok {
+		if msg, ok = p.catalog.plurals[fallback][key]; !ok {
+			return key
+		}
+	}
+
+	return fmt.Sprintf(msg.form(PluralFormOf(p.lang, n)), p.Number(n))
+}
+
+// Number formats integer with locale group separator, e.g. "12,345" in English or "12 345" in Ukrainian.
+func (p *Printer) Number(n int) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
+	return sign + p.group(strconv.Itoa(n))
+}
+
+// Money formats YNAB milliunits as amount with two decimals, e.g. "1,234.56" in English or "1 234,56" in Ukrainian.
+// Like budget.FormatMoney, amounts less than a cent are shown as a cent so they are not mistaken for zero.
+func (p *Printer) Money(milliunits int) string {
+	const centsInUnit, milliunitsInCent = 100, 10
+
+	sign := ""
+	if milliunits < 0 {
+		sign, milliunits = "-", -milliunits
+	}
+	cents := int(math.Round(float64(milliunits) / milliunitsInCent))
+	if cents == 0 && milliunits > 0 {
+		cents = 1
+	}
+
+	return fmt.Sprintf("%s%s%s%02d",
+		sign, p.group(strconv.Itoa(cents/centsInUnit)), p.number.decimal, cents%centsInUnit)
+}
+
+// group inserts group separators into digits. Numbers shorter than minGrouping+3 digits are not grouped.
+func (p *Printer) group(digits string) string {
+	const groupSize = 3
+
+	if len(digits) < p.number.minGrouping+groupSize {
+		return digits
+	}
+
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%groupSize == 0 {
+			sb.WriteString(p.number.group)
+		}
+		sb.WriteRune(d)
+	}
+
+	return sb.String()
+}
+
+// numberFormat follows CLDR number symbols of the language.
+type numberFormat struct {
+	group   string
+	decimal string
+	// minGrouping is CLDR minimumGroupingDigits, e.g. Polish doesn't group four-digit numbers.
+	minGrouping int
+}
+
+func numberFormats() map[Lang]numberFormat {
+	const nbsp = "\u00a0"
+
+	return map[Lang]numberFormat{
+		Ukrainian: {group: nbsp, decimal: ",", minGrouping: 1},
+		English:   {group: ",", decimal: ".", minGrouping: 1},
+		Polish:    {group: nbsp, decimal: ",", minGrouping: 2}, //nolint: gomnd // CLDR minimumGroupingDigits
+	}
+}
+
+type PluralForm int
+
+const (
+	FormOther PluralForm = iota
+	FormOne
+	FormFew
+	FormMany
+)
+
+// PluralFormOf returns CLDR plural category of integer n in the language.
+func PluralFormOf(lang Lang, n int) PluralForm {
+	if n < 0 {
+		n = -n
+	}
+	//nolint: gomnd // CLDR plural rules
+	mod10, mod100 := n%10, n%100
+
+	switch lang {
+	case Ukrainian:
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return FormOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return FormFew
+		default:
+			return FormMany
+		}
+	case Polish:
+		switch {
+		case n == 1:
+			return FormOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return FormFew
+		default:
+			return FormMany
+		}
+	default:
+		if n == 1 {
+			return FormOne
+		}
+		return FormOther
+	}
+}
@@ -0,0 +1,136 @@
+package i18n_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		code string
+		want i18n.Lang
+		ok   bool
+	}{
+		{code: "uk", want: i18n.Ukrainian, ok: true},
+		{code: "uk-UA", want: i18n.Ukrainian, ok: true},
+		{code: "EN-us", want: i18n.English, ok: true},
+		{code: "pl", want: i18n.Polish, ok: true},
+		{code: "de"},
+		{code: ""},
+	}
+	for _, tt := range tests {
+		got, ok := i18n.ParseLang(tt.code)
+		assert.Equal(t, tt.want, got, tt.code)
+		assert.Equal(t, tt.ok, ok, tt.code)
+	}
+}
+
+func TestPrinter_N(t *testing.T) {
+	catalog := i18n.NewCatalog()
+	tests := []struct {
+		lang i18n.Lang
+		want map[int]string
+	}{
+		{
+			lang: i18n.Ukrainian,
+			want: map[int]string{
+				0: "0 днів", 1: "1 день", 2: "2 дні", 4: "4 дні", 5: "5 днів", 11: "11 днів", 12: "12 днів",
+				14: "14 днів", 21: "21 день", 22: "22 дні", 25: "25 днів", 101: "101 день", 111: "111 днів",
+				1024: "1\u00a0024 дні",
+			},
+		},
+		{
+			lang: i18n.Polish,
+			want: map[int]string{
+				0: "0 dni", 1: "1 dzień", 2: "2 dni", 5: "5 dni", 12: "12 dni", 21: "21 dni", 22: "22 dni",
+			},
+		},
+		{
+			lang: i18n.English,
+			want: map[int]string{0: "0 days", 1: "1 day", 2: "2 days", 21: "21 days", 1024: "1,024 days"},
+		},
+	}
+	for _, tt := range tests {
+		p := catalog.Printer(tt.lang)
+		for n, want := range tt.want {
+			assert.Equal(t, want, p.N("days", n), "%s %d", tt.lang, n)
+		}
+	}
+}
+
+func TestPluralFormOf(t *testing.T) {
+	assert.Equal(t, i18n.FormOne, i18n.PluralFormOf(i18n.Ukrainian, 31))
+	assert.Equal(t, i18n.FormFew, i18n.PluralFormOf(i18n.Ukrainian, 1003))
+	assert.Equal(t, i18n.FormMany, i18n.PluralFormOf(i18n.Ukrainian, 1012))
+	assert.Equal(t, i18n.FormOne, i18n.PluralFormOf(i18n.Ukrainian, -1))
+	assert.Equal(t, i18n.FormMany, i18n.PluralFormOf(i18n.Polish, 31), "only 1 is singular in Polish")
+	assert.Equal(t, i18n.FormFew, i18n.PluralFormOf(i18n.Polish, 103))
+	assert.Equal(t, i18n.FormOther, i18n.PluralFormOf(i18n.English, 0))
+}
+
+func TestPrinter_Money(t *testing.T) {
+	catalog := i18n.NewCatalog()
+	tests := []struct {
+		milliunits int
+		uk, en, pl string
+	}{
+		{milliunits: 0, uk: "0,00", en: "0.00", pl: "0,00"},
+		{milliunits: 5, uk: "0,01", en: "0.01", pl: "0,01"},
+		{milliunits: -5, uk: "-0,01", en: "-0.01", pl: "-0,01"},
+		{milliunits: 580000, uk: "580,00", en: "580.00", pl: "580,00"},
+		{milliunits: 1234567, uk: "1\u00a0234,57", en: "1,234.57", pl: "1234,57"},
+		{milliunits: -12345670, uk: "-12\u00a0345,67", en: "-12,345.67", pl: "-12\u00a0345,67"},
+		{milliunits: 1234567890, uk: "1\u00a0234\u00a0567,89", en: "1,234,567.89", pl: "1\u00a0234\u00a0567,89"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.uk, catalog.Printer(i18n.Ukrainian).Money(tt.milliunits))
+		assert.Equal(t, tt.en, catalog.Printer(i18n.English).Money(tt.milliunits))
+		assert.Equal(t, tt.pl, catalog.Printer(i18n.Polish).Money(tt.milliunits))
+	}
+}
+
+func TestPrinter_T(t *testing.T) {
+	catalog := i18n.NewCatalog()
+
+	assert.Equal(t, "Стан", catalog.Printer(i18n.Ukrainian).T("button.state"))
+	assert.Equal(t, "Access of chat 42 is revoked", catalog.Printer(i18n.English).T("access.chat_revoked", 42))
+	assert.Equal(t, "missing.key", catalog.Printer(i18n.Polish).T("missing.key"))
+	assert.Equal(t, i18n.English, catalog.Printer("de").Lang(), "unsupported language falls back to English")
+}
+
+func TestCatalog_Complete(t *testing.T) {
+	catalog := i18n.NewCatalog()
+	keys := []string{
+		"error.unexpected", "button.state", "access.not_allowed", "link.open", "role.usage", "lang.set",
+		"statistic.spent", "statistic.currency",
+	}
+	for _, lang := range catalog.Languages() {
+		for _, key := range keys {
+			assert.NotEqual(t, key, catalog.Printer(lang).T(key), "%s %s", lang, key)
+		}
+	}
+}
+
+func TestChatLanguages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+	s, err := store.OpenJSONFile[map[int64]i18n.Lang](path)
+	require.NoError(t, err)
+	langs := i18n.NewChatLanguages(s)
+
+	_, ok := langs.Language(1)
+	assert.False(t, ok)
+	require.NoError(t, langs.SetLanguage(1, i18n.Polish))
+	assert.Error(t, langs.SetLanguage(1, "de"))
+
+	s, err = store.OpenJSONFile[map[int64]i18n.Lang](path)
+	require.NoError(t, err)
+	lang, ok := i18n.NewChatLanguages(s).Language(1)
+	assert.True(t, ok)
+	assert.Equal(t, i18n.Polish, lang, "language is persisted")
+}
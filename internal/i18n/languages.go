@@ -0,0 +1,41 @@
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+// ChatLanguages keeps languages chosen by chats.
+type ChatLanguages struct {
+	store *store.JSONFile[map[int64]Lang]
+}
+
+func NewChatLanguages(s *store.JSONFile[map[int64]Lang]) *ChatLanguages {
+	return &ChatLanguages{store: s}
+}
+
+// Language returns language chosen by the chat. It returns false if chat hasn't chosen any.
+func (l *ChatLanguages) Language(chatID int64) (Lang, bool) {
+	var lang Lang
+	var ok bool
+	l.store.View(func(langs map[int64]Lang) {
+		lang, ok = langs[chatID]
+	})
+
+	return lang, ok
+}
+
+func (l *ChatLanguages) SetLanguage(chatID int64, lang Lang) error {
+	if _, ok := ParseLang(string(lang)); !ok {
+		return fmt.Errorf("unsupported language %q", lang)
+	}
+
+	return l.store.Update(func(langs *map[int64]Lang) error {
+		if *langs == nil {
+			*langs = make(map[int64]Lang)
+		}
+		(*langs)[chatID] = lang
+		return nil
+	})
+}
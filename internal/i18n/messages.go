@@ -0,0 +1,396 @@
+package i18n
+
+// messages returns built-in messages. Keys are grouped by the feature they belong to.
+//
+// nolint: lll // messages are easier to read unwrapped
+func messages() map[Lang]map[string]string {
+	return map[Lang]map[string]string{
+		Ukrainian: {
+			"lang.name": "Українська",
+
+			"error.unexpected":        "Сталася неочікувана помилка. Ви знаєте, кому дзвонити",
+			"error.ynab_unauthorized": "Доступ до YNAB закінчився або був відкликаний. Використайте /link, щоб підключити акаунт знову",
+
+			"button.state":          "Стан",
+			"button.request_access": "Запросити доступ",
+			"button.approve":        "Дозволити",
+			"button.deny":           "Відхилити",
+			"button.connect_ynab":   "Підключити YNAB",
+
+			"access.not_allowed":       "Вам не дозволено користуватися цим ботом",
+			"access.forbidden":         "У вас немає прав на цю дію",
+			"access.already_granted":   "У вас уже є доступ",
+			"access.already_requested": "Запит на доступ уже надіслано",
+			"access.requested":         "Запит на доступ надіслано адміністраторам",
+			"access.request_admin":     "Чат %q (%d) просить доступ до бота",
+			"access.invalid_request":   "Некоректний запит",
+			"access.granted":           "Доступ надано. Використайте /state, щоб побачити статистику",
+			"access.request_resolved":  "Запит уже розглянуто або він застарів",
+			"access.request_approved":  "Запит схвалено",
+			"access.request_denied":    "Запит відхилено",
+			"access.chats_header":      "Чати з доступом:",
+			"access.chat_configured":   "налаштований",
+			"access.chats_footer":      "Використайте /revoke <id чату>, щоб прибрати схвалений чат",
+			"access.revoke_usage":      "Використання: /revoke <id чату>",
+			"access.revoke_failed":     "Відкликати можна лише чати, схвалені через запит на доступ",
+			"access.revoked":           "Ваш доступ до бота відкликано",
+			"access.chat_revoked":      "Доступ чату %d відкликано",
+
+			"link.open":                "Відкрийте посилання, щоб надати боту доступ на читання вашого бюджету YNAB",
+			"link.category_saved":      "Категорію збережено",
+			"link.linked":              "Акаунт YNAB підключено. Оберіть категорію для відстеження",
+			"link.choose_category":     "Оберіть категорію для відстеження",
+			"link.category_not_linked": "Категорію можна змінити лише для чатів, підключених через /link",
+			"link.configured":          "Цей чат уже користується бюджетом YNAB, налаштованим адміністратором, /link недоступний",
+
+			"role.usage": "Використання: /role <id користувача або чату> <viewer|member|admin|none>",
+			"role.set":   "Роль %d тепер %s",
+
+			"lang.choose": "Оберіть мову",
+			"lang.set":    "Мову змінено на українську",
+
+			"statistic.spent":               "Статистика",
+			"statistic.balance":             "Залишок",
+			"statistic.per_day":             "В день",
+			"statistic.daily":               "грн. в день",
+			"statistic.currency":            "грн.",
+			"statistic.updated_at":          "Оновлено о %s",
+			"statistic.not_changed":         "Нічого не змінилося",
+			"dashboard.disabled":            "Дашборд вимкнено",
+			"dashboard.pin_failed":          "Не вдалося закріпити дашборд. Переконайтеся, що бот може закріплювати повідомлення",
+			"history.title":                 "Транзакції %d–%d з %d",
+			"history.empty":                 "Транзакцій за останні місяці немає",
+			"category.not_found":            "Категорію %q не знайдено",
+			"history.transaction_not_found": "Транзакцію не знайдено, оновіть список",
+			"history.no_payee":              "Без отримувача",
+			"history.date":                  "Дата",
+			"history.amount":                "Сума",
+			"history.category":              "Категорія",
+			"history.account":               "Рахунок",
+			"history.status":                "Статус",
+			"history.memo":                  "Нотатка",
+			"history.cleared.cleared":       "Підтверджена банком",
+			"history.cleared.uncleared":     "Не підтверджена банком",
+			"history.cleared.reconciled":    "Звірена",
+			"history.unapproved":            "Транзакція ще не схвалена в YNAB",
+			"button.back":                   "⬅ Назад",
+			"chart.summary":                 "%s: витрачено %s з %s %s",
+			"chart.legend":                  "Синя лінія — витрати з початку місяця, червона — понад план, зелена пунктирна — ідеальні витрати.",
+			"chart.breakdown":               "Витрати за категоріями:",
+			"move.usage":                    "Вкажіть суму, наприклад: /move 500 from Розваги to Продукти",
+			"move.choose_from":              "Звідки перемістити %s?",
+			"move.choose_to":                "Куди перемістити %s з %q?",
+			"move.confirm":                  "Перемістити %s з %q до %q?",
+			"move.done":                     "Переміщено %s %s з %q до %q",
+			"move.cancelled":                "Переміщення скасовано",
+			"move.same_category":            "Категорії мають бути різними",
+			"move.expired":                  "Запит застарів, надішліть /move ще раз",
+			"move.failed":                   "Не вдалося перемістити кошти",
+			"button.move":                   "Перемістити",
+			"button.cancel":                 "Скасувати",
+			"expense.draft":                 "Нова транзакція",
+			"expense.created":               "Транзакцію створено",
+			"expense.cancelled":             "Транзакцію скасовано",
+			"expense.expired":               "Чернетка застаріла, надішліть витрату ще раз",
+			"expense.exists":                "Транзакцію вже створено",
+			"expense.payee":                 "Отримувач",
+			"expense.no_category":           "Без категорії",
+			"expense.suggested":             "%s (пропозиція, %d%%)",
+			"expense.no_account":            "Оберіть рахунок",
+			"expense.today":                 "Сьогодні",
+			"expense.yesterday":             "Вчора",
+			"button.create":                 "✅ Створити",
+			"duplicate.found":               "👯 Схоже на дублікат у %s",
+			"duplicate.manual":              "✍️ Внесена вручну: %s",
+			"duplicate.imported":            "🏦 Імпортована з банку: %s",
+			"duplicate.question":            "Видалити внесену вручну?",
+			"duplicate.deleted":             "🗑 Внесену вручну транзакцію видалено",
+			"duplicate.merged":              "🗑 Внесену вручну транзакцію видалено, її категорію та нотатку перенесено до імпортованої",
+			"duplicate.kept":                "Обидві транзакції залишено",
+			"duplicate.not_found":           "Транзакції не знайдено, можливо, їх уже змінено",
+			"button.delete_manual":          "🗑 Видалити внесену вручну",
+			"button.keep_both":              "Залишити обидві",
+			"reminder.uncategorized":        "🏷 Транзакція без категорії: %s",
+			"reminder.pick":                 "Оберіть категорію:",
+			"reminder.no_suggestions":       "Немає з чого запропонувати категорію, оберіть її в YNAB",
+			"reminder.categorized":          "✅ Категорія: %s",
+			"reminder.skipped":              "Пропущено",
+			"reminder.changed":              "Транзакцію вже змінено або видалено",
+			"reminder.expired":              "Нагадування застаріло, оберіть категорію в YNAB",
+			"button.skip":                   "Пропустити",
+			"alerts.usage":                  "Надішліть /alerts low, medium або high, щоб отримувати сповіщення про незвичні витрати, /alerts off — щоб вимкнути",
+			"alerts.enabled":                "Сповіщення про незвичні витрати увімкнено, чутливість: %s",
+			"alerts.disabled":               "Сповіщення про незвичні витрати вимкнено",
+			"alert.spike":                   "⚠️ %s витрачено в %q за %s, зазвичай за день — %s",
+			"alert.new_payee":               "🆕 Новий отримувач %q: %s за %s, %s",
+			"alert.duplicate":               "👯 Схоже на дублікат: %s до %q за %s (%s) і за %s (%s)",
+			"receipt.ask_amount":            "Надішліть суму з чеку, наприклад: АТБ 145.50",
+			"receipt.attached":              "🧾 Чек буде збережено",
+			"receipt.failed":                "Не вдалося зберегти чек",
+			"button.receipt":                "🧾 Чек",
+		},
+		English: {
+			"lang.name": "English",
+
+			"error.unexpected":        "Unexpected error occurred. You know whom to call",
+			"error.ynab_unauthorized": "YNAB access has expired or was revoked. Use /link to connect your account again",
+
+			"button.state":          "Status",
+			"button.request_access": "Request access",
+			"button.approve":        "Approve",
+			"button.deny":           "Deny",
+			"button.connect_ynab":   "Connect YNAB",
+
+			"access.not_allowed":       "You are not allowed to use this bot",
+			"access.forbidden":         "You don't have permission to do this",
+			"access.already_granted":   "You already have access",
+			"access.already_requested": "Access request is already sent",
+			"access.requested":         "Access request is sent to admins",
+			"access.request_admin":     "Chat %q (%d) requests access to the bot",
+			"access.invalid_request":   "Invalid request",
+			"access.granted":           "Access granted. Use /state to see statistic",
+			"access.request_resolved":  "Request is already resolved or expired",
+			"access.request_approved":  "Request is approved",
+			"access.request_denied":    "Request is denied",
+			"access.chats_header":      "Chats with access:",
+			"access.chat_configured":   "configured",
+			"access.chats_footer":      "Use /revoke <chat id> to remove approved chat",
+			"access.revoke_usage":      "Usage: /revoke <chat id>",
+			"access.revoke_failed":     "Only chats approved by access request can be revoked",
+			"access.revoked":           "Your access to the bot was revoked",
+			"access.chat_revoked":      "Access of chat %d is revoked",
+
+			"link.open":                "Open the link to give the bot read access to your YNAB budget",
+			"link.category_saved":      "Category is saved",
+			"link.linked":              "YNAB account is linked. Choose category to watch",
+			"link.choose_category":     "Choose category to watch",
+			"link.category_not_linked": "Category can only be changed for chats linked with /link",
+			"link.configured":          "This chat already uses YNAB budget set up by the bot admin, /link is not available",
+
+			"role.usage": "Usage: /role <user or chat id> <viewer|member|admin|none>",
+			"role.set":   "Role of %d is %s now",
+
+			"lang.choose": "Choose language",
+			"lang.set":    "Language is set to English",
+
+			"statistic.spent":               "Statistic",
+			"statistic.balance":             "Balance",
+			"statistic.per_day":             "Per day",
+			"statistic.daily":               "UAH per day",
+			"statistic.currency":            "UAH",
+			"statistic.updated_at":          "Updated at %s",
+			"statistic.not_changed":         "Nothing has changed",
+			"dashboard.disabled":            "Dashboard is turned off",
+			"dashboard.pin_failed":          "Failed to pin dashboard. Make sure the bot is allowed to pin messages",
+			"history.title":                 "Transactions %d–%d of %d",
+			"history.empty":                 "No transactions in recent months",
+			"category.not_found":            "Category %q is not found",
+			"history.transaction_not_found": "Transaction is not found, refresh the list",
+			"history.no_payee":              "No payee",
+			"history.date":                  "Date",
+			"history.amount":                "Amount",
+			"history.category":              "Category",
+			"history.account":               "Account",
+			"history.status":                "Status",
+			"history.memo":                  "Memo",
+			"history.cleared.cleared":       "Cleared",
+			"history.cleared.uncleared":     "Uncleared",
+			"history.cleared.reconciled":    "Reconciled",
+			"history.unapproved":            "Transaction is not approved in YNAB yet",
+			"button.back":                   "⬅ Back",
+			"chart.summary":                 "%s: spent %s of %s %s",
+			"chart.legend":                  "Blue line is spending since the start of the month, red is above the plan, green dashed is ideal spending.",
+			"chart.breakdown":               "Spending by category:",
+			"move.usage":                    "Specify amount, for example: /move 500 from Fun to Groceries",
+			"move.choose_from":              "Where to move %s from?",
+			"move.choose_to":                "Where to move %s from %q to?",
+			"move.confirm":                  "Move %s from %q to %q?",
+			"move.done":                     "Moved %s %s from %q to %q",
+			"move.cancelled":                "Move is cancelled",
+			"move.same_category":            "Categories must be different",
+			"move.expired":                  "Request is expired, send /move again",
+			"move.failed":                   "Failed to move money",
+			"button.move":                   "Move",
+			"button.cancel":                 "Cancel",
+			"expense.draft":                 "New transaction",
+			"expense.created":               "Transaction is created",
+			"expense.cancelled":             "Transaction is cancelled",
+			"expense.expired":               "Draft is expired, send the expense again",
+			"expense.exists":                "Transaction is created already",
+			"expense.payee":                 "Payee",
+			"expense.no_category":           "Uncategorized",
+			"expense.suggested":             "%s (suggested, %d%%)",
+			"expense.no_account":            "Choose account",
+			"expense.today":                 "Today",
+			"expense.yesterday":             "Yesterday",
+			"button.create":                 "✅ Create",
+			"duplicate.found":               "👯 Looks like a duplicate in %s",
+			"duplicate.manual":              "✍️ Entered manually: %s",
+			"duplicate.imported":            "🏦 Imported from bank: %s",
+			"duplicate.question":            "Delete the one entered manually?",
+			"duplicate.deleted":             "🗑 Transaction entered manually is deleted",
+			"duplicate.merged":              "🗑 Transaction entered manually is deleted, its category and memo are moved to the imported one",
+			"duplicate.kept":                "Both transactions are kept",
+			"duplicate.not_found":           "Transactions are not found, they may have been changed already",
+			"button.delete_manual":          "🗑 Delete manual",
+			"button.keep_both":              "Keep both",
+			"reminder.uncategorized":        "🏷 Transaction without category: %s",
+			"reminder.pick":                 "Pick a category:",
+			"reminder.no_suggestions":       "Nothing to suggest a category from, pick it in YNAB",
+			"reminder.categorized":          "✅ Category: %s",
+			"reminder.skipped":              "Skipped",
+			"reminder.changed":              "Transaction is changed or deleted already",
+			"reminder.expired":              "Reminder is expired, pick the category in YNAB",
+			"button.skip":                   "Skip",
+			"alerts.usage":                  "Send /alerts low, medium or high to be notified about unusual spending, /alerts off to stop",
+			"alerts.enabled":                "Alerts about unusual spending are on, sensitivity: %s",
+			"alerts.disabled":               "Alerts about unusual spending are off",
+			"alert.spike":                   "⚠️ %s spent in %q on %s, a typical day is %s",
+			"alert.new_payee":               "🆕 New payee %q: %s on %s, %s",
+			"alert.duplicate":               "👯 Looks like a duplicate: %s to %q on %s (%s) and on %s (%s)",
+			"receipt.ask_amount":            "Send the amount from the receipt, e.g.: Lidl 45.50",
+			"receipt.attached":              "🧾 Receipt will be saved",
+			"receipt.failed":                "Failed to save the receipt",
+			"button.receipt":                "🧾 Receipt",
+		},
+		Polish: {
+			"lang.name": "Polski",
+
+			"error.unexpected":        "Wystąpił nieoczekiwany błąd. Wiesz, do kogo dzwonić",
+			"error.ynab_unauthorized": "Dostęp do YNAB wygasł lub został cofnięty. Użyj /link, aby ponownie połączyć konto",
+
+			"button.state":          "Stan",
+			"button.request_access": "Poproś o dostęp",
+			"button.approve":        "Zatwierdź",
+			"button.deny":           "Odrzuć",
+			"button.connect_ynab":   "Połącz YNAB",
+
+			"access.not_allowed":       "Nie masz uprawnień do korzystania z tego bota",
+			"access.forbidden":         "Nie masz uprawnień do tej czynności",
+			"access.already_granted":   "Masz już dostęp",
+			"access.already_requested": "Prośba o dostęp została już wysłana",
+			"access.requested":         "Prośba o dostęp została wysłana do administratorów",
+			"access.request_admin":     "Czat %q (%d) prosi o dostęp do bota",
+			"access.invalid_request":   "Nieprawidłowe żądanie",
+			"access.granted":           "Dostęp przyznany. Użyj /state, aby zobaczyć statystykę",
+			"access.request_resolved":  "Prośba została już rozpatrzona lub wygasła",
+			"access.request_approved":  "Prośba została zatwierdzona",
+			"access.request_denied":    "Prośba została odrzucona",
+			"access.chats_header":      "Czaty z dostępem:",
+			"access.chat_configured":   "skonfigurowany",
+			"access.chats_footer":      "Użyj /revoke <id czatu>, aby usunąć zatwierdzony czat",
+			"access.revoke_usage":      "Użycie: /revoke <id czatu>",
+			"access.revoke_failed":     "Cofnąć można tylko czaty zatwierdzone przez prośbę o dostęp",
+			"access.revoked":           "Twój dostęp do bota został cofnięty",
+			"access.chat_revoked":      "Dostęp czatu %d został cofnięty",
+
+			"link.open":                "Otwórz link, aby dać botowi dostęp do odczytu Twojego budżetu YNAB",
+			"link.category_saved":      "Kategoria została zapisana",
+			"link.linked":              "Konto YNAB zostało połączone. Wybierz kategorię do obserwowania",
+			"link.choose_category":     "Wybierz kategorię do obserwowania",
+			"link.category_not_linked": "Kategorię można zmienić tylko dla czatów połączonych przez /link",
+			"link.configured":          "Ten czat korzysta już z budżetu YNAB skonfigurowanego przez administratora, /link jest niedostępny",
+
+			"role.usage": "Użycie: /role <id użytkownika lub czatu> <viewer|member|admin|none>",
+			"role.set":   "Rola %d to teraz %s",
+
+			"lang.choose": "Wybierz język",
+			"lang.set":    "Język został zmieniony na polski",
+
+			"statistic.spent":               "Statystyka",
+			"statistic.balance":             "Saldo",
+			"statistic.per_day":             "Na dzień",
+			"statistic.daily":               "UAH dziennie",
+			"statistic.currency":            "UAH",
+			"statistic.updated_at":          "Zaktualizowano o %s",
+			"statistic.not_changed":         "Nic się nie zmieniło",
+			"dashboard.disabled":            "Panel wyłączony",
+			"dashboard.pin_failed":          "Nie udało się przypiąć panelu. Upewnij się, że bot może przypinać wiadomości",
+			"history.title":                 "Transakcje %d–%d z %d",
+			"history.empty":                 "Brak transakcji w ostatnich miesiącach",
+			"category.not_found":            "Nie znaleziono kategorii %q",
+			"history.transaction_not_found": "Nie znaleziono transakcji, odśwież listę",
+			"history.no_payee":              "Bez odbiorcy",
+			"history.date":                  "Data",
+			"history.amount":                "Kwota",
+			"history.category":              "Kategoria",
+			"history.account":               "Konto",
+			"history.status":                "Status",
+			"history.memo":                  "Notatka",
+			"history.cleared.cleared":       "Rozliczona",
+			"history.cleared.uncleared":     "Nierozliczona",
+			"history.cleared.reconciled":    "Uzgodniona",
+			"history.unapproved":            "Transakcja nie jest jeszcze zatwierdzona w YNAB",
+			"button.back":                   "⬅ Wstecz",
+			"chart.summary":                 "%s: wydano %s z %s %s",
+			"chart.legend":                  "Niebieska linia to wydatki od początku miesiąca, czerwona ponad plan, zielona przerywana to idealne wydatki.",
+			"chart.breakdown":               "Wydatki według kategorii:",
+			"move.usage":                    "Podaj kwotę, na przykład: /move 500 from Rozrywka to Zakupy",
+			"move.choose_from":              "Skąd przenieść %s?",
+			"move.choose_to":                "Dokąd przenieść %s z %q?",
+			"move.confirm":                  "Przenieść %s z %q do %q?",
+			"move.done":                     "Przeniesiono %s %s z %q do %q",
+			"move.cancelled":                "Przeniesienie anulowane",
+			"move.same_category":            "Kategorie muszą być różne",
+			"move.expired":                  "Prośba wygasła, wyślij /move ponownie",
+			"move.failed":                   "Nie udało się przenieść środków",
+			"button.move":                   "Przenieś",
+			"button.cancel":                 "Anuluj",
+			"expense.draft":                 "Nowa transakcja",
+			"expense.created":               "Transakcja utworzona",
+			"expense.cancelled":             "Transakcja anulowana",
+			"expense.expired":               "Szkic wygasł, wyślij wydatek ponownie",
+			"expense.exists":                "Transakcja jest już utworzona",
+			"expense.payee":                 "Odbiorca",
+			"expense.no_category":           "Bez kategorii",
+			"expense.suggested":             "%s (sugestia, %d%%)",
+			"expense.no_account":            "Wybierz konto",
+			"expense.today":                 "Dziś",
+			"expense.yesterday":             "Wczoraj",
+			"button.create":                 "✅ Utwórz",
+			"duplicate.found":               "👯 Wygląda na duplikat w %s",
+			"duplicate.manual":              "✍️ Wprowadzona ręcznie: %s",
+			"duplicate.imported":            "🏦 Zaimportowana z banku: %s",
+			"duplicate.question":            "Usunąć wprowadzoną ręcznie?",
+			"duplicate.deleted":             "🗑 Transakcja wprowadzona ręcznie została usunięta",
+			"duplicate.merged":              "🗑 Transakcja wprowadzona ręcznie została usunięta, jej kategoria i notatka przeniesione do zaimportowanej",
+			"duplicate.kept":                "Obie transakcje zostały zachowane",
+			"duplicate.not_found":           "Nie znaleziono transakcji, mogły zostać już zmienione",
+			"button.delete_manual":          "🗑 Usuń ręczną",
+			"button.keep_both":              "Zachowaj obie",
+			"reminder.uncategorized":        "🏷 Transakcja bez kategorii: %s",
+			"reminder.pick":                 "Wybierz kategorię:",
+			"reminder.no_suggestions":       "Nie ma z czego zaproponować kategorii, wybierz ją w YNAB",
+			"reminder.categorized":          "✅ Kategoria: %s",
+			"reminder.skipped":              "Pominięto",
+			"reminder.changed":              "Transakcja jest już zmieniona lub usunięta",
+			"reminder.expired":              "Przypomnienie wygasło, wybierz kategorię w YNAB",
+			"button.skip":                   "Pomiń",
+			"alerts.usage":                  "Wyślij /alerts low, medium lub high, aby otrzymywać powiadomienia o nietypowych wydatkach, /alerts off, aby wyłączyć",
+			"alerts.enabled":                "Powiadomienia o nietypowych wydatkach są włączone, czułość: %s",
+			"alerts.disabled":               "Powiadomienia o nietypowych wydatkach są wyłączone",
+			"alert.spike":                   "⚠️ Wydano %s w %q dnia %s, zwykle dziennie %s",
+			"alert.new_payee":               "🆕 Nowy odbiorca %q: %s dnia %s, %s",
+			"alert.duplicate":               "👯 Wygląda na duplikat: %s do %q dnia %s (%s) i dnia %s (%s)",
+			"receipt.ask_amount":            "Wyślij kwotę z paragonu, np.: Biedronka 45.50",
+			"receipt.attached":              "🧾 Paragon zostanie zapisany",
+			"receipt.failed":                "Nie udało się zapisać paragonu",
+			"button.receipt":                "🧾 Paragon",
+		},
+	}
+}
+
+// plurals returns built-in messages depending on a number. Number is formatted in place of %s.
+func plurals() map[Lang]map[string]Plural {
+	return map[Lang]map[string]Plural{
+		Ukrainian: {
+			"days": {One: "%s день", Few: "%s дні", Many: "%s днів", Other: "%s дня"},
+		},
+		English: {
+			"days": {One: "%s day", Other: "%s days"},
+		},
+		Polish: {
+			"days": {One: "%s dzień", Few: "%s dni", Many: "%s dni", Other: "%s dnia"},
+		},
+	}
+}
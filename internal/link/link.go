@@ -0,0 +1,206 @@
+package link
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// DefaultBudgetID makes YNAB API resolve the budget user has opened last.
+const DefaultBudgetID = "last-used"
+
+const (
+	stateTTL      = 15 * time.Minute
+	stateBytes    = 16
+	exchangeLimit = 30 * time.Second
+)
+
+type Logger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Link is YNAB account linked to the chat with OAuth. UserID is the Telegram user who linked it.
+type Link struct {
+	ChatID     int64           `json:"chat_id"`
+	UserID     int64           `json:"user_id,omitempty"`
+	Token      ynab.OAuthToken `json:"token"`
+	BudgetID   string          `json:"budget_id"`
+	CategoryID string          `json:"category_id"`
+}
+
+type pendingState struct {
+	chatID  int64
+	userID  int64
+	expires time.Time
+}
+
+// Service links chats to YNAB accounts with OAuth authorization code flow.
+// It serves OAuth callback and persists tokens per chat.
+type Service struct {
+	oauth    *ynab.OAuth
+	links    *store.JSONFile[map[int64]Link]
+	onLinked func(Link)
+	log      Logger
+
+	mu     sync.Mutex
+	states map[string]pendingState
+}
+
+// NewService creates linking service. onLinked is called every time link is created or changed.
+func NewService(oauth *ynab.OAuth, links *store.JSONFile[map[int64]Link], onLinked func(Link), log Logger) *Service {
+	return &Service{
+		oauth:    oauth,
+		links:    links,
+		onLinked: onLinked,
+		log:      log,
+		states:   make(map[string]pendingState),
+	}
+}
+
+// Links returns all persisted links.
+func (s *Service) Links() []Link {
+	res := make([]Link, 0)
+	s.links.View(func(links map[int64]Link) {
+		for _, l := range links {
+			res = append(res, l)
+		}
+	})
+
+	return res
+}
+
+// AuthURL returns URL the user should open to grant the bot access to YNAB on behalf of the chat.
+func (s *Service) AuthURL(chatID, userID int64) (string, error) {
+	b := make([]byte, stateBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating state: %w", err)
+	}
+	state := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range s.states {
+		if now.After(v.expires) {
+			delete(s.states, k)
+		}
+	}
+	s.states[state] = pendingState{chatID: chatID, userID: userID, expires: now.Add(stateTTL)}
+
+	return s.oauth.AuthCodeURL(state), nil
+}
+
+// SetCategory changes category watched by the linked chat.
+func (s *Service) SetCategory(chatID int64, categoryID string) error {
+	var res Link
+	err := s.links.Update(func(links *map[int64]Link) error {
+		l, ok := (*links)[chatID]
+		if !ok {
+			return fmt.Errorf("chat %d is not linked", chatID)
+		}
+		l.CategoryID = categoryID
+		(*links)[chatID] = l
+		res = l
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.onLinked(res)
+	return nil
+}
+
+// TokenSource returns token source of the linked chat which persists refreshed tokens.
+func (s *Service) TokenSource(l Link) ynab.TokenSource {
+	return ynab.NewRefreshingTokenSource(s.oauth, l.Token, func(token ynab.OAuthToken) {
+		err := s.links.Update(func(links *map[int64]Link) error {
+			stored, ok := (*links)[l.ChatID]
+			if !ok {
+				return fmt.Errorf("chat %d is not linked", l.ChatID)
+			}
+			stored.Token = token
+			(*links)[l.ChatID] = stored
+			return nil
+		})
+		if err != nil {
+			s.log.Errorw("failed to persist refreshed token", "chatID", l.ChatID, "error", err)
+		}
+	})
+}
+
+// ServeHTTP handles OAuth redirect with authorization code.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		s.log.Warnw("authorization denied", "error", e)
+		http.Error(w, "Authorization was denied. You can close this page.", http.StatusBadRequest)
+		return
+	}
+
+	p, ok := s.popState(q.Get("state"))
+	if !ok {
+		http.Error(w, "Link is invalid or expired. Request a new one in Telegram.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), exchangeLimit)
+	defer cancel()
+	token, err := s.oauth.Exchange(ctx, q.Get("code"))
+	if err != nil {
+		s.log.Errorw("failed to exchange code", "chatID", p.chatID, "error", err)
+		http.Error(w, "Failed to link YNAB account. Please try again.", http.StatusBadGateway)
+		return
+	}
+
+	var res Link
+	err = s.links.Update(func(links *map[int64]Link) error {
+		if *links == nil {
+			*links = make(map[int64]Link)
+		}
+		l, exists := (*links)[p.chatID]
+		if !exists {
+			l = Link{ChatID: p.chatID, BudgetID: DefaultBudgetID}
+		}
+		if l.UserID == 0 {
+			l.UserID = p.userID
+		}
+		l.Token = *token
+		(*links)[p.chatID] = l
+		res = l
+		return nil
+	})
+	if err != nil {
+		s.log.Errorw("failed to store link", "chatID", p.chatID, "error", err)
+		http.Error(w, "Failed to link YNAB account. Please try again.", http.StatusInternalServerError)
+		return
+	}
+
+	s.log.Infow("chat linked", "chatID", p.chatID, "userID", p.userID)
+	s.onLinked(res)
+
+	_, _ = w.Write([]byte("YNAB account is linked. You can return to Telegram."))
+}
+
+func (s *Service) popState(state string) (pendingState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.states[state]
+	if !ok {
+		return pendingState{}, false
+	}
+	delete(s.states, state)
+
+	return p, time.Now().Before(p.expires)
+}
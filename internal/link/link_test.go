@@ -0,0 +1,75 @@
+package link_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/link"
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestService(t *testing.T) {
+	oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh", "expires_in": 7200}`))
+	}))
+	defer oauthServer.Close()
+
+	path := filepath.Join(t.TempDir(), "links.json")
+	links, err := store.OpenJSONFile[map[int64]link.Link](path)
+	require.NoError(t, err)
+
+	log := zap.NewNop().Sugar()
+	oauth := ynab.NewOAuth(oauthServer.URL, "id", "secret", "https://bot.example.com/oauth/callback", log)
+	linked := make([]link.Link, 0)
+	svc := link.NewService(oauth, links, func(l link.Link) {
+		linked = append(linked, l)
+	}, log)
+
+	authURL, err := svc.AuthURL(42, 7)
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	state := u.Query().Get("state")
+	require.NotEmpty(t, state)
+
+	callback := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/oauth/callback?"+query, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, callback("state=unknown&code=code").Code)
+	assert.Equal(t, http.StatusOK, callback("state="+state+"&code=code").Code)
+	assert.Equal(t, http.StatusBadRequest, callback("state="+state+"&code=code").Code, "state must be single use")
+
+	require.Len(t, linked, 1)
+	assert.Equal(t, int64(42), linked[0].ChatID)
+	assert.Equal(t, int64(7), linked[0].UserID)
+	assert.Equal(t, link.DefaultBudgetID, linked[0].BudgetID)
+	assert.Equal(t, "access", linked[0].Token.AccessToken)
+
+	require.NoError(t, svc.SetCategory(42, "cat"))
+	assert.Error(t, svc.SetCategory(43, "cat"))
+	require.Len(t, linked, 2)
+	assert.Equal(t, "cat", linked[1].CategoryID)
+
+	reopened, err := store.OpenJSONFile[map[int64]link.Link](path)
+	require.NoError(t, err)
+	got := link.NewService(oauth, reopened, func(link.Link) {}, log).Links()
+	require.Len(t, got, 1)
+	assert.Equal(t, "cat", got[0].CategoryID)
+	assert.Equal(t, "refresh", got[0].Token.RefreshToken)
+}
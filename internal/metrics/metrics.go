@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const namespace = "ynabnotifier"
+
+// Metrics collects Prometheus metrics of YNAB requests, bot handlers and notifications.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	ynabRequests        *prometheus.CounterVec
+	ynabRequestDuration *prometheus.HistogramVec
+	handlerInvocations  *prometheus.CounterVec
+	handlerDuration     *prometheus.HistogramVec
+	notifications       *prometheus.CounterVec
+
+	rateLimitMu sync.RWMutex
+	rateLimits  map[string]func() int
+	rateLimit   *prometheus.Desc
+}
+
+// New creates metrics registered in their own registry along with Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		ynabRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ynab_requests_total",
+			Help:      "Number of YNAB API requests by endpoint and status.",
+		}, []string{"endpoint", "status"}),
+		ynabRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ynab_request_duration_seconds",
+			Help:      "Duration of YNAB API requests by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		handlerInvocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bot_handler_invocations_total",
+			Help:      "Number of bot handler invocations by command, chat and result.",
+		}, []string{"command", "chat", "result"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "bot_handler_duration_seconds",
+			Help:      "Duration of bot handlers by command.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"command"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notifications_total",
+			Help:      "Number of messages sent by the bot on its own by kind and result.",
+		}, []string{"kind", "result"}),
+		rateLimits: make(map[string]func() int),
+		rateLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "ynab_rate_limit_remaining"),
+			"Number of YNAB API requests tenant can still make within rate limit window.",
+			[]string{"tenant"}, nil,
+		),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.ynabRequests,
+		m.ynabRequestDuration,
+		m.handlerInvocations,
+		m.handlerDuration,
+		m.notifications,
+		m,
+	)
+
+	return m
+}
+
+// Handler returns HTTP handler exposing metrics in Prometheus format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest implements ynab.Observer.
+func (m *Metrics) ObserveRequest(endpoint string, statusCode int, duration time.Duration, err error) {
+	status := strconv.Itoa(statusCode)
+	switch {
+	case statusCode != 0:
+	case errors.Is(err, ynab.ErrRateLimited):
+		status = "rate_limited"
+	default:
+		status = "error"
+	}
+
+	m.ynabRequests.WithLabelValues(endpoint, status).Inc()
+	m.ynabRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// ObserveHandler records invocation of bot handler.
+func (m *Metrics) ObserveHandler(command string, chatID int64, duration time.Duration, err error) {
+	m.handlerInvocations.WithLabelValues(command, strconv.FormatInt(chatID, 10), result(err)).Inc()
+	m.handlerDuration.WithLabelValues(command).Observe(duration.Seconds())
+}
+
+// ObserveNotification records delivery of the message bot sends on its own.
+func (m *Metrics) ObserveNotification(kind string, err error) {
+	m.notifications.WithLabelValues(kind, result(err)).Inc()
+}
+
+// TrackRateLimit reports remaining rate limit of tenant's client.
+func (m *Metrics) TrackRateLimit(tenantID string, remaining func() int) {
+	m.rateLimitMu.Lock()
+	defer m.rateLimitMu.Unlock()
+
+	m.rateLimits[tenantID] = remaining
+}
+
+// Describe implements prometheus.Collector for rate limit gauges.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.rateLimit
+}
+
+// Collect implements prometheus.Collector for rate limit gauges.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.rateLimitMu.RLock()
+	defer m.rateLimitMu.RUnlock()
+
+	for tenantID, remaining := range m.rateLimits {
+		ch <- prometheus.MustNewConstMetric(m.rateLimit, prometheus.GaugeValue, float64(remaining()), tenantID)
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
@@ -0,0 +1,51 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/metrics"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	m.ObserveRequest("GetCategory", http.StatusOK, time.Millisecond, nil)
+	m.ObserveRequest("GetCategory", 0, 0, ynab.ErrRateLimited)
+	m.ObserveRequest("GetCategories", 0, time.Second, errors.New("connection refused"))
+	m.ObserveHandler("/state", 42, time.Millisecond, nil)
+	m.ObserveHandler("state", 42, time.Millisecond, errors.New("failed"))
+	m.ObserveNotification("linked", nil)
+	m.ObserveNotification("access_request", errors.New("blocked"))
+	m.TrackRateLimit("a", func() int { return 150 })
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`ynabnotifier_ynab_requests_total{endpoint="GetCategory",status="200"} 1`,
+		`ynabnotifier_ynab_requests_total{endpoint="GetCategory",status="rate_limited"} 1`,
+		`ynabnotifier_ynab_requests_total{endpoint="GetCategories",status="error"} 1`,
+		`ynabnotifier_ynab_request_duration_seconds_count{endpoint="GetCategory"} 2`,
+		`ynabnotifier_bot_handler_invocations_total{chat="42",command="/state",result="success"} 1`,
+		`ynabnotifier_bot_handler_invocations_total{chat="42",command="state",result="failure"} 1`,
+		`ynabnotifier_notifications_total{kind="linked",result="success"} 1`,
+		`ynabnotifier_notifications_total{kind="access_request",result="failure"} 1`,
+		`ynabnotifier_ynab_rate_limit_remaining{tenant="a"} 150`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
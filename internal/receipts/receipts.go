@@ -0,0 +1,234 @@
+// Package receipts keeps photos of receipts of transactions in a directory.
+//
+// Photos are stored as <budget id>/<transaction id>.jpg, so receipt is found only by the budget of its transaction.
+// Photos older than Config.MaxAge are removed and the oldest photos are evicted when the directory grows over
+// Config.MaxSize.
+package receipts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxAge is how long receipts are kept by default.
+	DefaultMaxAge = 365 * 24 * time.Hour
+	// DefaultMaxSize is the default cap of total size of receipts in bytes.
+	DefaultMaxSize = 500 << 20
+	// DefaultCleanupInterval is how often expired receipts are removed.
+	DefaultCleanupInterval = time.Hour
+
+	ext = ".jpg"
+)
+
+var errInvalidID = errors.New("invalid id")
+
+type Logger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Config limits receipts kept by Store. Zero MaxAge or MaxSize means no limit.
+type Config struct {
+	MaxAge  time.Duration
+	MaxSize int64
+}
+
+// Store keeps receipts in a directory. It is safe for concurrent use.
+type Store struct {
+	dir string
+	cfg Config
+	log Logger
+
+	mu sync.Mutex
+}
+
+// New returns store of receipts in dir. The directory is created with the first receipt.
+func New(dir string, cfg Config, log Logger) *Store {
+	return &Store{dir: dir, cfg: cfg, log: log}
+}
+
+// Save stores receipt of the transaction of the budget replacing the previous one and evicts the oldest receipts
+// if the size cap is exceeded.
+func (s *Store) Save(budgetID, txID string, r io.Reader) error {
+	path, err := s.path(budgetID, txID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0o755); err != nil { //nolint: gomnd // rwxr-xr-x
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	// Receipt is written to a temporary file first, so a failed download doesn't leave a broken photo.
+	tmp, err := os.CreateTemp(dir, "receipt-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing receipt: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming receipt: %w", err)
+	}
+
+	if _, err = s.cleanup(time.Now()); err != nil {
+		return fmt.Errorf("cleaning up receipts: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns path of receipt of the transaction of the budget if it is stored.
+func (s *Store) Path(budgetID, txID string) (string, bool) {
+	path, err := s.path(budgetID, txID)
+	if err != nil {
+		return "", false
+	}
+	if _, err = os.Stat(path); err != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// Cleanup removes receipts older than MaxAge and then the oldest ones until total size fits MaxSize.
+// It returns number of removed receipts.
+func (s *Store) Cleanup(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cleanup(now)
+}
+
+// Run removes expired receipts every interval until ctx is done.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removed, err := s.Cleanup(time.Now())
+		if err != nil {
+			s.log.Errorw("failed to clean up receipts", "dir", s.dir, "error", err)
+			continue
+		}
+		if removed > 0 {
+			s.log.Infow("receipts are cleaned up", "dir", s.dir, "removed", removed)
+		}
+	}
+}
+
+type receipt struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *Store) cleanup(now time.Time) (int, error) {
+	all, err := s.receipts()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []receipt
+	var size int64
+	removed := 0
+	for _, r := range all {
+		if s.cfg.MaxAge > 0 && now.Sub(r.modTime) > s.cfg.MaxAge {
+			if err = os.Remove(r.path); err != nil {
+				return removed, fmt.Errorf("removing %s: %w", r.path, err)
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, r)
+		size += r.size
+	}
+
+	if s.cfg.MaxSize <= 0 || size <= s.cfg.MaxSize {
+		return removed, nil
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].modTime.Before(kept[j].modTime)
+	})
+	for _, r := range kept {
+		if size <= s.cfg.MaxSize {
+			break
+		}
+		if err = os.Remove(r.path); err != nil {
+			return removed, fmt.Errorf("removing %s: %w", r.path, err)
+		}
+		size -= r.size
+		removed++
+	}
+
+	return removed, nil
+}
+
+// receipts lists receipts of all budgets. Receipts stored in the directory itself, by transaction only,
+// are listed too, so they are still removed when they expire.
+func (s *Store) receipts() ([]receipt, error) {
+	var res []receipt
+	err := filepath.WalkDir(s.dir, func(path string, e fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, os.ErrNotExist) && path == s.dir:
+			return fs.SkipDir
+		case err != nil:
+			return err
+		case e.IsDir() && path != s.dir && filepath.Dir(path) != s.dir:
+			return fs.SkipDir
+		case e.IsDir() || filepath.Ext(path) != ext:
+			return nil
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		res = append(res, receipt{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.dir, err)
+	}
+
+	return res, nil
+}
+
+// path returns path of receipt of the transaction of the budget. IDs are checked, so they can't point outside
+// the directory.
+func (s *Store) path(budgetID, txID string) (string, error) {
+	for _, id := range []string{budgetID, txID} {
+		if id == "" || strings.IndexFunc(id, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+		}) >= 0 {
+			return "", fmt.Errorf("%w: %q", errInvalidID, id)
+		}
+	}
+
+	return filepath.Join(s.dir, budgetID, txID+ext), nil
+}
@@ -0,0 +1,91 @@
+package receipts_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/receipts"
+)
+
+func TestStore_Save(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "receipts")
+	s := receipts.New(dir, receipts.Config{}, zap.NewNop().Sugar())
+
+	_, ok := s.Path("budget-1", "tx-1")
+	assert.False(t, ok)
+
+	require.NoError(t, s.Save("budget-1", "tx-1", strings.NewReader("first")))
+	require.NoError(t, s.Save("budget-1", "tx-1", strings.NewReader("second")))
+
+	path, ok := s.Path("budget-1", "tx-1")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "budget-1", "tx-1.jpg"), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+	_, ok = s.Path("budget-2", "tx-1")
+	assert.False(t, ok, "receipt is found only by budget of the transaction")
+
+	entries, err := os.ReadDir(filepath.Join(dir, "budget-1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+
+	for _, id := range []string{"", "../tx-1", "tx/1"} {
+		assert.Error(t, s.Save("budget-1", id, strings.NewReader("x")), id)
+		_, ok = s.Path("budget-1", id)
+		assert.False(t, ok, id)
+		assert.Error(t, s.Save(id, "tx-1", strings.NewReader("x")), id)
+		_, ok = s.Path(id, "tx-1")
+		assert.False(t, ok, id)
+	}
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	s := receipts.New(dir, receipts.Config{MaxAge: 24 * time.Hour, MaxSize: 10}, zap.NewNop().Sugar())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "budget-1"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "budget-2"), 0o700))
+	for name, age := range map[string]time.Duration{
+		"budget-1/expired.jpg": 48 * time.Hour,
+		"budget-2/oldest.jpg":  3 * time.Hour,
+		"budget-1/older.jpg":   2 * time.Hour,
+		"budget-2/newest.jpg":  time.Hour,
+		// Receipts stored before budgets were part of the path.
+		"legacy.jpg": 72 * time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("1234"), 0o600))
+		require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a receipt"), 0o600))
+
+	removed, err := s.Cleanup(now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, removed, "expired receipts and the oldest one over size cap")
+
+	for name, want := range map[string]bool{
+		"budget-1/expired.jpg": false,
+		"budget-2/oldest.jpg":  false,
+		"budget-1/older.jpg":   true,
+		"budget-2/newest.jpg":  true,
+		"legacy.jpg":           false,
+	} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		assert.Equal(t, want, err == nil, name)
+	}
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+
+	s = receipts.New(filepath.Join(dir, "missing"), receipts.Config{MaxAge: time.Hour}, zap.NewNop().Sugar())
+	removed, err = s.Cleanup(now)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONFile keeps value of type T in memory and persists it to a JSON file on every update.
+type JSONFile[T any] struct {
+	mu    sync.RWMutex
+	path  string
+	value T
+}
+
+// OpenJSONFile loads value from the file at path. Zero value is used if file does not exist yet.
+func OpenJSONFile[T any](path string) (*JSONFile[T], error) {
+	f := &JSONFile[T]{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err = json.Unmarshal(data, &f.value); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// View calls fn with current value. fn must not modify or retain the value.
+func (f *JSONFile[T]) View(fn func(T)) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	fn(f.value)
+}
+
+// Update calls fn with pointer to current value and persists it if fn succeeds.
+// fn must not modify the value if it returns an error.
+func (f *JSONFile[T]) Update(fn func(*T) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := fn(&f.value); err != nil {
+		return err
+	}
+
+	return f.write()
+}
+
+func (f *JSONFile[T]) write() error {
+	data, err := json.MarshalIndent(f.value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", f.path, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", f.path, err)
+	}
+
+	tmp := f.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("renaming %s: %w", tmp, err)
+	}
+
+	return nil
+}
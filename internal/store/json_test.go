@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+func TestJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	f, err := store.OpenJSONFile[map[int64]string](path)
+	require.NoError(t, err)
+
+	f.View(func(v map[int64]string) {
+		assert.Empty(t, v)
+	})
+
+	require.NoError(t, f.Update(func(v *map[int64]string) error {
+		*v = map[int64]string{1: "one"}
+		return nil
+	}))
+	assert.EqualError(t, f.Update(func(v *map[int64]string) error {
+		(*v)[2] = "two"
+		return fmt.Errorf("boom")
+	}), "boom")
+
+	reopened, err := store.OpenJSONFile[map[int64]string](path)
+	require.NoError(t, err)
+	reopened.View(func(v map[int64]string) {
+		assert.Equal(t, map[int64]string{1: "one"}, v)
+	})
+}
@@ -0,0 +1,163 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	// payeeWeight is weight of transactions with the same payee. Words of other payees and memos weigh wordWeight.
+	payeeWeight = 1.0
+	wordWeight  = 0.5
+	// minWordLen is the shortest word of payee or memo used as keyword.
+	minWordLen = 3
+	// MinConfidence is the lowest confidence of a suggestion worth applying without asking.
+	MinConfidence = 0.3
+)
+
+// Suggestion is a category suggested for a transaction.
+type Suggestion struct {
+	CategoryID   string
+	CategoryName string
+	// Confidence is from 0 to 1. It is the weighted share of matching transactions of the category,
+	// lowered when only a few transactions match.
+	Confidence float64
+}
+
+// Suggester suggests categories of transactions by payee and memo learned from transactions of the budget.
+// It is immutable and safe for concurrent use.
+type Suggester struct {
+	names map[string]string
+	// payees and words count transactions by category for normalized payees and keywords.
+	payees map[string]map[string]int
+	words  map[string]map[string]int
+}
+
+// New learns from history. Only categories are suggested, transactions of other categories
+// (e.g. hidden ones or inflows) and transfers are skipped.
+func New(categories []ynab.Category, history []ynab.Transaction) *Suggester {
+	s := &Suggester{
+		names:  make(map[string]string, len(categories)),
+		payees: make(map[string]map[string]int),
+		words:  make(map[string]map[string]int),
+	}
+	for _, c := range categories {
+		s.names[c.ID] = c.Name
+	}
+
+	for _, tx := range history {
+		if _, ok := s.names[tx.CategoryID]; !ok || tx.Deleted || tx.IsTransfer() {
+			continue
+		}
+		if payee := normalize(tx.PayeeName); payee != "" {
+			add(s.payees, payee, tx.CategoryID)
+		}
+		for _, w := range keywords(tx.PayeeName + " " + tx.Memo) {
+			add(s.words, w, tx.CategoryID)
+		}
+	}
+
+	return s
+}
+
+// Suggest returns categories for transaction with the payee and memo, the most confident first.
+// Transactions with the same payee weigh more than ones sharing words with the payee or the memo.
+// It returns nothing if there is nothing alike in the history.
+func (s *Suggester) Suggest(payee, memo string) []Suggestion {
+	scores := make(map[string]float64)
+	var weights float64
+	var matched int
+	vote := func(counts map[string]int, weight float64) {
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		if total == 0 {
+			return
+		}
+		for id, n := range counts {
+			scores[id] += weight * float64(n) / float64(total)
+		}
+		weights += weight
+		if total > matched {
+			matched = total
+		}
+	}
+
+	vote(s.payees[normalize(payee)], payeeWeight)
+	for _, w := range keywords(payee + " " + memo) {
+		vote(s.words[w], wordWeight)
+	}
+	if matched == 0 {
+		return nil
+	}
+
+	// Certainty grows with number of transactions of the best matching payee or word:
+	// 1 transaction gives half of the share, 9 give 90%.
+	certainty := float64(matched) / float64(matched+1)
+	res := make([]Suggestion, 0, len(scores))
+	for id, score := range scores {
+		res = append(res, Suggestion{
+			CategoryID:   id,
+			CategoryName: s.names[id],
+			Confidence:   score / weights * certainty,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Confidence != res[j].Confidence {
+			return res[i].Confidence > res[j].Confidence
+		}
+		return res[i].CategoryName < res[j].CategoryName
+	})
+
+	return res
+}
+
+// Best returns the most confident suggestion if it is at least MinConfidence.
+func (s *Suggester) Best(payee, memo string) (Suggestion, bool) {
+	suggestions := s.Suggest(payee, memo)
+	if len(suggestions) == 0 || suggestions[0].Confidence < MinConfidence {
+		return Suggestion{}, false
+	}
+
+	return suggestions[0], true
+}
+
+func add(counts map[string]map[string]int, key, categoryID string) {
+	if counts[key] == nil {
+		counts[key] = make(map[string]int)
+	}
+	counts[key][categoryID]++
+}
+
+// normalize lowercases s, drops everything but letters and digits and collapses spaces,
+// so "АТБ-Маркет  #12" and "атб маркет 12" are the same payee.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// keywords returns distinct words of s which have at least minWordLen letters.
+func keywords(s string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(normalize(s)) {
+		letters := 0
+		for _, r := range w {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		if letters < minWordLen || seen[w] {
+			continue
+		}
+		seen[w] = true
+		res = append(res, w)
+	}
+
+	return res
+}
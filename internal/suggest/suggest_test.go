@@ -0,0 +1,84 @@
+package suggest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/suggest"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestSuggester_Suggest(t *testing.T) {
+	categories := []ynab.Category{
+		{ID: "c-groceries", Name: "Groceries"},
+		{ID: "c-cafe", Name: "Cafe"},
+		{ID: "c-transport", Name: "Transport"},
+		{ID: "c-gifts", Name: "Gifts"},
+	}
+	history := []ynab.Transaction{
+		{PayeeName: "АТБ-Маркет #12", CategoryID: "c-groceries"},
+		{PayeeName: "атб маркет 12", CategoryID: "c-groceries"},
+		{PayeeName: "АТБ Маркет 12", CategoryID: "c-groceries"},
+		{PayeeName: "АТБ Маркет 12", CategoryID: "c-cafe", Memo: "coffee"},
+		{PayeeName: "Uber", CategoryID: "c-transport", Memo: "taxi home"},
+		{PayeeName: "Bolt", CategoryID: "c-transport", Memo: "taxi to work"},
+		{PayeeName: "Rozetka", CategoryID: "c-gifts", Memo: "birthday present"},
+		{PayeeName: "Rozetka", CategoryID: "c-hidden"},
+		{PayeeName: "Rozetka", CategoryID: "c-gifts", Deleted: true},
+		{PayeeName: "Transfer : Cash", CategoryID: "c-groceries", TransferAccountID: "a-cash"},
+	}
+	s := suggest.New(categories, history)
+
+	t.Run("same payee", func(t *testing.T) {
+		got := s.Suggest("атб-маркет 12", "")
+		require.Len(t, got, 2)
+		assert.Equal(t, "c-groceries", got[0].CategoryID)
+		assert.Equal(t, "Groceries", got[0].CategoryName)
+		assert.InDelta(t, 0.6, got[0].Confidence, 0.001, "3 of 4 transactions, certainty 4/5")
+		assert.Equal(t, "c-cafe", got[1].CategoryID)
+		assert.InDelta(t, 0.2, got[1].Confidence, 0.001)
+	})
+
+	t.Run("memo keyword", func(t *testing.T) {
+		got := s.Suggest("Uklon", "taxi")
+		require.Len(t, got, 1)
+		assert.Equal(t, "c-transport", got[0].CategoryID)
+		assert.InDelta(t, 2.0/3, got[0].Confidence, 0.001)
+	})
+
+	t.Run("payee word", func(t *testing.T) {
+		best, ok := s.Best("маркет", "")
+		require.True(t, ok)
+		assert.Equal(t, "c-groceries", best.CategoryID)
+	})
+
+	t.Run("unknown categories and deleted transactions are skipped", func(t *testing.T) {
+		got := s.Suggest("rozetka", "")
+		require.Len(t, got, 1)
+		assert.Equal(t, suggest.Suggestion{CategoryID: "c-gifts", CategoryName: "Gifts", Confidence: 0.5}, got[0])
+	})
+
+	t.Run("transfers are skipped", func(t *testing.T) {
+		assert.Empty(t, s.Suggest("Transfer : Cash", ""))
+	})
+
+	t.Run("nothing alike", func(t *testing.T) {
+		assert.Empty(t, s.Suggest("Silpo", "12"))
+		_, ok := s.Best("Silpo", "")
+		assert.False(t, ok)
+	})
+}
+
+func TestSuggester_Best(t *testing.T) {
+	s := suggest.New([]ynab.Category{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}, {ID: "c", Name: "C"}},
+		[]ynab.Transaction{
+			{PayeeName: "Shop", CategoryID: "a"},
+			{PayeeName: "Shop", CategoryID: "b"},
+			{PayeeName: "Shop", CategoryID: "c"},
+		})
+
+	_, ok := s.Best("shop", "")
+	assert.False(t, ok, "payee is spread between categories too much")
+}
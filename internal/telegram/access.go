@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+)
+
+// AccessManager handles access requests of chats which are not linked to any tenant.
+type AccessManager interface {
+	Admins() []int64
+	Request(chatID int64, title string) bool
+	Approve(adminChatID, chatID int64) error
+	Deny(adminChatID, chatID int64) error
+	Revoke(adminChatID, chatID int64) error
+	Chats(adminChatID int64) ([]TenantChat, error)
+}
+
+type accessButtons struct {
+	request *tb.Btn
+	approve *tb.Btn
+	deny    *tb.Btn
+}
+
+func newAccessButtons() accessButtons {
+	return accessButtons{
+		request: &tb.Btn{Unique: "request_access"},
+		approve: &tb.Btn{Unique: "approve_access"},
+		deny:    &tb.Btn{Unique: "deny_access"},
+	}
+}
+
+// requestAccessMarkup returns markup attached to rejection message of chats without access.
+func (b *Bot) requestAccessMarkup(p *i18n.Printer) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(p.T("button.request_access"), b.accessBtns.request.Unique)))
+
+	return markup
+}
+
+func (b *Bot) requestAccessHandler(c tb.Context) error {
+	chat, p := c.Chat(), b.printerFrom(c)
+	b.log.Infow("request access handler", "chatID", chat.ID)
+
+	if _, ok := b.tenants.ByChat(chat.ID); ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.already_granted")})
+	}
+
+	title := chatTitle(chat)
+	if !b.access.Request(chat.ID, title) {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.already_requested")})
+	}
+
+	data := strconv.FormatInt(chat.ID, 10)
+	for _, adminID := range b.access.Admins() {
+		ap := b.printer(adminID, "")
+		markup := &tb.ReplyMarkup{}
+		markup.Inline(markup.Row(
+			markup.Data(ap.T("button.approve"), b.accessBtns.approve.Unique, data),
+			markup.Data(ap.T("button.deny"), b.accessBtns.deny.Unique, data),
+		))
+		msg := ap.T("access.request_admin", title, chat.ID)
+		if err := b.notify(adminID, "access_request", msg, markup); err != nil {
+			b.log.Errorw("failed to send access request to admin", "chatID", chat.ID, "adminChatID", adminID, "error", err)
+		}
+	}
+
+	return c.Respond(&tb.CallbackResponse{Text: p.T("access.requested")})
+}
+
+func (b *Bot) approveAccessHandler(c tb.Context) error {
+	return b.resolveAccessRequest(c, true)
+}
+
+func (b *Bot) denyAccessHandler(c tb.Context) error {
+	return b.resolveAccessRequest(c, false)
+}
+
+func (b *Bot) resolveAccessRequest(c tb.Context, approve bool) error {
+	p := b.printerFrom(c)
+	b.log.Infow("resolve access request handler", "chatID", c.Chat().ID, "data", c.Data(), "approve", approve)
+
+	chatID, err := strconv.ParseInt(c.Data(), 10, 64)
+	if err != nil {
+		b.log.Errorw("failed to parse chat id", "data", c.Data(), "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.invalid_request")})
+	}
+
+	resolve, reply, result := b.access.Deny, "access.not_allowed", "denied"
+	if approve {
+		resolve, reply, result = b.access.Approve, "access.granted", "approved"
+	}
+	if err = resolve(c.Chat().ID, chatID); err != nil {
+		b.log.Errorw("failed to resolve access request", "chatID", chatID, "approve", approve, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.request_resolved")})
+	}
+
+	if err = b.notify(chatID, "access_"+result, b.printer(chatID, "").T(reply)); err != nil {
+		b.log.Errorw("failed to send message", "chatID", chatID, "error", err)
+	}
+	if err = c.Edit(fmt.Sprintf("%s\n\n%s", c.Message().Text, p.T("access.request_"+result))); err != nil {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+	}
+
+	return c.Respond()
+}
+
+func (b *Bot) usersHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("users handler", "chatID", c.Chat().ID)
+
+	chats, err := b.access.Chats(c.Chat().ID)
+	if err != nil {
+		b.log.Errorw("failed to get chats", "chatID", c.Chat().ID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(p.T("access.chats_header") + "\n")
+	for _, chat := range chats {
+		if chat.Approved {
+			sb.WriteString(fmt.Sprintf("\n%d - %s", chat.ID, chat.Title))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n%d - %s", chat.ID, p.T("access.chat_configured")))
+		}
+	}
+	sb.WriteString("\n\n" + p.T("access.chats_footer"))
+
+	return b.sendWithErrorLogging(c, sb.String())
+}
+
+func (b *Bot) revokeHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("revoke handler", "chatID", c.Chat().ID, "args", c.Args())
+
+	if len(c.Args()) != 1 {
+		return b.sendWithErrorLogging(c, p.T("access.revoke_usage"))
+	}
+	chatID, err := strconv.ParseInt(c.Args()[0], 10, 64)
+	if err != nil {
+		return b.sendWithErrorLogging(c, p.T("access.revoke_usage"))
+	}
+
+	if err = b.access.Revoke(c.Chat().ID, chatID); err != nil {
+		b.log.Warnw("failed to revoke access", "chatID", chatID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("access.revoke_failed"))
+	}
+
+	if err = b.notify(chatID, "access_revoked", b.printer(chatID, "").T("access.revoked")); err != nil {
+		b.log.Errorw("failed to send message", "chatID", chatID, "error", err)
+	}
+
+	return b.sendWithErrorLogging(c, p.T("access.chat_revoked", chatID))
+}
+
+func chatTitle(chat *tb.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	if chat.Username != "" {
+		return "@" + chat.Username
+	}
+
+	return strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+}
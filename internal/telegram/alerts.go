@@ -0,0 +1,277 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	// DefaultAlertsInterval is how often new transactions are checked for anomalies.
+	DefaultAlertsInterval = 5 * time.Minute
+	// alertsHistoryDays is how many days of transactions new ones are compared to.
+	alertsHistoryDays = 90
+)
+
+// AlertStore keeps sensitivity of anomaly alerts of chats which turned them on.
+type AlertStore interface {
+	Alerts() map[int64]anomaly.Sensitivity
+	SetAlerts(chatID int64, s anomaly.Sensitivity) error
+	RemoveAlerts(chatID int64) error
+}
+
+// SeenStore keeps transactions checked for anomalies, so ones added while the bot is down are checked after restart.
+type SeenStore interface {
+	// Seen returns dates of checked transactions of the budget by their IDs and false if the budget was never checked.
+	Seen(budgetKey string) (map[string]string, bool)
+	SetSeen(budgetKey string, txs map[string]string) error
+}
+
+// memorySeen is SeenStore used if Dependencies.Seen is not set. Checked transactions are lost on restart.
+type memorySeen struct {
+	mu   sync.Mutex
+	seen map[string]map[string]string
+}
+
+func (m *memorySeen) Seen(budgetKey string) (map[string]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs, ok := m.seen[budgetKey]
+	res := make(map[string]string, len(txs))
+	for id, date := range txs {
+		res[id] = date
+	}
+	return res, ok
+}
+
+func (m *memorySeen) SetSeen(budgetKey string, txs map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seen == nil {
+		m.seen = make(map[string]map[string]string)
+	}
+	m.seen[budgetKey] = txs
+	return nil
+}
+
+// alertsSync is state of RunAlerts.
+type alertsSync struct {
+	// budgets are transactions of the recent alertsHistoryDays by tenant and budget.
+	budgets map[string]*budgetTransactions
+}
+
+// budgetTransactions are transactions of the budget kept up to date with delta requests.
+type budgetTransactions struct {
+	// knowledge is the YNAB server knowledge of the last request, zero before the first one.
+	knowledge    int64
+	transactions map[string]ynab.Transaction
+}
+
+// alertsHandler turns anomaly alerts on with "/alerts low|medium|high" or off with "/alerts off".
+func (b *Bot) alertsHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	chatID := c.Chat().ID
+	payload := strings.TrimSpace(c.Message().Payload)
+	b.log.Infow("alerts handler", "chatID", chatID, "payload", payload)
+
+	if payload == "off" {
+		if err := b.alerts.RemoveAlerts(chatID); err != nil {
+			b.log.Errorw("failed to remove alerts", "chatID", chatID, "error", err)
+			return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+		}
+		return b.sendWithErrorLogging(c, p.T("alerts.disabled"))
+	}
+
+	sensitivity, ok := anomaly.ParseSensitivity(payload)
+	if !ok {
+		if current, enabled := b.alerts.Alerts()[chatID]; enabled {
+			return b.sendWithErrorLogging(c, p.T("alerts.enabled", string(current))+"\n\n"+p.T("alerts.usage"))
+		}
+		return b.sendWithErrorLogging(c, p.T("alerts.usage"))
+	}
+	if err := b.alerts.SetAlerts(chatID, sensitivity); err != nil {
+		b.log.Errorw("failed to save alerts", "chatID", chatID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	return b.sendWithErrorLogging(c, p.T("alerts.enabled", string(sensitivity)))
+}
+
+// RunAlerts notifies chats which turned alerts on about anomalies of new transactions until ctx is done.
+// Duplicates of transactions created from drafts are also asked about in chats which created them.
+// Transactions existing on the first check of a budget are not reported. It must be called after Start.
+func (b *Bot) RunAlerts(ctx context.Context, interval time.Duration) {
+	if b.alerts == nil {
+		return
+	}
+
+	s := &alertsSync{budgets: make(map[string]*budgetTransactions)}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		b.syncAlerts(ctx, s)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bot) syncAlerts(ctx context.Context, s *alertsSync) {
+	alerts := b.alerts.Alerts()
+	chats := make(map[string][]int64)
+	for chatID := range alerts {
+		if t, ok := b.tenants.ByChat(chatID); ok {
+			chats[t.ID] = append(chats[t.ID], chatID)
+		}
+	}
+
+	// All budgets are checked, chats which created duplicates are asked about them even without alerts.
+	for _, t := range b.tenants.Tenants() {
+		chatIDs := chats[t.ID]
+		history, fresh, err := b.newTransactions(ctx, t, s)
+		if err != nil {
+			b.log.Warnw("failed to get transactions", "tenantID", t.ID, "error", err)
+			continue
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		// New transactions may teach suggestions something.
+		b.forgetResolver(t)
+
+		// Duplicates of imported transactions are asked about instead of being reported as anomalies.
+		pairs := anomaly.FindDuplicates(history, fresh)
+		paired := make(map[string]bool, 2*len(pairs)) //nolint: gomnd // two transactions per pair
+		for _, pair := range pairs {
+			paired[pair.Manual.ID], paired[pair.Imported.ID] = true, true
+		}
+		for _, chatID := range chatIDs {
+			p := b.printer(chatID, "")
+			for _, a := range anomaly.Detect(alerts[chatID].Config(), history, fresh) {
+				if paired[a.Transaction.ID] {
+					continue
+				}
+				if err = b.notify(chatID, "anomaly", formatAnomaly(p, a)); err != nil {
+					b.log.Errorw("failed to send alert", "chatID", chatID, "tenantID", t.ID, "error", err)
+				}
+			}
+		}
+		for _, pair := range pairs {
+			for _, chatID := range b.duplicateChats(t, chatIDs, pair) {
+				if err = b.notifyDuplicate(chatID, pair); err != nil {
+					b.log.Errorw("failed to send duplicate", "chatID", chatID, "tenantID", t.ID, "error", err)
+				}
+			}
+		}
+		b.remindUncategorized(t, chatIDs, uncategorized(fresh, paired))
+	}
+}
+
+// newTransactions returns transactions of the recent alertsHistoryDays split into ones checked before
+// and new ones. Transactions are fetched entirely once and then with delta requests. Checked ones are kept
+// in SeenStore, all transactions are checked on the first call for the budget.
+func (b *Bot) newTransactions(ctx context.Context, t *Tenant, s *alertsSync) ([]ynab.Transaction, []ynab.Transaction,
+	error,
+) {
+	ctx, cancelFunc := context.WithTimeout(ctx, handlerTimeout)
+	defer cancelFunc()
+
+	key := budgetKey(t)
+	bt := s.budgets[key]
+	if bt == nil {
+		bt = &budgetTransactions{transactions: make(map[string]ynab.Transaction)}
+		s.budgets[key] = bt
+	}
+	since := time.Now().AddDate(0, 0, -alertsHistoryDays)
+	changed, knowledge, err := t.Client.GetTransactionsDelta(ctx, t.BudgetID, since, bt.knowledge)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting transactions: %w", err)
+	}
+
+	seen, checked := b.seen.Seen(key)
+	if seen == nil {
+		seen = make(map[string]string)
+	}
+	updated := !checked
+	var fresh []ynab.Transaction
+	for _, tx := range changed {
+		if tx.Deleted {
+			delete(bt.transactions, tx.ID)
+			continue
+		}
+		bt.transactions[tx.ID] = tx
+		if _, ok := seen[tx.ID]; ok {
+			continue
+		}
+		seen[tx.ID], updated = tx.Date, true
+		if checked {
+			fresh = append(fresh, tx)
+		}
+	}
+
+	sinceDate := since.Format(ynabDateLayout)
+	for id, date := range seen {
+		if date < sinceDate {
+			delete(seen, id)
+			updated = true
+		}
+	}
+	if updated {
+		if err = b.seen.SetSeen(key, seen); err != nil {
+			return nil, nil, fmt.Errorf("saving seen transactions: %w", err)
+		}
+	}
+	bt.knowledge = knowledge
+
+	isFresh := make(map[string]bool, len(fresh))
+	for _, tx := range fresh {
+		isFresh[tx.ID] = true
+	}
+	history := make([]ynab.Transaction, 0, len(bt.transactions))
+	for id, tx := range bt.transactions {
+		switch {
+		case tx.Date < sinceDate:
+			delete(bt.transactions, id)
+		case !isFresh[id]:
+			history = append(history, tx)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].Date != history[j].Date {
+			return history[i].Date < history[j].Date
+		}
+		return history[i].ID < history[j].ID
+	})
+
+	return history, fresh, nil
+}
+
+func formatAnomaly(p *i18n.Printer, a anomaly.Anomaly) string {
+	money := func(amount int) string {
+		return p.Money(amount) + " " + p.T("statistic.currency")
+	}
+	tx := a.Transaction
+	switch a.Kind {
+	case anomaly.Spike:
+		return p.T("alert.spike", money(a.DaySpent), tx.CategoryName, shortDate(tx.Date), money(a.TypicalDay))
+	case anomaly.Duplicate:
+		return p.T("alert.duplicate", money(-tx.Amount), payeeOf(p, tx),
+			shortDate(a.Original.Date), a.Original.AccountName, shortDate(tx.Date), tx.AccountName)
+	default:
+		return p.T("alert.new_payee", payeeOf(p, tx), money(-tx.Amount), shortDate(tx.Date), tx.AccountName)
+	}
+}
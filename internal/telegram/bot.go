@@ -2,11 +2,14 @@ package telegram
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"time"
 
 	tb "gopkg.in/telebot.v3"
 
 	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
 	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
 )
 
@@ -18,128 +21,327 @@ type Logger interface {
 
 type YNABClient interface {
 	GetCategory(ctx context.Context, budgetID, categoryID string) (*ynab.Category, error)
+	GetCategories(ctx context.Context, budgetID string) ([]ynab.CategoryGroup, error)
+	// GetCategoriesDelta returns categories changed after lastKnowledge of YNAB server and its current knowledge.
+	GetCategoriesDelta(ctx context.Context, budgetID string, lastKnowledge int64) ([]ynab.CategoryGroup, int64, error)
+	GetCategoryTransactions(ctx context.Context, budgetID, categoryID string, since time.Time) ([]ynab.Transaction, error)
+	// GetCurrentMonthCategory and SetCurrentMonthBudgeted are used to change budget, so they must not be cached.
+	GetCurrentMonthCategory(ctx context.Context, budgetID, categoryID string) (*ynab.Category, error)
+	SetCurrentMonthBudgeted(ctx context.Context, budgetID, categoryID string, budgeted int) (*ynab.Category, error)
+	GetTransactions(ctx context.Context, budgetID string, since time.Time) ([]ynab.Transaction, error)
+	// GetTransactionsDelta returns transactions changed after lastKnowledge of YNAB server and its current knowledge.
+	GetTransactionsDelta(
+		ctx context.Context, budgetID string, since time.Time, lastKnowledge int64,
+	) ([]ynab.Transaction, int64, error)
+	GetTransaction(ctx context.Context, budgetID, transactionID string) (*ynab.Transaction, error)
+	CreateTransaction(ctx context.Context, budgetID string, tx ynab.NewTransaction) (*ynab.Transaction, error)
+	UpdateTransaction(
+		ctx context.Context, budgetID, transactionID string, tx ynab.NewTransaction,
+	) (*ynab.Transaction, error)
+	// UpdateTransactionFields changes only the fields set in upd, the rest of transaction is kept as is.
+	UpdateTransactionFields(
+		ctx context.Context, budgetID, transactionID string, upd ynab.TransactionUpdate,
+	) (*ynab.Transaction, error)
+	DeleteTransaction(ctx context.Context, budgetID, transactionID string) error
+	GetAccounts(ctx context.Context, budgetID string) ([]ynab.Account, error)
 }
 
-type StatisticMessageFormatter func(cat budget.GeneralCategoryStatistic) (string, error)
+// Linker links chats to YNAB accounts with OAuth.
+type Linker interface {
+	AuthURL(chatID, userID int64) (string, error)
+	SetCategory(chatID int64, categoryID string) error
+}
 
-type Bot struct {
-	chatIDs map[int64]struct{}
+// StatisticMessage is what statistic message is formatted from.
+type StatisticMessage struct {
+	ChatID int64
+	// Command is the command or button the message is sent for without leading slash, e.g. "state".
+	Command   string
+	Category  string
+	Printer   *i18n.Printer
+	Statistic budget.GeneralCategoryStatistic
+}
 
-	ynabBudgetID   string
-	ynabCategoryID string
-	ynabClient     YNABClient
-	msgFormatter   StatisticMessageFormatter
+type StatisticMessageFormatter func(msg StatisticMessage) (string, error)
 
-	markup   *tb.ReplyMarkup
-	stateBtn *tb.Btn
+type Bot struct {
+	bot *tb.Bot
+	ctx context.Context // base context of handlers, set in Start
+	// halt stops receiving updates, dispatched is closed when no more handlers are started after that.
+	halt       chan struct{}
+	haltOnce   sync.Once
+	dispatched chan struct{}
+	handlers   sync.WaitGroup
+
+	tenants      TenantResolver
+	linker       Linker
+	access       AccessManager
+	roles        RoleManager
+	msgFormatter StatisticMessageFormatter
+	metrics      Metrics
+	catalog      *i18n.Catalog
+	languages    LanguageStore
+	defaultLang  i18n.Lang
+	dashboards   DashboardStore
+	receipts     ReceiptStore
+	alerts       AlertStore
+	seen         SeenStore
+
+	stateBtn     *tb.Btn
+	categoryBtn  *tb.Btn
+	langBtn      *tb.Btn
+	historyBtn   *tb.Btn
+	historyTxBtn *tb.Btn
+	receiptBtn   *tb.Btn
+	accessBtns   accessButtons
+	moveBtns     moveButtons
+	expenseBtns  expenseButtons
+	dupBtns      duplicateButtons
+	reminderBtns reminderButtons
+
+	moves  pending[moveRequest]
+	drafts pending[expenseDraft]
+	// resolvers are resolvers of expenses by budget, they suggest categories too.
+	resolvers resolverCache
+	reminders reminders
+	// photos are receipt photos waiting for expense text.
+	photos pending[pendingPhoto]
 
 	log Logger
 }
 
-type YNABDependencies struct {
-	BudgetID   string
-	CategoryID string
-	Client     YNABClient
-}
-
 type Dependencies struct {
-	AllowedChats              []int64
-	YNAB                      YNABDependencies
+	Tenants TenantResolver
+	// Linker is optional. /link and /category commands are disabled without it.
+	Linker Linker
+	// Access is optional. Without it chats can't request access and admin commands are disabled.
+	Access                    AccessManager
+	Roles                     RoleManager
 	StatisticMessageFormatter StatisticMessageFormatter
-	Logger                    Logger
+	// Metrics is optional.
+	Metrics Metrics
+	// Catalog is optional. Built-in messages are used without it.
+	Catalog *i18n.Catalog
+	// Languages is optional. Chats can't choose language with /lang without it.
+	Languages LanguageStore
+	// DefaultLanguage is used for chats which haven't chosen language if language of the user is not supported.
+	// Ukrainian is used if empty.
+	DefaultLanguage i18n.Lang
+	// Dashboards is optional. Chats can't pin dashboard with /dashboard without it.
+	Dashboards DashboardStore
+	// Receipts is optional. Receipt photos are ignored without it.
+	Receipts ReceiptStore
+	// Alerts is optional. Chats can't turn anomaly alerts on with /alerts without it.
+	Alerts AlertStore
+	// Seen is optional. Without it transactions added while the bot is down are not checked for anomalies.
+	Seen   SeenStore
+	Logger Logger
 }
 
 func NewBot(deps Dependencies) *Bot {
-	chatIDs := make(map[int64]struct{}, len(deps.AllowedChats))
-	for _, chatID := range deps.AllowedChats {
-		chatIDs[chatID] = struct{}{}
+	metrics := deps.Metrics
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	catalog := deps.Catalog
+	if catalog == nil {
+		catalog = i18n.NewCatalog()
+	}
+	seen := deps.Seen
+	if seen == nil {
+		seen = &memorySeen{}
+	}
+	defaultLang := deps.DefaultLanguage
+	if defaultLang == "" {
+		defaultLang = i18n.Ukrainian
 	}
-	markup, btn := newMarkups()
 
 	return &Bot{
-		chatIDs: chatIDs,
-
-		ynabBudgetID:   deps.YNAB.BudgetID,
-		ynabCategoryID: deps.YNAB.CategoryID,
-		ynabClient:     deps.YNAB.Client,
-
-		markup:   markup,
-		stateBtn: btn,
+		tenants: deps.Tenants,
+		linker:  deps.Linker,
+		access:  deps.Access,
+		roles:   deps.Roles,
+
+		stateBtn:     &tb.Btn{Unique: "state"},
+		categoryBtn:  &tb.Btn{Unique: "category"},
+		langBtn:      &tb.Btn{Unique: "lang"},
+		historyBtn:   &tb.Btn{Unique: "history"},
+		historyTxBtn: &tb.Btn{Unique: "history_tx"},
+		receiptBtn:   &tb.Btn{Unique: "history_receipt"},
+		accessBtns:   newAccessButtons(),
+		moveBtns:     newMoveButtons(),
+		expenseBtns:  newExpenseButtons(),
+		dupBtns:      newDuplicateButtons(),
+		reminderBtns: newReminderButtons(),
 
 		msgFormatter: deps.StatisticMessageFormatter,
+		metrics:      metrics,
+		catalog:      catalog,
+		languages:    deps.Languages,
+		defaultLang:  defaultLang,
+		dashboards:   deps.Dashboards,
+		receipts:     deps.Receipts,
+		alerts:       deps.Alerts,
+		seen:         seen,
 
 		log: deps.Logger,
 	}
 }
 
-func (b *Bot) Start(bot *tb.Bot) {
-	bot.Use(AllowedChatsMiddleware(b.chatIDs, b.log))
+func (b *Bot) registerHandlers(bot *tb.Bot) {
+	if b.linker != nil {
+		bot.Handle("/link", b.linkHandler)
+	}
 
-	bot.Handle("/start", b.stateHandler)
-	bot.Handle("/state", b.stateHandler)
-	bot.Handle(b.stateBtn, b.stateHandler)
+	var rejectMarkup func(p *i18n.Printer) *tb.ReplyMarkup
+	if b.access != nil {
+		rejectMarkup = b.requestAccessMarkup
+		bot.Handle(b.accessBtns.request, b.requestAccessHandler)
+	}
+
+	// Free text is not rejected like commands are, expenseHandler checks the chat itself.
+	bot.Handle(tb.OnText, b.expenseHandler)
+	if b.receipts != nil {
+		bot.Handle(tb.OnPhoto, b.photoHandler)
+	}
 
-	bot.Start()
+	g := bot.Group()
+	g.Use(TenantMiddleware(b.tenants, b.printerFrom, rejectMarkup, b.log))
+
+	g.Handle("/start", b.stateHandler, b.require(RoleViewer))
+	g.Handle("/state", b.stateHandler, b.require(RoleViewer))
+	g.Handle(b.stateBtn, b.stateHandler, b.require(RoleViewer))
+	g.Handle("/chart", b.chartHandler, b.require(RoleViewer))
+	g.Handle("/history", b.historyHandler, b.require(RoleViewer))
+	g.Handle(b.historyBtn, b.historyPageHandler, b.require(RoleViewer))
+	g.Handle(b.historyTxBtn, b.historyTransactionHandler, b.require(RoleViewer))
+	g.Handle("/move", b.moveHandler, b.require(RoleMember))
+	g.Handle(b.moveBtns.from, b.moveCategoryHandler, b.require(RoleMember))
+	g.Handle(b.moveBtns.to, b.moveCategoryHandler, b.require(RoleMember))
+	g.Handle(b.moveBtns.confirm, b.moveConfirmHandler, b.require(RoleMember))
+	g.Handle(b.moveBtns.cancel, b.moveCancelHandler, b.require(RoleMember))
+	for _, btn := range []*tb.Btn{b.expenseBtns.category, b.expenseBtns.account, b.expenseBtns.date} {
+		g.Handle(btn, b.expensePickHandler, b.require(RoleMember))
+	}
+	for _, btn := range []*tb.Btn{b.expenseBtns.setCategory, b.expenseBtns.setAccount, b.expenseBtns.setDate} {
+		g.Handle(btn, b.expenseSetHandler, b.require(RoleMember))
+	}
+	g.Handle(b.expenseBtns.back, b.expenseBackHandler, b.require(RoleMember))
+	g.Handle(b.expenseBtns.create, b.expenseCreateHandler, b.require(RoleMember))
+	g.Handle(b.expenseBtns.cancel, b.expenseCancelHandler, b.require(RoleMember))
+	g.Handle("/role", b.roleHandler, b.require(RoleAdmin))
+	if b.languages != nil {
+		g.Handle("/lang", b.langHandler, b.require(RoleMember))
+		g.Handle(b.langBtn, b.langSelectedHandler, b.require(RoleMember))
+	}
+	if b.receipts != nil {
+		g.Handle(b.receiptBtn, b.historyReceiptHandler, b.require(RoleViewer))
+	}
+	if b.alerts != nil {
+		g.Handle("/alerts", b.alertsHandler, b.require(RoleAdmin))
+		g.Handle(b.dupBtns.deleteManual, b.duplicateDeleteHandler, b.require(RoleMember))
+		g.Handle(b.dupBtns.keepBoth, b.duplicateKeepHandler, b.require(RoleMember))
+		g.Handle(b.reminderBtns.category, b.reminderCategoryHandler, b.require(RoleMember))
+		g.Handle(b.reminderBtns.skip, b.reminderSkipHandler, b.require(RoleMember))
+	}
+	if b.dashboards != nil {
+		g.Handle("/dashboard", b.dashboardHandler, b.require(RoleAdmin))
+	}
+	if b.linker != nil {
+		g.Handle("/category", b.categoryHandler, b.require(RoleAdmin))
+		g.Handle(b.categoryBtn, b.categorySelectedHandler, b.require(RoleAdmin))
+	}
+	if b.access != nil {
+		g.Handle(b.accessBtns.approve, b.approveAccessHandler, b.require(RoleAdmin))
+		g.Handle(b.accessBtns.deny, b.denyAccessHandler, b.require(RoleAdmin))
+		g.Handle("/users", b.usersHandler, b.require(RoleAdmin))
+		g.Handle("/revoke", b.revokeHandler, b.require(RoleAdmin))
+	}
 }
 
 func (b *Bot) stateHandler(c tb.Context) error {
-	b.log.Infow("status handler", "chatID", c.Chat().ID)
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("status handler", "chatID", c.Chat().ID, "tenantID", t.ID)
+
+	if t.CategoryID == "" {
+		return b.categoryHandler(c)
+	}
 
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancelFunc := b.requestContext()
 	defer cancelFunc()
-	cat, err := b.ynabClient.GetCategory(ctx, b.ynabBudgetID, b.ynabCategoryID)
+	cat, err := t.Client.GetCategory(ctx, t.BudgetID, t.CategoryID)
 	if err != nil {
-		b.log.Errorw("failed to get category", "error", err)
-		return b.sendWithErrorLogging(c, "Unexpected error occurred. You know whom to call")
+		b.log.Errorw("failed to get category", "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
 	}
 
 	if cat == nil {
-		b.log.Warnw("category is nil", "chatID", c.Chat().ID, "budgetID", b.ynabBudgetID, "categoryID", b.ynabCategoryID)
-		return b.sendWithErrorLogging(c, "Unexpected error occurred. You know whom to call")
+		b.log.Warnw("category is nil",
+			"chatID", c.Chat().ID, "tenantID", t.ID, "budgetID", t.BudgetID, "categoryID", t.CategoryID,
+		)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
 	}
 
-	msg, err := b.msgFormatter(budget.CalculateStatistic(*cat))
+	msg, err := b.msgFormatter(StatisticMessage{
+		ChatID:    c.Chat().ID,
+		Command:   strings.TrimPrefix(commandOf(c), "/"),
+		Category:  cat.Name,
+		Printer:   p,
+		Statistic: budget.CalculateStatistic(*cat),
+	})
 	if err != nil {
 		b.log.Errorw("failed to format message", "chatID", c.Chat().ID, "error", err)
-		return b.sendWithErrorLogging(c, "Unexpected error occurred. You know whom to call")
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
 	}
 
-	return b.sendWithErrorLogging(c, msg)
+	if c.Callback() != nil {
+		return b.refreshState(c, msg, p)
+	}
+	return b.sendHTML(c, msg, b.stateMarkup(p))
 }
 
-func (b *Bot) sendWithErrorLogging(c tb.Context, msg string) error {
-	if err := c.Send(msg, b.markup); err != nil {
-		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+// refreshState shows msg in the message with the tapped button instead of sending a new one.
+// The message is left as is if statistic hasn't changed since it was shown.
+func (b *Bot) refreshState(c tb.Context, msg string, p *i18n.Printer) error {
+	if c.Message() != nil && sameState(c.Message().Text, msg) {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("statistic.not_changed")})
+	}
+
+	if err := b.editHTML(c, withUpdatedAt(msg, p), b.stateMarkup(p)); err != nil {
 		return err
 	}
 
-	return nil
+	return c.Respond()
 }
 
-func AllowedChatsMiddleware(chatIDs map[int64]struct{}, log Logger) func(next tb.HandlerFunc) tb.HandlerFunc {
-	return func(next tb.HandlerFunc) tb.HandlerFunc {
-		return func(c tb.Context) error {
-			if _, ok := chatIDs[c.Chat().ID]; !ok {
-				log.Warnw("chat is not allowed", "chatID", c.Chat().ID)
-				if err := c.Send("You are not allowed to use this bot"); err != nil {
-					log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
-				}
+// withUpdatedAt appends the current time to statistic message.
+func withUpdatedAt(msg string, p *i18n.Printer) string {
+	return strings.TrimRight(msg, "\n") + "\n\n<i>" +
+		EscapeHTML(p.T("statistic.updated_at", time.Now().Format("15:04"))) + "</i>"
+}
 
-				return nil
-			}
+// sameState reports whether shown plain text of the message is msg followed by the time it was updated at.
+func sameState(shown, msg string) bool {
+	rest, ok := strings.CutPrefix(shown, strings.TrimSpace(StripHTML(msg)))
+	rest = strings.TrimLeft(rest, " ")
+	return ok && (rest == "" || rest[0] == '\n' && !strings.Contains(strings.TrimSpace(rest), "\n"))
+}
 
-			return next(c)
-		}
+func (b *Bot) sendWithErrorLogging(c tb.Context, msg string) error {
+	if err := c.Send(msg, b.stateMarkup(b.printerFrom(c))); err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
 	}
+
+	return nil
 }
 
-func newMarkups() (*tb.ReplyMarkup, *tb.Btn) {
+// stateMarkup returns markup with button showing statistic.
+func (b *Bot) stateMarkup(p *i18n.Printer) *tb.ReplyMarkup {
 	markup := &tb.ReplyMarkup{}
-
-	btn := markup.Data("Стан", "state")
 	markup.Inline(
-		markup.Row(btn),
+		markup.Row(markup.Data(p.T("button.state"), b.stateBtn.Unique)),
 	)
 
-	return markup, &btn
+	return markup
 }
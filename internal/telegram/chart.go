@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/chart"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// maxChartBars is the number of categories shown on the breakdown chart.
+const maxChartBars = 8
+
+// chartHandler sends chart of spending of the watched category this month and breakdown of spending by categories.
+func (b *Bot) chartHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("chart handler", "chatID", c.Chat().ID, "tenantID", t.ID)
+
+	if t.CategoryID == "" {
+		return b.categoryHandler(c)
+	}
+
+	now := time.Now()
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	cat, err := t.Client.GetCategory(ctx, t.BudgetID, t.CategoryID)
+	if err != nil {
+		b.log.Errorw("failed to get category", "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+	if cat == nil {
+		b.log.Warnw("category is nil",
+			"chatID", c.Chat().ID, "tenantID", t.ID, "budgetID", t.BudgetID, "categoryID", t.CategoryID,
+		)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	txs, err := t.Client.GetCategoryTransactions(ctx, t.BudgetID, t.CategoryID, since)
+	if err != nil {
+		b.log.Errorw("failed to get transactions", "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+	groups, err := t.Client.GetCategories(ctx, t.BudgetID)
+	if err != nil {
+		b.log.Errorw("failed to get categories", "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+
+	s := budget.CalculateStatisticAt(*cat, now)
+	top := topSpending(groups, maxChartBars)
+	bars := make([]chart.Bar, 0, len(top))
+	for i, spending := range top {
+		bars = append(bars, chart.Bar{Label: strconv.Itoa(i + 1), Value: -spending.Activity})
+	}
+
+	var buf bytes.Buffer
+	if err = chart.Render(&buf, chart.MonthOf(s, txs, now), bars); err != nil {
+		b.log.Errorw("failed to render chart", "chatID", c.Chat().ID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	photo := &tb.Photo{File: tb.FromReader(&buf), Caption: chartCaption(p, *cat, s, top)}
+	if err = c.Send(photo, b.stateMarkup(p)); err != nil {
+		b.log.Errorw("failed to send chart", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ynabErrorMessage returns message shown to user when YNAB request fails.
+func (b *Bot) ynabErrorMessage(p *i18n.Printer, err error) string {
+	if errors.Is(err, ynab.ErrUnauthorized) && b.linker != nil {
+		return p.T("error.ynab_unauthorized")
+	}
+
+	return p.T("error.unexpected")
+}
+
+// chartCaption lists names of the categories as chart can't draw them.
+func chartCaption(p *i18n.Printer, cat ynab.Category, s budget.GeneralCategoryStatistic, top []ynab.Category) string {
+	var sb strings.Builder
+	sb.WriteString(p.T("chart.summary",
+		cat.Name, p.Money(-s.Activity), p.Money(s.Balance-s.Activity), p.T("statistic.currency"),
+	))
+	sb.WriteString("\n" + p.T("chart.legend"))
+	if len(top) > 0 {
+		sb.WriteString("\n\n" + p.T("chart.breakdown"))
+		for i, c := range top {
+			sb.WriteString(fmt.Sprintf("\n%d. %s — %s", i+1, c.Name, p.Money(-c.Activity)))
+		}
+	}
+
+	return sb.String()
+}
+
+// topSpending returns up to n visible categories with the most money spent this month.
+func topSpending(groups []ynab.CategoryGroup, n int) []ynab.Category {
+	var res []ynab.Category
+	for _, g := range groups {
+		if g.Hidden || g.Deleted || g.Name == internalCategoryGroup {
+			continue
+		}
+		for _, cat := range g.Categories {
+			if cat.Hidden || cat.Deleted || cat.Activity >= 0 {
+				continue
+			}
+			res = append(res, cat)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Activity < res[j].Activity
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+
+	return res
+}
@@ -0,0 +1,239 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+)
+
+const (
+	// DefaultDashboardSyncInterval is how often YNAB is asked for changes of categories shown on dashboards.
+	DefaultDashboardSyncInterval = 5 * time.Minute
+	// DefaultDashboardRefreshInterval is how often dashboards are refreshed even if nothing has changed.
+	DefaultDashboardRefreshInterval = time.Hour
+)
+
+// DashboardStore keeps IDs of pinned dashboard messages of chats.
+type DashboardStore interface {
+	Dashboards() map[int64]int
+	SetDashboard(chatID int64, messageID int) error
+	RemoveDashboard(chatID int64) error
+}
+
+// dashboardSync is state of RunDashboards.
+type dashboardSync struct {
+	// knowledge is the last YNAB server knowledge of budgets by tenant.
+	knowledge map[string]int64
+	refreshed map[int64]time.Time
+}
+
+// dashboardHandler pins dashboard to the chat replacing the previous one. "/dashboard off" unpins it.
+func (b *Bot) dashboardHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	chatID := c.Chat().ID
+	b.log.Infow("dashboard handler", "chatID", chatID, "tenantID", t.ID)
+
+	old, exists := b.dashboards.Dashboards()[chatID]
+	if strings.TrimSpace(c.Message().Payload) == "off" {
+		if exists {
+			b.unpinDashboard(c.Chat(), old)
+			if err := b.dashboards.RemoveDashboard(chatID); err != nil {
+				b.log.Errorw("failed to remove dashboard", "chatID", chatID, "error", err)
+				return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+			}
+		}
+		return b.sendWithErrorLogging(c, p.T("dashboard.disabled"))
+	}
+
+	if t.CategoryID == "" {
+		return b.categoryHandler(c)
+	}
+
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	text, err := b.dashboardText(ctx, t, chatID, p)
+	if err != nil {
+		b.log.Errorw("failed to prepare dashboard", "chatID", chatID, "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+
+	if exists {
+		b.unpinDashboard(c.Chat(), old)
+	}
+	if err = b.pinDashboard(c.Chat(), text); err != nil {
+		b.log.Errorw("failed to pin dashboard", "chatID", chatID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("dashboard.pin_failed"))
+	}
+
+	return nil
+}
+
+// RunDashboards keeps pinned dashboards up to date until ctx is done. Every syncInterval YNAB is asked for changes
+// of the watched categories with delta requests. Dashboards are refreshed when their category changes
+// and at least every refreshInterval. Deleted dashboards are sent and pinned again. It must be called after Start.
+func (b *Bot) RunDashboards(ctx context.Context, syncInterval, refreshInterval time.Duration) {
+	if b.dashboards == nil {
+		return
+	}
+
+	s := &dashboardSync{knowledge: make(map[string]int64), refreshed: make(map[int64]time.Time)}
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		b.syncDashboards(ctx, s, refreshInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bot) syncDashboards(ctx context.Context, s *dashboardSync, refreshInterval time.Duration) {
+	dashboards := b.dashboards.Dashboards()
+	chats := make(map[*Tenant][]int64)
+	for chatID := range dashboards {
+		t, ok := b.tenants.ByChat(chatID)
+		if !ok || t.CategoryID == "" {
+			continue
+		}
+		chats[t] = append(chats[t], chatID)
+	}
+
+	for t, chatIDs := range chats {
+		changed, err := b.watchedCategoryChanged(ctx, t, s)
+		if err != nil {
+			b.log.Warnw("failed to get changes of categories", "tenantID", t.ID, "error", err)
+		}
+		for _, chatID := range chatIDs {
+			if !changed && time.Since(s.refreshed[chatID]) < refreshInterval {
+				continue
+			}
+			if err = b.refreshDashboard(ctx, t, chatID, dashboards[chatID]); err != nil {
+				b.log.Errorw("failed to refresh dashboard", "chatID", chatID, "tenantID", t.ID, "error", err)
+				continue
+			}
+			s.refreshed[chatID] = time.Now()
+		}
+	}
+}
+
+// watchedCategoryChanged reports whether category of the tenant changed since the previous call.
+// The first call reports change as YNAB returns all categories then.
+func (b *Bot) watchedCategoryChanged(ctx context.Context, t *Tenant, s *dashboardSync) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, handlerTimeout)
+	defer cancelFunc()
+
+	key := budgetKey(t)
+	groups, knowledge, err := t.Client.GetCategoriesDelta(ctx, t.BudgetID, s.knowledge[key])
+	if err != nil {
+		return false, fmt.Errorf("getting categories delta: %w", err)
+	}
+	s.knowledge[key] = knowledge
+
+	for _, g := range groups {
+		for _, cat := range g.Categories {
+			if cat.ID == t.CategoryID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// refreshDashboard edits dashboard message. New dashboard is pinned if the message was deleted.
+func (b *Bot) refreshDashboard(ctx context.Context, t *Tenant, chatID int64, messageID int) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, handlerTimeout)
+	defer cancelFunc()
+
+	text, err := b.dashboardText(ctx, t, chatID, b.printer(chatID, ""))
+	if err != nil {
+		return err
+	}
+
+	msg := &tb.StoredMessage{ChatID: chatID, MessageID: strconv.Itoa(messageID)}
+	_, err = b.bot.Edit(msg, text, tb.ModeHTML)
+	if isParseError(err) {
+		b.log.Warnw("telegram rejected html, editing dashboard with plain text", "chatID", chatID, "error", err)
+		_, err = b.bot.Edit(msg, StripHTML(text))
+	}
+	switch {
+	case err == nil, isNotModified(err):
+		return nil
+	case isMessageGone(err):
+		b.log.Infow("dashboard is deleted, pinning new one", "chatID", chatID, "messageID", messageID)
+		return b.pinDashboard(&tb.Chat{ID: chatID}, text)
+	default:
+		return fmt.Errorf("editing dashboard: %w", err)
+	}
+}
+
+// dashboardText formats statistic of the watched category with "dashboard" command, so templates can customize it.
+// Tenant watches a single category, so the dashboard shows only that one.
+func (b *Bot) dashboardText(ctx context.Context, t *Tenant, chatID int64, p *i18n.Printer) (string, error) {
+	cat, err := t.Client.GetCategory(ctx, t.BudgetID, t.CategoryID)
+	if err != nil {
+		return "", fmt.Errorf("getting category: %w", err)
+	}
+	if cat == nil {
+		return "", errors.New("category is nil")
+	}
+
+	msg, err := b.msgFormatter(StatisticMessage{
+		ChatID:    chatID,
+		Command:   "dashboard",
+		Category:  cat.Name,
+		Printer:   p,
+		Statistic: budget.CalculateStatistic(*cat),
+	})
+	if err != nil {
+		return "", fmt.Errorf("formatting message: %w", err)
+	}
+
+	return withUpdatedAt(msg, p), nil
+}
+
+// pinDashboard sends dashboard silently, remembers and pins it.
+func (b *Bot) pinDashboard(chat *tb.Chat, text string) error {
+	msg, err := b.bot.Send(chat, text, tb.ModeHTML, tb.Silent)
+	if isParseError(err) {
+		b.log.Warnw("telegram rejected html, sending dashboard as plain text", "chatID", chat.ID, "error", err)
+		msg, err = b.bot.Send(chat, StripHTML(text), tb.Silent)
+	}
+	b.metrics.ObserveNotification("dashboard", err)
+	if err != nil {
+		return fmt.Errorf("sending dashboard: %w", err)
+	}
+
+	if err = b.dashboards.SetDashboard(chat.ID, msg.ID); err != nil {
+		return fmt.Errorf("saving dashboard: %w", err)
+	}
+	if err = b.bot.Pin(msg, tb.Silent); err != nil {
+		return fmt.Errorf("pinning dashboard: %w", err)
+	}
+
+	return nil
+}
+
+// unpinDashboard unpins the previous dashboard. The message itself is kept.
+func (b *Bot) unpinDashboard(chat *tb.Chat, messageID int) {
+	if err := b.bot.Unpin(chat, messageID); err != nil {
+		b.log.Warnw("failed to unpin dashboard", "chatID", chat.ID, "messageID", messageID, "error", err)
+	}
+}
+
+// isMessageGone reports whether message can't be edited because it was deleted.
+// telebot doesn't have dedicated error for it, so description is checked.
+func isMessageGone(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message to edit not found")
+}
@@ -0,0 +1,201 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// duplicateShownLength is length of fingerprint of the shown pair in callback data. Callback data is limited
+// to 64 bytes and ID of the manual transaction takes 36 of them.
+const duplicateShownLength = 8
+
+type duplicateButtons struct {
+	deleteManual *tb.Btn
+	keepBoth     *tb.Btn
+}
+
+func newDuplicateButtons() duplicateButtons {
+	return duplicateButtons{
+		deleteManual: &tb.Btn{Unique: "dup_delete"},
+		keepBoth:     &tb.Btn{Unique: "dup_keep"},
+	}
+}
+
+// duplicateChats returns chats which are asked about the pair: chats of the tenant with alerts and the chat
+// the manual transaction was created from.
+func (b *Bot) duplicateChats(t *Tenant, alertChatIDs []int64, pair anomaly.DuplicatePair) []int64 {
+	chatID, ok := expense.ImportChatID(pair.Manual.ImportID)
+	if !ok {
+		return alertChatIDs
+	}
+	if other, linked := b.tenants.ByChat(chatID); !linked || other.ID != t.ID {
+		return alertChatIDs
+	}
+	for _, id := range alertChatIDs {
+		if id == chatID {
+			return alertChatIDs
+		}
+	}
+
+	return append(append([]int64(nil), alertChatIDs...), chatID)
+}
+
+// notifyDuplicate asks the chat whether the manual transaction of the pair should be deleted.
+// Data of the buttons is ID of the manual transaction and fingerprint of the pair. The pair is found again when
+// a button is pressed and it is not deleted unless it is the one shown.
+func (b *Bot) notifyDuplicate(chatID int64, pair anomaly.DuplicatePair) error {
+	p := b.printer(chatID, "")
+	shown := duplicateShown(pair)
+	markup := &tb.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(p.T("button.delete_manual"), b.dupBtns.deleteManual.Unique, pair.Manual.ID, shown),
+		markup.Data(p.T("button.keep_both"), b.dupBtns.keepBoth.Unique, pair.Manual.ID),
+	))
+
+	return b.notify(chatID, "duplicate", formatDuplicate(p, pair), markup)
+}
+
+// duplicateDeleteHandler deletes the manual transaction of the pair. Category and memo of the manual transaction
+// are moved to the imported one if it doesn't have them.
+func (b *Bot) duplicateDeleteHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	manualID, shown, _ := strings.Cut(c.Data(), "|")
+	b.log.Infow("duplicate delete handler", "chatID", c.Chat().ID, "tenantID", t.ID, "transactionID", manualID)
+
+	pair, ok, err := b.findDuplicate(t, manualID)
+	if err != nil {
+		b.log.Errorw("failed to find duplicate", "tenantID", t.ID, "transactionID", manualID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	// The pair may be found with another imported transaction or changed since it was shown.
+	if !ok || duplicateShown(pair) != shown {
+		return b.resolveQuestion(c, p.T("duplicate.not_found"))
+	}
+
+	merged, err := b.mergeDuplicate(t, pair)
+	b.forgetResolver(t)
+	if err != nil {
+		b.log.Errorw("failed to merge duplicate", "tenantID", t.ID, "transactionID", manualID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	b.log.Infow("duplicate deleted", "chatID", c.Chat().ID, "tenantID", t.ID,
+		"manualID", pair.Manual.ID, "importedID", pair.Imported.ID, "merged", merged)
+
+	if merged {
+		return b.resolveQuestion(c, p.T("duplicate.merged"))
+	}
+	return b.resolveQuestion(c, p.T("duplicate.deleted"))
+}
+
+func (b *Bot) duplicateKeepHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("duplicate keep handler", "chatID", c.Chat().ID, "transactionID", c.Data())
+
+	return b.resolveQuestion(c, p.T("duplicate.kept"))
+}
+
+// resolveQuestion replaces buttons of the question the bot asked on its own with the result.
+func (b *Bot) resolveQuestion(c tb.Context, result string) error {
+	text := result
+	if c.Message() != nil && c.Message().Text != "" {
+		text = c.Message().Text + "\n\n" + result
+	}
+	if err := c.Edit(text); err != nil && !isNotModified(err) {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	return c.Respond()
+}
+
+// findDuplicate finds the pair of the manual transaction among recent transactions of the budget.
+func (b *Bot) findDuplicate(t *Tenant, manualID string) (anomaly.DuplicatePair, bool, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	txs, err := t.Client.GetTransactions(ctx, t.BudgetID, time.Now().AddDate(0, 0, -alertsHistoryDays))
+	if err != nil {
+		return anomaly.DuplicatePair{}, false, fmt.Errorf("getting transactions: %w", err)
+	}
+	var history, manual []ynab.Transaction
+	for _, tx := range txs {
+		if tx.ID == manualID {
+			manual = append(manual, tx)
+		} else {
+			history = append(history, tx)
+		}
+	}
+	for _, pair := range anomaly.FindDuplicates(history, manual) {
+		if pair.Manual.ID == manualID {
+			return pair, true, nil
+		}
+	}
+
+	return anomaly.DuplicatePair{}, false, nil
+}
+
+// mergeDuplicate copies category and memo of the manual transaction to the imported one if it doesn't have them
+// and deletes the manual transaction. It reports whether the imported transaction was updated.
+func (b *Bot) mergeDuplicate(t *Tenant, pair anomaly.DuplicatePair) (bool, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	var upd ynab.TransactionUpdate
+	if pair.Imported.CategoryID == "" {
+		upd.CategoryID = pair.Manual.CategoryID
+	}
+	if pair.Imported.Memo == "" {
+		upd.Memo = pair.Manual.Memo
+	}
+	merged := upd != (ynab.TransactionUpdate{})
+	if merged {
+		_, err := t.Client.UpdateTransactionFields(ctx, t.BudgetID, pair.Imported.ID, upd)
+		if err != nil {
+			return false, fmt.Errorf("updating imported transaction: %w", err)
+		}
+	}
+
+	if err := t.Client.DeleteTransaction(ctx, t.BudgetID, pair.Manual.ID); err != nil {
+		return false, fmt.Errorf("deleting manual transaction: %w", err)
+	}
+
+	return merged, nil
+}
+
+// duplicateShown returns fingerprint of what is shown about the pair: the imported transaction and amounts and dates
+// of both transactions.
+func duplicateShown(pair anomaly.DuplicatePair) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		pair.Imported.ID,
+		strconv.Itoa(pair.Manual.Amount), pair.Manual.Date,
+		strconv.Itoa(pair.Imported.Amount), pair.Imported.Date,
+	}, "|")))
+
+	return hex.EncodeToString(sum[:])[:duplicateShownLength]
+}
+
+func formatDuplicate(p *i18n.Printer, pair anomaly.DuplicatePair) string {
+	line := func(tx ynab.Transaction) string {
+		category := tx.CategoryName
+		if tx.CategoryID == "" {
+			category = p.T("expense.no_category")
+		}
+		return p.Money(-tx.Amount) + " " + p.T("statistic.currency") + ", " + payeeOf(p, tx) + ", " +
+			shortDate(tx.Date) + ", " + category
+	}
+
+	return p.T("duplicate.found", pair.Manual.AccountName) + "\n\n" +
+		p.T("duplicate.manual", line(pair.Manual)) + "\n" +
+		p.T("duplicate.imported", line(pair.Imported)) + "\n\n" +
+		p.T("duplicate.question")
+}
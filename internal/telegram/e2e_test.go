@@ -0,0 +1,1250 @@
+package telegram_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/receipts"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram/telegramtest"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab/ynabtest"
+)
+
+const (
+	chatID        = 100
+	unknownChatID = 200
+	viewerChatID  = 300
+)
+
+type tenantsStub map[int64]*telegram.Tenant
+
+func (s tenantsStub) ByChat(chatID int64) (*telegram.Tenant, bool) {
+	t, ok := s[chatID]
+	return t, ok
+}
+
+func (s tenantsStub) Tenants() []*telegram.Tenant {
+	seen := make(map[string]bool)
+	var res []*telegram.Tenant
+	for _, t := range s {
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// rolesStub keeps roles by user IDs, users are admins by default. ID of a private chat is ID of its user.
+type rolesStub map[int64]telegram.Role
+
+func (s rolesStub) Role(_ string, _, userID int64) telegram.Role {
+	if r, ok := s[userID]; ok {
+		return r
+	}
+	return telegram.RoleAdmin
+}
+
+func (s rolesStub) SetRole(string, int64, telegram.Role) error {
+	return nil
+}
+
+type linkerStub struct{}
+
+func (linkerStub) AuthURL(int64, int64) (string, error) {
+	return "https://app.ynab.com/oauth/authorize", nil
+}
+
+func (linkerStub) SetCategory(int64, string) error {
+	return nil
+}
+
+type languagesStub struct {
+	mu    sync.Mutex
+	langs map[int64]i18n.Lang
+}
+
+func (s *languagesStub) Language(chatID int64) (i18n.Lang, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.langs[chatID]
+	return l, ok
+}
+
+func (s *languagesStub) SetLanguage(chatID int64, lang i18n.Lang) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.langs[chatID] = lang
+	return nil
+}
+
+type dashboardsStub struct {
+	mu       sync.Mutex
+	messages map[int64]int
+}
+
+func (s *dashboardsStub) Dashboards() map[int64]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[int64]int, len(s.messages))
+	for chatID, messageID := range s.messages {
+		res[chatID] = messageID
+	}
+	return res
+}
+
+func (s *dashboardsStub) SetDashboard(chatID int64, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[chatID] = messageID
+	return nil
+}
+
+func (s *dashboardsStub) RemoveDashboard(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, chatID)
+	return nil
+}
+
+type alertsStub struct {
+	mu     sync.Mutex
+	alerts map[int64]anomaly.Sensitivity
+}
+
+func (s *alertsStub) Alerts() map[int64]anomaly.Sensitivity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[int64]anomaly.Sensitivity, len(s.alerts))
+	for chatID, sensitivity := range s.alerts {
+		res[chatID] = sensitivity
+	}
+	return res
+}
+
+func (s *alertsStub) SetAlerts(chatID int64, sensitivity anomaly.Sensitivity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts[chatID] = sensitivity
+	return nil
+}
+
+func (s *alertsStub) RemoveAlerts(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.alerts, chatID)
+	return nil
+}
+
+type seenStub struct {
+	mu   sync.Mutex
+	seen map[string]map[string]string
+}
+
+func (s *seenStub) Seen(budgetKey string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	txs, ok := s.seen[budgetKey]
+	res := make(map[string]string, len(txs))
+	for id, date := range txs {
+		res[id] = date
+	}
+	return res, ok
+}
+
+func (s *seenStub) SetSeen(budgetKey string, txs map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[budgetKey] = txs
+	return nil
+}
+
+type e2e struct {
+	tg   *telegramtest.Server
+	ynab *ynabtest.Server
+	bot  *telegram.Bot
+}
+
+// startBot starts bot connected to fake Telegram and YNAB APIs. Chats chatID and viewerChatID belong to the tenant.
+// Chats speak English unless they choose other language with /lang.
+func startBot(t *testing.T, categoryID string, linker telegram.Linker, opts ...func(*telegram.Dependencies)) *e2e {
+	t.Helper()
+
+	env := &e2e{tg: telegramtest.NewServer(t), ynab: ynabtest.NewServer(ynabtest.DefaultFixture())}
+	log := zap.NewNop().Sugar()
+
+	tenant := &telegram.Tenant{
+		ID:         "family",
+		BudgetID:   "budget-1",
+		CategoryID: categoryID,
+		Client:     ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), log),
+	}
+	formatter, err := telegram.NewDefaultStatisticMessageFormatter()
+	require.NoError(t, err)
+
+	deps := telegram.Dependencies{
+		Tenants:                   tenantsStub{chatID: tenant, viewerChatID: tenant},
+		Linker:                    linker,
+		Roles:                     rolesStub{viewerChatID: telegram.RoleViewer},
+		StatisticMessageFormatter: formatter,
+		Languages:                 &languagesStub{langs: map[int64]i18n.Lang{}},
+		DefaultLanguage:           i18n.English,
+		Logger:                    log,
+	}
+	for _, opt := range opts {
+		opt(&deps)
+	}
+	bot := telegram.NewBot(deps)
+	env.bot = bot
+	telebot, err := env.tg.NewBot()
+	require.NoError(t, err)
+	bot.Start(context.Background(), telebot)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, bot.Stop(ctx))
+	})
+
+	return env
+}
+
+func TestBot_State(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/start")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Contains(t, msgs[0].Text, budget.FormatMoney(580000))
+	require.NotNil(t, msgs[0].ReplyMarkup)
+
+	env.tg.SendText(chatID, "/state")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, msgs[0].Text, msgs[1].Text)
+
+	assert.Equal(t, []string{
+		"GET /v1/budgets/budget-1/categories/category-groceries",
+		"GET /v1/budgets/budget-1/categories/category-groceries",
+	}, env.ynab.Requests())
+}
+
+func TestBot_StateRefresh(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/state")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+
+	require.NoError(t, env.tg.Press(msgs[0], "Status"))
+	waitCalls(t, env, "answerCallbackQuery", 1)
+	assert.Empty(t, env.tg.Calls("editMessageText"), "message is not edited when nothing changed")
+	assert.Contains(t, env.tg.Calls("answerCallbackQuery")[0].Params["text"], "Nothing has changed")
+
+	require.NoError(t, env.ynab.SetCategory("budget-1", ynab.Category{
+		ID: "category-groceries", Name: "Groceries", Budgeted: 1000000, Activity: -500000, Balance: 500000,
+	}))
+	require.NoError(t, env.tg.Press(msgs[0], "Status"))
+	waitCalls(t, env, "answerCallbackQuery", 2)
+
+	msgs = env.tg.Messages(chatID)
+	require.Len(t, msgs, 1, "message is edited instead of sending a new one")
+	assert.True(t, msgs[0].Edited)
+	assert.Equal(t, "HTML", msgs[0].ParseMode)
+	assert.Contains(t, msgs[0].Text, "500.00 UAH")
+	assert.Regexp(t, `<i>Updated at \d\d:\d\d</i>$`, msgs[0].Text)
+	require.NotNil(t, msgs[0].ReplyMarkup)
+
+	require.NoError(t, env.tg.Press(msgs[0], "Status"))
+	waitCalls(t, env, "answerCallbackQuery", 3)
+	assert.Len(t, env.tg.Calls("editMessageText"), 1, "updated at is ignored when comparing")
+}
+
+func TestBot_StopDrainsHandlers(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	env.ynab.InjectFault(ynabtest.Fault{Latency: 500 * time.Millisecond})
+
+	env.tg.SendText(chatID, "/state")
+	time.Sleep(200 * time.Millisecond) // handler is waiting for YNAB
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, env.bot.Stop(ctx))
+	assert.Len(t, env.tg.Messages(chatID), 1, "in-flight handler finishes before Stop returns")
+
+	env.tg.SendText(chatID, "/state")
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, env.tg.Messages(chatID), 1, "updates are not handled after Stop")
+}
+
+func waitCalls(t *testing.T, env *e2e, method string, n int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		return len(env.tg.Calls(method)) >= n
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBot_HTML(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/state")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "HTML", msgs[0].ParseMode)
+	assert.Contains(t, msgs[0].Text, "<b>Groceries</b>")
+	assert.Contains(t, msgs[0].Text, "<pre>🟢 Balance  580.00 UAH  ")
+}
+
+func TestBot_HTMLFallback(t *testing.T) {
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.StatisticMessageFormatter = func(msg telegram.StatisticMessage) (string, error) {
+			return "<b>" + msg.Category + "</b> <blink>&lt;3</blink>", nil
+		}
+	})
+
+	env.tg.SendText(chatID, "/state")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Groceries <3", msgs[0].Text)
+	assert.Empty(t, msgs[0].ParseMode)
+	assert.NotNil(t, msgs[0].ReplyMarkup, "markup is kept")
+	assert.Len(t, env.tg.Calls("sendMessage"), 2)
+}
+
+func TestBot_Language(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/lang")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Choose language", msgs[0].Text)
+
+	require.NoError(t, env.tg.Press(msgs[0], "Українська"))
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "Мову змінено на українську", msgs[1].Text)
+
+	env.tg.SendText(chatID, "/state")
+	msgs = env.tg.WaitMessages(t, chatID, 3)
+	assert.Contains(t, msgs[2].Text, "Залишок")
+	assert.Contains(t, msgs[2].Text, "580,00 грн.")
+	require.NoError(t, env.tg.Press(msgs[2], "Стан"), "buttons are translated too")
+
+	env.tg.SendText(viewerChatID, "/lang")
+	msgs = env.tg.WaitMessages(t, viewerChatID, 1)
+	assert.Equal(t, "You don't have permission to do this", msgs[0].Text, "language of other chats is not changed")
+}
+
+func TestBot_UnknownChat(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(unknownChatID, "/state")
+	msgs := env.tg.WaitMessages(t, unknownChatID, 1)
+	assert.Equal(t, "You are not allowed to use this bot", msgs[0].Text)
+	assert.Empty(t, env.ynab.Requests())
+}
+
+func TestBot_Roles(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(viewerChatID, "/role 1 admin")
+	msgs := env.tg.WaitMessages(t, viewerChatID, 1)
+	assert.Equal(t, "You don't have permission to do this", msgs[0].Text)
+
+	env.tg.SendText(viewerChatID, "/state")
+	msgs = env.tg.WaitMessages(t, viewerChatID, 2)
+	assert.Contains(t, msgs[1].Text, budget.FormatMoney(580000), "viewer can see state")
+}
+
+func TestBot_Link(t *testing.T) {
+	env := startBot(t, "category-groceries", linkerStub{})
+
+	env.tg.SendText(chatID, "/link")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "This chat already uses YNAB budget set up by the bot admin, /link is not available", msgs[0].Text)
+
+	env.tg.SendText(unknownChatID, "/link")
+	msgs = env.tg.WaitMessages(t, unknownChatID, 1)
+	assert.Equal(t, "Open the link to give the bot read access to your YNAB budget", msgs[0].Text)
+
+	linked := &telegram.Tenant{ID: telegram.LinkedTenantID(chatID), BudgetID: "budget-1"}
+	env = startBot(t, "", linkerStub{}, func(deps *telegram.Dependencies) {
+		deps.Tenants = tenantsStub{chatID: linked}
+	})
+	env.tg.SendText(chatID, "/link")
+	msgs = env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Open the link to give the bot read access to your YNAB budget", msgs[0].Text,
+		"linked chat can be linked again")
+}
+
+func TestBot_CategoryPicker(t *testing.T) {
+	env := startBot(t, "", linkerStub{})
+
+	env.tg.SendText(chatID, "/state")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Choose category to watch", msgs[0].Text)
+	require.NotNil(t, msgs[0].ReplyMarkup)
+
+	var buttons []string
+	for _, row := range msgs[0].ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			buttons = append(buttons, btn.Text)
+		}
+	}
+	assert.Equal(t, []string{"Groceries", "Restaurants", "Transport"}, buttons, "hidden and internal are skipped")
+
+	require.NoError(t, env.tg.Press(msgs[0], "Restaurants"))
+	assert.Eventually(t, func() bool {
+		return len(env.tg.Calls("answerCallbackQuery")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBot_YNABErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		linker telegram.Linker
+		want   string
+	}{
+		{
+			name:   "server_error",
+			status: http.StatusInternalServerError,
+			want:   "Unexpected error occurred. You know whom to call",
+		},
+		{
+			name:   "rate_limited",
+			status: http.StatusTooManyRequests,
+			want:   "Unexpected error occurred. You know whom to call",
+		},
+		{
+			name:   "unauthorized_without_linker",
+			status: http.StatusUnauthorized,
+			want:   "Unexpected error occurred. You know whom to call",
+		},
+		{
+			name:   "unauthorized_with_linker",
+			status: http.StatusUnauthorized,
+			linker: linkerStub{},
+			want:   "YNAB access has expired or was revoked. Use /link to connect your account again",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := startBot(t, "category-groceries", tt.linker)
+			env.ynab.InjectFault(ynabtest.Fault{Status: tt.status})
+
+			env.tg.SendText(chatID, "/state")
+			msgs := env.tg.WaitMessages(t, chatID, 1)
+			assert.Equal(t, tt.want, msgs[0].Text)
+		})
+	}
+}
+
+func TestBot_SendFailure(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	env.tg.Fail("sendMessage", http.StatusForbidden, "Forbidden: bot was blocked by the user")
+
+	env.tg.SendText(chatID, "/state")
+	assert.Eventually(t, func() bool {
+		return len(env.tg.Calls("sendMessage")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, env.tg.Messages(chatID))
+}
+
+func TestBot_Chart(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/chart")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.NotEmpty(t, msgs[0].Photo)
+	assert.Contains(t, msgs[0].Text, "Groceries: spent 420.00 of 1,000.00 UAH")
+	assert.Contains(t, msgs[0].Text, "1. Groceries — 420.00\n2. Restaurants — 120.00\n3. Transport — 50.00")
+	require.Len(t, env.tg.Calls("sendPhoto"), 1)
+
+	env.tg.SendText(unknownChatID, "/chart")
+	msgs = env.tg.WaitMessages(t, unknownChatID, 1)
+	assert.Equal(t, "You are not allowed to use this bot", msgs[0].Text)
+}
+
+func TestBot_Dashboard(t *testing.T) {
+	dashboards := &dashboardsStub{messages: map[int64]int{}}
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Dashboards = dashboards
+	})
+
+	env.tg.SendText(chatID, "/dashboard")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.True(t, msgs[0].Pinned)
+	assert.Contains(t, msgs[0].Text, "580.00 UAH")
+	assert.Regexp(t, `<i>Updated at \d\d:\d\d</i>$`, msgs[0].Text)
+	assert.Equal(t, map[int64]int{chatID: msgs[0].ID}, dashboards.Dashboards())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.bot.RunDashboards(ctx, 10*time.Millisecond, time.Hour)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.NoError(t, env.ynab.SetCategory("budget-1", ynab.Category{
+		ID: "category-groceries", Name: "Groceries", Budgeted: 1000000, Activity: -500000, Balance: 500000,
+	}))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return len(msgs) == 1 && msgs[0].Edited && strings.Contains(msgs[0].Text, "500.00 UAH")
+	}, 5*time.Second, 10*time.Millisecond, "dashboard is edited after YNAB change")
+
+	env.tg.Delete(chatID, msgs[0].ID)
+	require.NoError(t, env.ynab.SetCategory("budget-1", ynab.Category{
+		ID: "category-groceries", Name: "Groceries", Budgeted: 1000000, Activity: -600000, Balance: 400000,
+	}))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return len(msgs) == 1 && msgs[0].Pinned && strings.Contains(msgs[0].Text, "400.00 UAH")
+	}, 5*time.Second, 10*time.Millisecond, "deleted dashboard is pinned again")
+	assert.Equal(t, map[int64]int{chatID: msgs[0].ID}, dashboards.Dashboards())
+
+	env.tg.SendText(chatID, "/dashboard off")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "Dashboard is turned off", msgs[1].Text)
+	assert.False(t, msgs[0].Pinned)
+	assert.Empty(t, dashboards.Dashboards())
+
+	env.tg.SendText(viewerChatID, "/dashboard")
+	msgs = env.tg.WaitMessages(t, viewerChatID, 1)
+	assert.Equal(t, "You don't have permission to do this", msgs[0].Text)
+}
+
+func TestBot_History(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	today := time.Now().Format("2006-01-02")
+	for i := 1; i <= 12; i++ {
+		_, err := env.ynab.AddTransaction("budget-1", ynabtest.Transaction{
+			Date: today, Amount: -i * 1000, Cleared: "cleared", Approved: i != 12, AccountID: "account-card",
+			AccountName: "Card", PayeeName: fmt.Sprintf("Shop %d", i), Memo: fmt.Sprintf("<memo %d>", i),
+			CategoryID: "category-groceries", CategoryName: "Groceries",
+		})
+		require.NoError(t, err)
+	}
+
+	env.tg.SendText(chatID, "/history")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "HTML", msgs[0].ParseMode)
+	assert.Contains(t, msgs[0].Text, "<b>Groceries</b>\nTransactions 1–10 of 12")
+	assert.Contains(t, msgs[0].Text, "Shop 1 <b>-1.00</b> — <i>&lt;memo 1&gt;</i>")
+	assert.NotContains(t, msgs[0].Text, "Shop 11")
+	assert.Error(t, env.tg.Press(msgs[0], "◀"), "there is no previous page")
+
+	require.NoError(t, env.tg.Press(msgs[0], "▶"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.Contains(msgs[0].Text, "Transactions 11–12 of 12")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, msgs, 1, "page is shown in the same message")
+	assert.Contains(t, msgs[0].Text, "12. <code>")
+
+	_, err := env.ynab.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: time.Now().AddDate(0, 0, 1).Format("2006-01-02"), Amount: -1000, AccountID: "account-card",
+		PayeeName: "Newer shop", CategoryID: "category-groceries", CategoryName: "Groceries",
+	})
+	require.NoError(t, err)
+	require.NoError(t, env.tg.Press(msgs[0], "12"), "transaction picked before the history has changed")
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[0].Text, "<b>Shop 12</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, msgs[0].Text, "Amount: -12.00 UAH\nCategory: Groceries\nAccount: Card\nStatus: Cleared")
+	assert.Contains(t, msgs[0].Text, "Memo: &lt;memo 12&gt;")
+	assert.Contains(t, msgs[0].Text, "Transaction is not approved in YNAB yet")
+
+	require.NoError(t, env.tg.Press(msgs[0], "⬅ Back"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.Contains(msgs[0].Text, "Transactions 11–13 of 13")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	env.tg.SendText(chatID, "/history restaurants")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "<b>Restaurants</b>\nNo transactions in recent months", msgs[1].Text)
+
+	env.tg.SendText(chatID, "/history Rent")
+	msgs = env.tg.WaitMessages(t, chatID, 3)
+	assert.Equal(t, `Category "Rent" is not found`, msgs[2].Text)
+}
+
+func TestBot_Move(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+
+	env.tg.SendText(chatID, "/move 100 from restaurants to Groceries")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, `Move 100.00 UAH from "Restaurants" to "Groceries"?`, msgs[0].Text)
+
+	require.NoError(t, env.tg.Press(msgs[0], "Move"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[0].Text, `Moved 100.00 UAH from "Restaurants" to "Groceries"`)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, msgs[0].Text, "80.00 UAH")
+	assert.Contains(t, msgs[0].Text, "680.00 UAH")
+
+	ctx := context.Background()
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	cat, err := client.GetCurrentMonthCategory(ctx, "budget-1", "category-restaurants")
+	require.NoError(t, err)
+	assert.Equal(t, 200000, cat.Budgeted)
+	cat, err = client.GetCurrentMonthCategory(ctx, "budget-1", "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, 1100000, cat.Budgeted)
+
+	env.tg.SendText(chatID, "/move 12,5")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "Where to move 12.50 UAH from?", msgs[1].Text)
+	require.NoError(t, env.tg.Press(msgs[1], "Transport"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return msgs[1].Text == `Where to move 12.50 UAH from "Transport" to?`
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, env.tg.Press(msgs[1], "Groceries"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return msgs[1].Text == `Move 12.50 UAH from "Transport" to "Groceries"?`
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, env.tg.Press(msgs[1], "Cancel"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return msgs[1].Text == "Move is cancelled"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	env.tg.SendText(chatID, "/move 10 from Groceries to groceries")
+	msgs = env.tg.WaitMessages(t, chatID, 3)
+	assert.Equal(t, "Categories must be different", msgs[2].Text)
+
+	env.tg.SendText(chatID, "/move lots")
+	msgs = env.tg.WaitMessages(t, chatID, 4)
+	assert.Equal(t, "Specify amount, for example: /move 500 from Fun to Groceries", msgs[3].Text)
+	env.tg.SendText(chatID, "/move nan from Groceries to Transport")
+	msgs = env.tg.WaitMessages(t, chatID, 5)
+	assert.Equal(t, "Specify amount, for example: /move 500 from Fun to Groceries", msgs[4].Text)
+
+	env.tg.SendText(viewerChatID, "/move 10 from Groceries to Transport")
+	msgs = env.tg.WaitMessages(t, viewerChatID, 1)
+	assert.Equal(t, "You don't have permission to do this", msgs[0].Text)
+}
+
+func TestBot_MoveOnce(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	env.ynab.InjectFault(ynabtest.Fault{Method: http.MethodPatch, Latency: 200 * time.Millisecond})
+
+	env.tg.SendText(chatID, "/move 100 from restaurants to Groceries")
+	env.tg.WaitMessages(t, chatID, 1)
+	env.tg.SendText(chatID, "/move 50 from Transport to Groceries")
+	msgs := env.tg.WaitMessages(t, chatID, 2)
+
+	// Buttons of the older request don't confirm the newer one.
+	require.NoError(t, env.tg.Press(msgs[0], "Move"))
+	waitCalls(t, env, "answerCallbackQuery", 1)
+	assert.Equal(t, "Request is expired, send /move again", env.tg.Calls("answerCallbackQuery")[0].Params["text"])
+
+	require.NoError(t, env.tg.Press(msgs[1], "Move"))
+	require.NoError(t, env.tg.Press(msgs[1], "Move"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[1].Text, `Moved 50.00 UAH from "Transport" to "Groceries"`)
+	}, 5*time.Second, 10*time.Millisecond)
+	waitCalls(t, env, "answerCallbackQuery", 3)
+
+	var patches []string
+	for _, r := range env.ynab.Requests() {
+		if strings.HasPrefix(r, http.MethodPatch) {
+			patches = append(patches, r)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"PATCH /v1/budgets/budget-1/months/current/categories/category-transport",
+		"PATCH /v1/budgets/budget-1/months/current/categories/category-groceries",
+	}, patches)
+}
+
+func TestBot_Expense(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	now := time.Now()
+	_, err := env.ynab.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: now.Format("2006-01-02"), Amount: -90000, Approved: true, AccountID: "account-cash",
+		PayeeName: "Coffee House", CategoryID: "category-restaurants",
+	})
+	require.NoError(t, err)
+
+	env.tg.SendText(chatID, "coffee house 65")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "<b>New transaction</b>\n\nAmount: -65.00 UAH\nPayee: Coffee House\n"+
+		"Category: Restaurants (suggested, 50%)\nAccount: Cash\nDate: "+now.Format("02.01.2006"), msgs[0].Text)
+
+	// Buttons are pressed as soon as they are shown, edits of the message replace them.
+	for _, button := range []string{"Date", "Yesterday", "Account", "Card", "✅ Create"} {
+		assert.Eventually(t, func() bool {
+			msgs = env.tg.Messages(chatID)
+			return env.tg.Press(msgs[0], button) == nil
+		}, 5*time.Second, 10*time.Millisecond, "button %q is shown", button)
+	}
+	yesterday := now.AddDate(0, 0, -1)
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[0].Text, "<b>Transaction is created</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, msgs[0].Text, "Account: Card\nDate: "+yesterday.Format("02.01.2006"))
+	assert.Contains(t, msgs[0].Text, "Restaurants")
+
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	txs, err := client.GetTransactions(context.Background(), "budget-1", yesterday)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, ynab.Transaction{
+		ID: txs[0].ID, Date: yesterday.Format("2006-01-02"), Amount: -65000, Cleared: "uncleared", Approved: true,
+		AccountID: "account-card", AccountName: "Card", PayeeName: "Coffee House",
+		CategoryID: "category-restaurants", CategoryName: "Restaurants",
+		ImportID: fmt.Sprintf("bot:%d:%d", chatID, msgs[0].ID),
+	}, txs[0])
+
+	env.tg.SendText(chatID, "145,50 АТБ groceries вчора")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Contains(t, msgs[1].Text, "Amount: -145.50 UAH\nPayee: АТБ\nCategory: Groceries")
+	require.NoError(t, env.tg.Press(msgs[1], "Cancel"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return msgs[1].Text == "Transaction is cancelled"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	env.tg.SendText(chatID, "hello there")
+	env.tg.SendText(viewerChatID, "coffee 10")
+	env.tg.SendText(chatID, "/move lots")
+	msgs = env.tg.WaitMessages(t, chatID, 3)
+	assert.Equal(t, "Specify amount, for example: /move 500 from Fun to Groceries", msgs[2].Text,
+		"text without amount is ignored")
+	assert.Empty(t, env.tg.Messages(viewerChatID), "viewers can't add transactions")
+}
+
+func TestBot_ExpenseOnce(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	env.ynab.InjectFault(ynabtest.Fault{Method: http.MethodPost, Status: http.StatusInternalServerError, Times: 1})
+	env.ynab.InjectFault(ynabtest.Fault{Method: http.MethodPost, Latency: 200 * time.Millisecond})
+	answers := func() []string {
+		var texts []string
+		for _, call := range env.tg.Calls("answerCallbackQuery") {
+			texts = append(texts, call.Params["text"])
+		}
+		return texts
+	}
+
+	env.tg.SendText(chatID, "coffee 65")
+	env.tg.WaitMessages(t, chatID, 1)
+	// Buttons are pressed as soon as they are shown, edits of the message replace them.
+	var msgs []telegramtest.Message
+	for _, button := range []string{"Account", "Cash"} {
+		assert.Eventually(t, func() bool {
+			msgs = env.tg.Messages(chatID)
+			return env.tg.Press(msgs[0], button) == nil
+		}, 5*time.Second, 10*time.Millisecond, "button %q is shown", button)
+	}
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.Contains(msgs[0].Text, "Account: Cash")
+	}, 5*time.Second, 10*time.Millisecond)
+	waitCalls(t, env, "answerCallbackQuery", 2)
+
+	// Draft is kept when YNAB fails, so it can be confirmed again.
+	require.NoError(t, env.tg.Press(msgs[0], "✅ Create"))
+	waitCalls(t, env, "answerCallbackQuery", 3)
+	assert.Equal(t, "Unexpected error occurred. You know whom to call", answers()[2])
+
+	require.NoError(t, env.tg.Press(msgs[0], "✅ Create"))
+	require.NoError(t, env.tg.Press(msgs[0], "✅ Create"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[0].Text, "<b>Transaction is created</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+	waitCalls(t, env, "answerCallbackQuery", 5)
+	assert.Equal(t, "Draft is expired, send the expense again", answers()[3], "the second tap")
+
+	n := 0
+	for _, r := range env.ynab.Requests() {
+		if r == "POST /v1/budgets/budget-1/transactions" {
+			n++
+		}
+	}
+	assert.Equal(t, 2, n, "the failed request and the first confirmation")
+}
+
+func TestBot_ExpenseResolverCache(t *testing.T) {
+	env := startBot(t, "category-groceries", nil)
+	transactionRequests := func() int {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n
+	}
+
+	env.tg.SendText(chatID, "coffee 10")
+	env.tg.WaitMessages(t, chatID, 1)
+	env.tg.SendText(chatID, "tea 20")
+	msgs := env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, 1, transactionRequests(), "transactions are loaded once")
+
+	require.NoError(t, env.tg.Press(msgs[1], "✅ Create"))
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(env.tg.Messages(chatID)[1].Text, "<b>Transaction is created</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+	env.tg.SendText(chatID, "juice 5")
+	msgs = env.tg.WaitMessages(t, chatID, 3)
+	assert.Contains(t, msgs[2].Text, "Payee: juice")
+	assert.Equal(t, 2, transactionRequests(), "created transaction drops cached transactions")
+}
+
+func TestBot_Receipt(t *testing.T) {
+	store := receipts.New(t.TempDir(), receipts.Config{}, zap.NewNop().Sugar())
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Receipts = store
+	})
+	pressAll := func(i int, buttons ...string) []telegramtest.Message {
+		var msgs []telegramtest.Message
+		for _, button := range buttons {
+			assert.Eventually(t, func() bool {
+				msgs = env.tg.Messages(chatID)
+				return len(msgs) > i && env.tg.Press(msgs[i], button) == nil
+			}, 5*time.Second, 10*time.Millisecond, "button %q is shown", button)
+		}
+		return msgs
+	}
+
+	env.tg.SendPhoto(chatID, "photo-1", "")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Send the amount from the receipt, e.g.: Lidl 45.50", msgs[0].Text)
+
+	env.tg.SendText(chatID, "groceries 145.50")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Contains(t, msgs[1].Text, "Amount: -145.50 UAH\nPayee: groceries\nCategory: Groceries")
+	assert.True(t, strings.HasSuffix(msgs[1].Text, "\n\n🧾 Receipt will be saved"), msgs[1].Text)
+	pressAll(1, "Account", "Card", "✅ Create")
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[1].Text, "<b>Transaction is created</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, msgs[1].Text, "Failed to save the receipt")
+
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	txs, err := client.GetTransactions(context.Background(), "budget-1", time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	path, ok := store.Path("budget-1", txs[0].ID)
+	require.True(t, ok, "receipt is stored by budget and transaction ID")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, telegramtest.PhotoContent("photo-1"), string(data))
+
+	env.tg.SendText(chatID, "/history")
+	env.tg.WaitMessages(t, chatID, 3)
+	msgs = pressAll(2, "1", "🧾 Receipt")
+	msgs = env.tg.WaitMessages(t, chatID, 4)
+	// Telebot uploads photos without file names, so the server records their content.
+	assert.Equal(t, telegramtest.PhotoContent("photo-1"), msgs[3].Photo, "receipt is sent from history")
+
+	// Receipt is sent only while its transaction is in the budget.
+	require.NoError(t, client.DeleteTransaction(context.Background(), "budget-1", txs[0].ID))
+	require.NoError(t, env.tg.Press(msgs[2], "🧾 Receipt"))
+	assert.Eventually(t, func() bool {
+		calls := env.tg.Calls("answerCallbackQuery")
+		return calls[len(calls)-1].Params["text"] == "Transaction is not found, refresh the list"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, env.tg.Messages(chatID), 4)
+
+	env.tg.SendPhoto(chatID, "photo-2", "coffee 20")
+	msgs = env.tg.WaitMessages(t, chatID, 5)
+	assert.Contains(t, msgs[4].Text, "Amount: -20.00 UAH\nPayee: coffee")
+	assert.Contains(t, msgs[4].Text, "🧾 Receipt will be saved", "caption is parsed")
+
+	env.tg.SendText(chatID, "tea 5")
+	msgs = env.tg.WaitMessages(t, chatID, 6)
+	assert.NotContains(t, msgs[5].Text, "Receipt", "photo is attached only once")
+
+	env.tg.SendPhoto(viewerChatID, "photo-3", "")
+	env.tg.SendText(chatID, "/move lots")
+	env.tg.WaitMessages(t, chatID, 7)
+	assert.Empty(t, env.tg.Messages(viewerChatID), "viewers can't add receipts")
+}
+
+func TestBot_ReceiptOfSender(t *testing.T) {
+	const groupChatID, author, other, viewer = -100, 1001, 1002, 1003
+	store := receipts.New(t.TempDir(), receipts.Config{}, zap.NewNop().Sugar())
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Receipts = store
+		tenants, _ := deps.Tenants.(tenantsStub)
+		tenants[groupChatID] = tenants[chatID]
+		deps.Roles = rolesStub{viewer: telegram.RoleViewer}
+	})
+	answers := func() []string {
+		var texts []string
+		for _, call := range env.tg.Calls("answerCallbackQuery") {
+			texts = append(texts, call.Params["text"])
+		}
+		return texts
+	}
+
+	env.tg.SendPhotoFrom(groupChatID, author, "photo-1", "")
+	env.tg.WaitMessages(t, groupChatID, 1)
+	env.tg.SendTextFrom(groupChatID, other, "tea 5")
+	msgs := env.tg.WaitMessages(t, groupChatID, 2)
+	assert.NotContains(t, msgs[1].Text, "Receipt", "photo isn't attached to expense of another member")
+
+	env.tg.SendTextFrom(groupChatID, author, "milk 30")
+	msgs = env.tg.WaitMessages(t, groupChatID, 3)
+	assert.Contains(t, msgs[2].Text, "🧾 Receipt will be saved")
+
+	// Roles are checked by the user who taps the button, not by the chat.
+	require.NoError(t, env.tg.PressFrom(msgs[2], viewer, "Account"))
+	waitCalls(t, env, "answerCallbackQuery", 1)
+	assert.Equal(t, []string{"You don't have permission to do this"}, answers())
+	for _, button := range []string{"Account", "Card", "✅ Create"} {
+		assert.Eventually(t, func() bool {
+			msgs = env.tg.Messages(groupChatID)
+			return env.tg.PressFrom(msgs[2], author, button) == nil
+		}, 5*time.Second, 10*time.Millisecond, "button %q is shown", button)
+	}
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(groupChatID)
+		return strings.HasPrefix(msgs[2].Text, "<b>Transaction is created</b>")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	txs, err := client.GetTransactions(context.Background(), "budget-1", time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "milk", txs[0].PayeeName)
+	_, ok := store.Path("budget-1", txs[0].ID)
+	assert.True(t, ok, "receipt of the author is saved")
+}
+
+func TestBot_Alerts(t *testing.T) {
+	alerts := &alertsStub{alerts: map[int64]anomaly.Sensitivity{}}
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Alerts = alerts
+	})
+
+	env.tg.SendText(chatID, "/alerts")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "Send /alerts low, medium or high to be notified about unusual spending, /alerts off to stop",
+		msgs[0].Text)
+	env.tg.SendText(chatID, "/alerts high")
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "Alerts about unusual spending are on, sensitivity: high", msgs[1].Text)
+	assert.Equal(t, map[int64]anomaly.Sensitivity{chatID: anomaly.High}, alerts.Alerts())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.bot.RunAlerts(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// Transactions existing on the first check are not reported, so new ones are added after it is done.
+	assert.Eventually(t, func() bool {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	today := time.Now().Format("2006-01-02")
+	for i := 0; i < 2; i++ {
+		_, err := env.ynab.AddTransaction("budget-1", ynabtest.Transaction{
+			Date: today, Amount: -300000, Approved: true, AccountID: "account-card",
+			PayeeName: "Rozetka", CategoryID: "category-groceries",
+		})
+		require.NoError(t, err)
+	}
+
+	date := time.Now().Format("02.01")
+	msgs = env.tg.WaitMessages(t, chatID, 4)
+	assert.Equal(t, `🆕 New payee "Rozetka": 300.00 UAH on `+date+", Card", msgs[2].Text)
+	assert.Equal(t, `👯 Looks like a duplicate: 300.00 UAH to "Rozetka" on `+date+" (Card) and on "+date+" (Card)",
+		msgs[3].Text)
+
+	env.tg.SendText(chatID, "/alerts off")
+	msgs = env.tg.WaitMessages(t, chatID, 5)
+	assert.Equal(t, "Alerts about unusual spending are off", msgs[4].Text)
+	assert.Empty(t, alerts.Alerts())
+
+	env.tg.SendText(viewerChatID, "/alerts high")
+	msgs = env.tg.WaitMessages(t, viewerChatID, 1)
+	assert.Equal(t, "You don't have permission to do this", msgs[0].Text)
+}
+
+func TestBot_AlertsAfterRestart(t *testing.T) {
+	seen := &seenStub{seen: map[string]map[string]string{}}
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Alerts = &alertsStub{alerts: map[int64]anomaly.Sensitivity{chatID: anomaly.High}}
+		deps.Seen = seen
+	})
+	requests := func() int {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n
+	}
+	// runAlerts runs alerts until the transactions are checked twice, so state of RunAlerts is lost like on restart.
+	runAlerts := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			env.bot.RunAlerts(ctx, 10*time.Millisecond)
+			close(done)
+		}()
+		n := requests()
+		assert.Eventually(t, func() bool { return requests() >= n+2 }, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		<-done
+	}
+
+	runAlerts()
+	assert.Empty(t, env.tg.Messages(chatID), "transactions existing on the first check are not reported")
+	_, err := env.ynab.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: time.Now().Format("2006-01-02"), Amount: -300000, Approved: true, AccountID: "account-card",
+		PayeeName: "Rozetka", CategoryID: "category-groceries",
+	})
+	require.NoError(t, err)
+
+	runAlerts()
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, `🆕 New payee "Rozetka": 300.00 UAH on `+time.Now().Format("02.01")+", Card", msgs[0].Text)
+	runAlerts()
+	assert.Len(t, env.tg.Messages(chatID), 1, "transaction is reported once")
+}
+
+func TestBot_Duplicates(t *testing.T) {
+	alerts := &alertsStub{alerts: map[int64]anomaly.Sensitivity{chatID: anomaly.Medium}}
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Alerts = alerts
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.bot.RunAlerts(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	assert.Eventually(t, func() bool {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	now := time.Now()
+	add := func(tx ynabtest.Transaction) ynabtest.Transaction {
+		tx.Date, tx.AccountID = now.Format("2006-01-02"), "account-card"
+		added, err := env.ynab.AddTransaction("budget-1", tx)
+		require.NoError(t, err)
+		return added
+	}
+	add(ynabtest.Transaction{
+		Amount: -65000, PayeeName: "Coffee House", CategoryID: "category-restaurants", Memo: "latte", Approved: true,
+	})
+	imported := add(ynabtest.Transaction{
+		Amount: -65000, PayeeName: "COFFEE HOUSE KYIV", Cleared: "cleared", ImportID: "YNAB:-65000:1",
+	})
+
+	date := now.Format("02.01")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "👯 Looks like a duplicate in Card\n\n"+
+		"✍️ Entered manually: 65.00 UAH, Coffee House, "+date+", Restaurants\n"+
+		"🏦 Imported from bank: 65.00 UAH, COFFEE HOUSE KYIV, "+date+", Uncategorized\n\n"+
+		"Delete the one entered manually?", msgs[0].Text)
+
+	require.NoError(t, env.tg.Press(msgs[0], "🗑 Delete manual"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasSuffix(msgs[0].Text, "\n\n🗑 Transaction entered manually is deleted, "+
+			"its category and memo are moved to the imported one")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, msgs[0].ReplyMarkup, "buttons are removed")
+
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	txs, err := client.GetTransactions(context.Background(), "budget-1", now.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, txs, 1, "manual transaction is deleted")
+	assert.Equal(t, ynab.Transaction{
+		ID: imported.ID, Date: imported.Date, Amount: -65000, Memo: "latte", Cleared: "cleared",
+		AccountID: "account-card", AccountName: "Card", PayeeName: "COFFEE HOUSE KYIV",
+		CategoryID: "category-restaurants", CategoryName: "Restaurants", ImportID: "YNAB:-65000:1",
+	}, txs[0])
+
+	add(ynabtest.Transaction{Amount: -20000, PayeeName: "Kiosk"})
+	add(ynabtest.Transaction{Amount: -20000, PayeeName: "LIQPAY*KIOSK", ImportID: "YNAB:-20000:1"})
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	require.NoError(t, env.tg.Press(msgs[1], "Keep both"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasSuffix(msgs[1].Text, "\n\nBoth transactions are kept")
+	}, 5*time.Second, 10*time.Millisecond)
+	txs, err = client.GetTransactions(context.Background(), "budget-1", now.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Len(t, txs, 3)
+}
+
+func TestBot_DuplicateOfDraft(t *testing.T) {
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Alerts = &alertsStub{alerts: map[int64]anomaly.Sensitivity{}}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.bot.RunAlerts(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	assert.Eventually(t, func() bool {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	now := time.Now()
+	add := func(tx ynabtest.Transaction) ynabtest.Transaction {
+		tx.AccountID = "account-card"
+		if tx.Date == "" {
+			tx.Date = now.Format("2006-01-02")
+		}
+		added, err := env.ynab.AddTransaction("budget-1", tx)
+		require.NoError(t, err)
+		return added
+	}
+	manual := add(ynabtest.Transaction{
+		Amount: -65000, PayeeName: "Coffee House", CategoryID: "category-restaurants", Approved: true,
+		ImportID: expense.ImportID(chatID, 7),
+	})
+	imported := add(ynabtest.Transaction{Amount: -65000, PayeeName: "COFFEE HOUSE KYIV", ImportID: "YNAB:-65000:1"})
+
+	// The chat which created the manual transaction is asked without alerts.
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.True(t, strings.HasPrefix(msgs[0].Text, "👯 Looks like a duplicate in Card"), msgs[0].Text)
+	assert.Empty(t, env.tg.Messages(viewerChatID))
+
+	// Another imported transaction is paired with the manual one when the shown one is deleted.
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	require.NoError(t, client.DeleteTransaction(context.Background(), "budget-1", imported.ID))
+	other := add(ynabtest.Transaction{
+		Date: now.AddDate(0, 0, -1).Format("2006-01-02"), Amount: -65000, PayeeName: "COFFEE HOUSE",
+		ImportID: "YNAB:-65000:2",
+	})
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	require.NoError(t, env.tg.Press(msgs[0], "🗑 Delete manual"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasSuffix(msgs[0].Text, "\n\nTransactions are not found, they may have been changed already")
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err := client.GetTransaction(context.Background(), "budget-1", manual.ID)
+	require.NoError(t, err, "manual transaction is kept")
+
+	require.NoError(t, env.tg.Press(msgs[1], "🗑 Delete manual"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasPrefix(msgs[1].Text, "👯") && strings.Contains(msgs[1].Text, "\n\n🗑 Transaction entered")
+	}, 5*time.Second, 10*time.Millisecond)
+	txs, err := client.GetTransactions(context.Background(), "budget-1", now.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, txs, 1, "manual transaction is deleted")
+	assert.Equal(t, other.ID, txs[0].ID)
+	assert.Equal(t, "category-restaurants", txs[0].CategoryID)
+}
+
+func TestBot_Reminders(t *testing.T) {
+	alerts := &alertsStub{alerts: map[int64]anomaly.Sensitivity{chatID: anomaly.Low}}
+	env := startBot(t, "category-groceries", nil, func(deps *telegram.Dependencies) {
+		deps.Alerts = alerts
+	})
+	now := time.Now()
+	add := func(tx ynabtest.Transaction) ynabtest.Transaction {
+		tx.Date, tx.AccountID = now.Format("2006-01-02"), "account-card"
+		added, err := env.ynab.AddTransaction("budget-1", tx)
+		require.NoError(t, err)
+		return added
+	}
+	add(ynabtest.Transaction{Amount: -90000, PayeeName: "Silpo", CategoryID: "category-groceries", Approved: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.bot.RunAlerts(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	assert.Eventually(t, func() bool {
+		n := 0
+		for _, r := range env.ynab.Requests() {
+			if r == "GET /v1/budgets/budget-1/transactions" {
+				n++
+			}
+		}
+		return n >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	imported := add(ynabtest.Transaction{Amount: -145000, PayeeName: "SILPO", ImportID: "YNAB:-145000:1"})
+	date := now.Format("02.01")
+	msgs := env.tg.WaitMessages(t, chatID, 1)
+	assert.Equal(t, "🏷 Transaction without category: 145.00 UAH, SILPO, "+date+", Card\n\nPick a category:",
+		msgs[0].Text)
+	require.NoError(t, env.tg.Press(msgs[0], "Groceries 50%"))
+	assert.Eventually(t, func() bool {
+		msgs = env.tg.Messages(chatID)
+		return strings.HasSuffix(msgs[0].Text, "\n\n✅ Category: Groceries")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, msgs[0].ReplyMarkup, "buttons are removed")
+
+	client := ynab.NewClient(ynabtest.Start(t, env.ynab), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	tx, err := client.GetTransaction(context.Background(), "budget-1", imported.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "category-groceries", tx.CategoryID)
+
+	add(ynabtest.Transaction{Amount: -20000, PayeeName: "Unknown Place", ImportID: "YNAB:-20000:1"})
+	msgs = env.tg.WaitMessages(t, chatID, 2)
+	assert.Equal(t, "🏷 Transaction without category: 20.00 UAH, Unknown Place, "+date+", Card\n\n"+
+		"Nothing to suggest a category from, pick it in YNAB", msgs[1].Text)
+}
@@ -0,0 +1,545 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/suggest"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	// expenseHistoryMonths is how many months of transactions category suggestions and accounts of payees
+	// are learned from.
+	expenseHistoryMonths = 3
+	// expenseDays is how many days can be picked with date buttons including today.
+	expenseDays = 7
+	// expenseResolverTTL is how long resolver of the budget is reused. Writes of the bot drop it earlier,
+	// changes made in YNAB are learned after it expires.
+	expenseResolverTTL = 15 * time.Minute
+)
+
+// expenseDraft is transaction waiting for confirmation in the message with MessageID.
+type expenseDraft struct {
+	expense.Draft
+	MessageID int
+	// PhotoID is file ID of receipt photo of the transaction, empty if there is none.
+	PhotoID string
+}
+
+type expenseButtons struct {
+	create      *tb.Btn
+	cancel      *tb.Btn
+	back        *tb.Btn
+	category    *tb.Btn
+	account     *tb.Btn
+	date        *tb.Btn
+	setCategory *tb.Btn
+	setAccount  *tb.Btn
+	setDate     *tb.Btn
+}
+
+func newExpenseButtons() expenseButtons {
+	return expenseButtons{
+		create:      &tb.Btn{Unique: "expense_create"},
+		cancel:      &tb.Btn{Unique: "expense_cancel"},
+		back:        &tb.Btn{Unique: "expense_back"},
+		category:    &tb.Btn{Unique: "expense_category"},
+		account:     &tb.Btn{Unique: "expense_account"},
+		date:        &tb.Btn{Unique: "expense_date"},
+		setCategory: &tb.Btn{Unique: "expense_set_category"},
+		setAccount:  &tb.Btn{Unique: "expense_set_account"},
+		setDate:     &tb.Btn{Unique: "expense_set_date"},
+	}
+}
+
+// expenseHandler turns free text like "кава 65" into draft transaction shown for confirmation.
+// Texts without amount and texts from chats which can't add transactions are ignored, so the bot keeps silent
+// in conversations. Receipt photo sent before the text is attached to the draft.
+func (b *Bot) expenseHandler(c tb.Context) error {
+	text := strings.TrimSpace(c.Text())
+	if text == "" || strings.HasPrefix(text, "/") {
+		return nil
+	}
+	t, ok := b.memberTenant(c)
+	if !ok {
+		return nil
+	}
+	e, ok := expense.Parse(text, time.Now())
+	if !ok {
+		return nil
+	}
+
+	// In groups photo of one member isn't attached to expenses of the others.
+	photo, ok := b.photos.take(c.Chat().ID, func(ph pendingPhoto) bool {
+		return ph.UserID == senderID(c)
+	})
+	if !ok || time.Since(photo.SentAt) >= receiptPhotoTTL {
+		photo = pendingPhoto{}
+	}
+
+	return b.sendDraft(c, t, e, photo.FileID)
+}
+
+// expensePickHandler replaces draft buttons with buttons picking category, account or date.
+func (b *Bot) expensePickHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("expense pick handler", "chatID", c.Chat().ID, "tenantID", t.ID, "button", c.Callback().Unique)
+
+	if _, ok := b.draftOf(c); !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("expense.expired")})
+	}
+
+	var markup *tb.ReplyMarkup
+	var err error
+	switch c.Callback().Unique {
+	case b.expenseBtns.category.Unique:
+		markup, err = b.categoriesMarkup(t, b.expenseBtns.setCategory)
+	case b.expenseBtns.account.Unique:
+		markup, err = b.accountsMarkup(t)
+	default:
+		markup = b.datesMarkup(p, time.Now())
+	}
+	if err != nil {
+		b.log.Errorw("failed to prepare buttons", "chatID", c.Chat().ID, "tenantID", t.ID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard,
+		[]tb.InlineButton{*markup.Data(p.T("button.back"), b.expenseBtns.back.Unique).Inline()})
+
+	if err = c.Edit(markup); err != nil && !isNotModified(err) {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	return c.Respond()
+}
+
+// expenseSetHandler changes category, account or date of the draft to the picked one.
+func (b *Bot) expenseSetHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("expense set handler", "chatID", c.Chat().ID, "tenantID", t.ID,
+		"button", c.Callback().Unique, "data", c.Data())
+
+	d, ok := b.draftOf(c)
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("expense.expired")})
+	}
+
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	switch c.Callback().Unique {
+	case b.expenseBtns.setCategory.Unique:
+		cat, err := t.Client.GetCategory(ctx, t.BudgetID, c.Data())
+		if err != nil || cat == nil {
+			b.log.Errorw("failed to get category", "tenantID", t.ID, "categoryID", c.Data(), "error", err)
+			return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+		}
+		d.CategoryID, d.CategoryName, d.Confidence = cat.ID, cat.Name, 0
+	case b.expenseBtns.setAccount.Unique:
+		accounts, err := t.Client.GetAccounts(ctx, t.BudgetID)
+		if err != nil {
+			b.log.Errorw("failed to get accounts", "tenantID", t.ID, "error", err)
+			return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+		}
+		for _, a := range accounts {
+			if a.ID == c.Data() {
+				d.AccountID, d.AccountName = a.ID, a.Name
+			}
+		}
+	default:
+		days, err := strconv.Atoi(c.Data())
+		if err != nil || days < 0 || days >= expenseDays {
+			return c.Respond(&tb.CallbackResponse{Text: p.T("access.invalid_request")})
+		}
+		d.Date = today().AddDate(0, 0, -days)
+	}
+	b.drafts.set(c.Chat().ID, d)
+
+	return b.showDraft(c, p, d)
+}
+
+// expenseBackHandler shows the draft buttons again.
+func (b *Bot) expenseBackHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	d, ok := b.draftOf(c)
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("expense.expired")})
+	}
+
+	return b.showDraft(c, p, d)
+}
+
+// expenseCreateHandler creates transaction of the draft and shows statistic of its category.
+func (b *Bot) expenseCreateHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("expense create handler", "chatID", c.Chat().ID, "tenantID", t.ID)
+
+	// Draft is taken before the transaction is created, so the second tap of the button finds no draft.
+	d, ok := b.drafts.take(c.Chat().ID, func(d expenseDraft) bool {
+		return isMessageOf(c, d.MessageID)
+	})
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("expense.expired")})
+	}
+	if d.AccountID == "" {
+		b.drafts.restore(c.Chat().ID, d)
+		return c.Respond(&tb.CallbackResponse{Text: p.T("expense.no_account")})
+	}
+
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	tx, err := t.Client.CreateTransaction(ctx, t.BudgetID, ynab.NewTransaction{
+		AccountID:  d.AccountID,
+		Date:       d.Date.Format(ynabDateLayout),
+		Amount:     -d.Amount,
+		PayeeName:  d.Payee,
+		CategoryID: d.CategoryID,
+		Cleared:    "uncleared",
+		Approved:   true,
+		ImportID:   expense.ImportID(c.Chat().ID, d.MessageID),
+	})
+	if errors.Is(err, ynab.ErrConflict) {
+		// Earlier attempt created the transaction even though its response was lost.
+		b.log.Warnw("transaction exists already", "chatID", c.Chat().ID, "tenantID", t.ID)
+		b.forgetResolver(t)
+		if err = c.Edit(p.T("expense.exists")); err != nil {
+			b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+			return err
+		}
+		return c.Respond()
+	}
+	if err != nil {
+		b.log.Errorw("failed to create transaction", "chatID", c.Chat().ID, "tenantID", t.ID, "error", err)
+		b.drafts.restore(c.Chat().ID, d)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	b.forgetResolver(t)
+	b.log.Infow("transaction created", "chatID", c.Chat().ID, "tenantID", t.ID, "transactionID", tx.ID)
+
+	d.Confidence = 0
+	text := "<b>" + EscapeHTML(p.T("expense.created")) + "</b>\n\n" + formatDraftDetails(p, d.Draft)
+	if d.PhotoID != "" {
+		if err = b.saveReceipt(c.Bot(), t.BudgetID, tx.ID, d.PhotoID); err != nil {
+			b.log.Errorw("failed to save receipt", "chatID", c.Chat().ID, "transactionID", tx.ID, "error", err)
+			text += "\n\n" + EscapeHTML(p.T("receipt.failed"))
+		}
+	}
+	if d.CategoryID != "" {
+		if cat, err := t.Client.GetCategory(ctx, t.BudgetID, d.CategoryID); err != nil || cat == nil {
+			b.log.Warnw("failed to get category", "tenantID", t.ID, "categoryID", d.CategoryID, "error", err)
+		} else if msg, err := b.msgFormatter(StatisticMessage{
+			ChatID:    c.Chat().ID,
+			Command:   "expense",
+			Category:  cat.Name,
+			Printer:   p,
+			Statistic: budget.CalculateStatistic(*cat),
+		}); err != nil {
+			b.log.Errorw("failed to format message", "chatID", c.Chat().ID, "error", err)
+		} else {
+			text += "\n\n" + strings.TrimRight(msg, "\n")
+		}
+	}
+
+	if err = b.editHTML(c, text, b.stateMarkup(p)); err != nil {
+		return err
+	}
+	return c.Respond()
+}
+
+func (b *Bot) expenseCancelHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("expense cancel handler", "chatID", c.Chat().ID)
+
+	if _, ok := b.draftOf(c); ok {
+		b.drafts.remove(c.Chat().ID)
+	}
+	if err := c.Edit(p.T("expense.cancelled")); err != nil {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	return c.Respond()
+}
+
+// memberTenant returns tenant of the chat if the sender can add transactions to its budget.
+func (b *Bot) memberTenant(c tb.Context) (*Tenant, bool) {
+	t, ok := b.tenants.ByChat(c.Chat().ID)
+	if !ok {
+		return nil, false
+	}
+	c.Set(tenantContextKey, t)
+	if roleOf(b.roles, c) < RoleMember {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// sendDraft resolves the expense and sends it as draft for confirmation. photoID is file ID of receipt photo
+// saved when the transaction is created. It is empty if there is no receipt.
+func (b *Bot) sendDraft(c tb.Context, t *Tenant, e expense.Expense, photoID string) error {
+	p := b.printerFrom(c)
+	b.log.Infow("expense draft", "chatID", c.Chat().ID, "tenantID", t.ID, "amount", e.Amount, "receipt", photoID != "")
+
+	r, err := b.expenseResolver(t)
+	if err != nil {
+		b.log.Errorw("failed to prepare expense", "chatID", c.Chat().ID, "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+	d := expenseDraft{Draft: r.Resolve(e), PhotoID: photoID}
+
+	// The older draft is dropped before the new one is shown, so its buttons don't create it meanwhile.
+	b.drafts.remove(c.Chat().ID)
+	msg, err := c.Bot().Send(c.Recipient(), formatDraft(p, d), tb.ModeHTML, b.draftMarkup(p))
+	if err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	d.MessageID = msg.ID
+	b.drafts.set(c.Chat().ID, d)
+
+	return nil
+}
+
+// draftOf returns draft of the chat if the callback belongs to its message. Buttons of older drafts are expired.
+func (b *Bot) draftOf(c tb.Context) (expenseDraft, bool) {
+	d, ok := b.drafts.get(c.Chat().ID)
+	if !ok || !isMessageOf(c, d.MessageID) {
+		return expenseDraft{}, false
+	}
+
+	return d, true
+}
+
+// isMessageOf reports whether the callback comes from the message with messageID.
+func isMessageOf(c tb.Context, messageID int) bool {
+	return c.Message() != nil && c.Message().ID == messageID
+}
+
+func (b *Bot) showDraft(c tb.Context, p *i18n.Printer, d expenseDraft) error {
+	if err := b.editHTML(c, formatDraft(p, d), b.draftMarkup(p)); err != nil {
+		return err
+	}
+	return c.Respond()
+}
+
+// resolverCache keeps resolvers of budgets, so months of transactions aren't downloaded for every expense.
+type resolverCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResolver
+	// generation is incremented by forget, resolvers loaded before it are not cached.
+	generation uint64
+}
+
+type cachedResolver struct {
+	resolver expense.Resolver
+	loadedAt time.Time
+}
+
+func (rc *resolverCache) get(key string) (expense.Resolver, uint64, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	e, ok := rc.entries[key]
+	if !ok || time.Since(e.loadedAt) >= expenseResolverTTL {
+		return expense.Resolver{}, rc.generation, false
+	}
+	return e.resolver, rc.generation, true
+}
+
+func (rc *resolverCache) set(key string, generation uint64, r expense.Resolver) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if generation != rc.generation {
+		return
+	}
+	if rc.entries == nil {
+		rc.entries = make(map[string]cachedResolver)
+	}
+	rc.entries[key] = cachedResolver{resolver: r, loadedAt: time.Now()}
+}
+
+func (rc *resolverCache) forget(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.generation++
+	delete(rc.entries, key)
+}
+
+// forgetResolver drops cached resolver of the budget of the tenant. It must be called after transactions are changed.
+func (b *Bot) forgetResolver(t *Tenant) {
+	b.resolvers.forget(budgetKey(t))
+}
+
+// expenseResolver returns what's needed to resolve expenses and suggest categories of the tenant.
+// It is cached for expenseResolverTTL and must not be modified.
+func (b *Bot) expenseResolver(t *Tenant) (expense.Resolver, error) {
+	key := budgetKey(t)
+	r, generation, ok := b.resolvers.get(key)
+	if ok {
+		return r, nil
+	}
+
+	r, err := b.loadExpenseResolver(t)
+	if err != nil {
+		return expense.Resolver{}, err
+	}
+	b.resolvers.set(key, generation, r)
+
+	return r, nil
+}
+
+// loadExpenseResolver loads what's needed to resolve expenses of the tenant.
+func (b *Bot) loadExpenseResolver(t *Tenant) (expense.Resolver, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	groups, err := t.Client.GetCategories(ctx, t.BudgetID)
+	if err != nil {
+		return expense.Resolver{}, fmt.Errorf("getting categories: %w", err)
+	}
+	accounts, err := t.Client.GetAccounts(ctx, t.BudgetID)
+	if err != nil {
+		return expense.Resolver{}, fmt.Errorf("getting accounts: %w", err)
+	}
+	history, err := t.Client.GetTransactions(ctx, t.BudgetID, time.Now().AddDate(0, -expenseHistoryMonths, 0))
+	if err != nil {
+		return expense.Resolver{}, fmt.Errorf("getting transactions: %w", err)
+	}
+
+	r := expense.Resolver{Accounts: accounts, History: history}
+	for _, g := range groups {
+		if g.Hidden || g.Deleted || g.Name == internalCategoryGroup {
+			continue
+		}
+		for _, cat := range g.Categories {
+			if !cat.Hidden && !cat.Deleted {
+				r.Categories = append(r.Categories, cat)
+			}
+		}
+	}
+	r.Suggester = suggest.New(r.Categories, history)
+
+	return r, nil
+}
+
+func (b *Bot) draftMarkup(p *i18n.Printer) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			markup.Data(p.T("history.category"), b.expenseBtns.category.Unique),
+			markup.Data(p.T("history.account"), b.expenseBtns.account.Unique),
+			markup.Data(p.T("history.date"), b.expenseBtns.date.Unique),
+		),
+		markup.Row(
+			markup.Data(p.T("button.create"), b.expenseBtns.create.Unique),
+			markup.Data(p.T("button.cancel"), b.expenseBtns.cancel.Unique),
+		),
+	)
+
+	return markup
+}
+
+// accountsMarkup returns buttons of open accounts.
+func (b *Bot) accountsMarkup(t *Tenant) (*tb.ReplyMarkup, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	accounts, err := t.Client.GetAccounts(ctx, t.BudgetID)
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+
+	markup := &tb.ReplyMarkup{}
+	rows := make([]tb.Row, 0, len(accounts))
+	for _, a := range accounts {
+		if a.Closed || a.Deleted {
+			continue
+		}
+		rows = append(rows, markup.Row(markup.Data(a.Name, b.expenseBtns.setAccount.Unique, a.ID)))
+	}
+	markup.Inline(rows...)
+
+	return markup, nil
+}
+
+// datesMarkup returns buttons of the last expenseDays days. Data of a button is number of days back.
+func (b *Bot) datesMarkup(p *i18n.Printer, now time.Time) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	var rows []tb.Row
+	var row tb.Row
+	for days := 0; days < expenseDays; days++ {
+		label := now.AddDate(0, 0, -days).Format("02.01")
+		switch days {
+		case 0:
+			label = p.T("expense.today")
+		case 1:
+			label = p.T("expense.yesterday")
+		}
+		row = append(row, markup.Data(label, b.expenseBtns.setDate.Unique, strconv.Itoa(days)))
+		if len(row) == historyColumns {
+			rows, row = append(rows, row), nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	markup.Inline(rows...)
+
+	return markup
+}
+
+func formatDraft(p *i18n.Printer, d expenseDraft) string {
+	text := "<b>" + EscapeHTML(p.T("expense.draft")) + "</b>\n\n" + formatDraftDetails(p, d.Draft)
+	if d.PhotoID != "" {
+		text += "\n\n" + EscapeHTML(p.T("receipt.attached"))
+	}
+
+	return text
+}
+
+func formatDraftDetails(p *i18n.Printer, d expense.Draft) string {
+	category, account := d.CategoryName, d.AccountName
+	switch {
+	case category == "":
+		category = p.T("expense.no_category")
+	case d.Confidence > 0:
+		category = p.T("expense.suggested", category, int(math.Round(d.Confidence*100))) //nolint: gomnd // percents
+	}
+	if account == "" {
+		account = "—"
+	}
+	rows := [][2]string{
+		{p.T("history.amount"), p.Money(-d.Amount) + " " + p.T("statistic.currency")},
+		{p.T("expense.payee"), d.Payee},
+		{p.T("history.category"), category},
+		{p.T("history.account"), account},
+		{p.T("history.date"), d.Date.Format("02.01.2006")},
+	}
+
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row[1] == "" {
+			continue
+		}
+		lines = append(lines, EscapeHTML(row[0])+": "+EscapeHTML(row[1]))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// today returns beginning of the current day in the local time zone.
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
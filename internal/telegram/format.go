@@ -3,40 +3,59 @@ package telegram
 import (
 	"bytes"
 	"fmt"
-	"html/template"
+	"text/template"
 
 	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
 )
 
+// extendedStatistic formats statistic in the language of the printer.
 type extendedStatistic struct {
 	budget.GeneralCategoryStatistic
+	*i18n.Printer
+	Category string
+}
+
+func (s extendedStatistic) BalanceS() string {
+	return s.Money(s.Balance)
+}
+
+func (s extendedStatistic) AvgSpentS() string {
+	return s.Money(s.AvgSpent)
+}
+
+func (s extendedStatistic) AvgSpentLeftS() string {
+	return s.Money(s.AvgSpentLeft)
 }
 
 func (s extendedStatistic) DaysLeftS() string {
-	switch s.DaysLeft {
-	case 1, 21, 31: //nolint: gomnd // 1, 21, 31 день
-		return fmt.Sprintf("%d день", s.DaysLeft)
-	case 2, 3, 4, 22, 23, 24: //nolint: gomnd // 2, 3, 4, 22, 23, 24 дні
-		return fmt.Sprintf("%d дні", s.DaysLeft)
-	default:
-		return fmt.Sprintf("%d днів", s.DaysLeft)
-	}
+	return s.N("days", s.DaysLeft)
+}
+
+// Table returns balance and amount left per day as monospace table.
+func (s extendedStatistic) Table() string {
+	return HTMLTable([][]string{
+		{"🟢 " + s.T("statistic.balance"), s.BalanceS() + " " + s.T("statistic.currency"), s.DaysLeftS()},
+		{"🟡 " + s.T("statistic.per_day"), s.AvgSpentLeftS() + " " + s.T("statistic.currency"), ""},
+	})
 }
 
+// NewDefaultStatisticMessageFormatter returns formatter of messages sent with Telegram HTML parse mode.
 func NewDefaultStatisticMessageFormatter() (StatisticMessageFormatter, error) {
 	t, err := template.New("defaultStatisticMessageFormatter").
-		Parse(`Статистика: 🔴 {{.AvgSpentS}} грн. в день
+		Funcs(template.FuncMap{"escape": EscapeHTML}).
+		Parse(`{{with .Category}}<b>{{escape .}}</b>
+{{end}}🔴 {{escape (.T "statistic.spent")}}: <b>{{.AvgSpentS}}</b> {{escape (.T "statistic.daily")}}
 
-Залишок:      🟢 {{.BalanceS}} грн. / {{.DaysLeftS}}
-В день:          🟡 {{.AvgSpentLeftS}} грн. в день
+{{.Table}}
 `)
 	if err != nil {
 		return nil, fmt.Errorf("parsing defaultStatisticMessageFormatter template: %w", err)
 	}
 
-	return func(cat budget.GeneralCategoryStatistic) (string, error) {
+	return func(msg StatisticMessage) (string, error) {
 		var buff bytes.Buffer
-		if err = t.Execute(&buff, extendedStatistic{cat}); err != nil {
+		if err := t.Execute(&buff, extendedStatistic{msg.Statistic, msg.Printer, msg.Category}); err != nil {
 			return "", fmt.Errorf("executing defaultStatisticMessageFormatter template: %w", err)
 		}
 		return buff.String(), nil
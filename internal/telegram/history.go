@@ -0,0 +1,336 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	historyPageSize = 10
+	// historyMonths is how many months of transactions are shown including the current one.
+	historyMonths  = 3
+	historyColumns = 5
+
+	ynabDateLayout = "2006-01-02"
+)
+
+// historyPage is a page of transactions of the category, the newest first.
+type historyPage struct {
+	Category     ynab.Category
+	Transactions []ynab.Transaction
+	Page         int
+	Total        int
+}
+
+func (h historyPage) pages() int {
+	return (h.Total + historyPageSize - 1) / historyPageSize
+}
+
+// historyHandler lists recent transactions of the category given by name or of the watched one.
+func (b *Bot) historyHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	name := strings.TrimSpace(c.Message().Payload)
+	b.log.Infow("history handler", "chatID", c.Chat().ID, "tenantID", t.ID, "category", name)
+
+	categoryID := t.CategoryID
+	if name != "" {
+		cat, err := b.findCategory(t, name)
+		if err != nil {
+			b.log.Errorw("failed to get categories", "tenantID", t.ID, "error", err)
+			return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+		}
+		if cat == nil {
+			return b.sendWithErrorLogging(c, p.T("category.not_found", name))
+		}
+		categoryID = cat.ID
+	}
+	if categoryID == "" {
+		return b.categoryHandler(c)
+	}
+
+	h, err := b.historyPage(t, categoryID, 0)
+	if err != nil {
+		b.log.Errorw("failed to get history", "tenantID", t.ID, "categoryID", categoryID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+
+	return b.sendHTML(c, formatHistory(p, h), b.historyMarkup(p, h))
+}
+
+// historyPageHandler shows another page of the history in the same message.
+func (b *Bot) historyPageHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("history page handler", "chatID", c.Chat().ID, "tenantID", t.ID, "data", c.Data())
+
+	categoryID, page, ok := parseHistoryData(c.Data())
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.invalid_request")})
+	}
+	h, err := b.historyPage(t, categoryID, page)
+	if err != nil {
+		b.log.Errorw("failed to get history", "tenantID", t.ID, "categoryID", categoryID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+
+	if err = b.editHTML(c, formatHistory(p, h), b.historyMarkup(p, h)); err != nil {
+		return err
+	}
+	return c.Respond()
+}
+
+// historyTransactionHandler shows details of the transaction in the same message. Transaction is looked up by ID,
+// so the one picked is shown even if the history has changed since. Page is only used by the back button.
+func (b *Bot) historyTransactionHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("history transaction handler", "chatID", c.Chat().ID, "tenantID", t.ID, "data", c.Data())
+
+	page, transactionID, ok := parseHistoryTransactionData(c.Data())
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("access.invalid_request")})
+	}
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	tx, err := t.Client.GetTransaction(ctx, t.BudgetID, transactionID)
+	switch {
+	case errors.Is(err, ynab.ErrNotFound) || err == nil && (tx == nil || tx.Deleted):
+		return c.Respond(&tb.CallbackResponse{Text: p.T("history.transaction_not_found")})
+	case err != nil:
+		b.log.Errorw("failed to get transaction", "tenantID", t.ID, "transactionID", transactionID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+
+	markup := &tb.ReplyMarkup{}
+	row := markup.Row(markup.Data(p.T("button.back"), b.historyBtn.Unique, tx.CategoryID, strconv.Itoa(page)))
+	if b.receipts != nil {
+		if _, ok := b.receipts.Path(t.BudgetID, tx.ID); ok {
+			row = append(row, markup.Data(p.T("button.receipt"), b.receiptBtn.Unique, tx.ID))
+		}
+	}
+	markup.Inline(row)
+	if err = b.editHTML(c, formatTransaction(p, *tx), markup); err != nil {
+		return err
+	}
+	return c.Respond()
+}
+
+// historyPage returns page of recent transactions of the category. The last page is returned if page is too big.
+func (b *Bot) historyPage(t *Tenant, categoryID string, page int) (historyPage, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	cat, err := t.Client.GetCategory(ctx, t.BudgetID, categoryID)
+	if err != nil {
+		return historyPage{}, fmt.Errorf("getting category: %w", err)
+	}
+	if cat == nil {
+		return historyPage{}, fmt.Errorf("category %q is nil", categoryID)
+	}
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month()-historyMonths+1, 1, 0, 0, 0, 0, time.UTC)
+	txs, err := t.Client.GetCategoryTransactions(ctx, t.BudgetID, categoryID, since)
+	if err != nil {
+		return historyPage{}, fmt.Errorf("getting transactions: %w", err)
+	}
+
+	recent := make([]ynab.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if !tx.Deleted {
+			recent = append(recent, tx)
+		}
+	}
+	// Dates are formatted as "2006-01-02", so they are sorted as strings.
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Date > recent[j].Date
+	})
+
+	h := historyPage{Category: *cat, Total: len(recent)}
+	h.Page = page
+	if last := h.pages() - 1; h.Page > last {
+		h.Page = last
+	}
+	if h.Page < 0 {
+		h.Page = 0
+	}
+	from := h.Page * historyPageSize
+	to := from + historyPageSize
+	if to > len(recent) {
+		to = len(recent)
+	}
+	h.Transactions = recent[from:to]
+
+	return h, nil
+}
+
+// findCategory returns visible category with the name ignoring case. Category which name starts with the name
+// is returned if there is no exact match. It returns nil if nothing matches.
+func (b *Bot) findCategory(t *Tenant, name string) (*ynab.Category, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	groups, err := t.Client.GetCategories(ctx, t.BudgetID)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	var prefixed *ynab.Category
+	for _, g := range groups {
+		if g.Hidden || g.Deleted || g.Name == internalCategoryGroup {
+			continue
+		}
+		for i, cat := range g.Categories {
+			if cat.Hidden || cat.Deleted {
+				continue
+			}
+			if strings.EqualFold(cat.Name, name) {
+				return &g.Categories[i], nil
+			}
+			if prefixed == nil && strings.HasPrefix(strings.ToLower(cat.Name), strings.ToLower(name)) {
+				prefixed = &g.Categories[i]
+			}
+		}
+	}
+
+	return prefixed, nil
+}
+
+// historyMarkup returns buttons opening transactions of the page and navigation buttons.
+func (b *Bot) historyMarkup(p *i18n.Printer, h historyPage) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	page := strconv.Itoa(h.Page)
+
+	rows := make([]tb.Row, 0)
+	var row tb.Row
+	for i, tx := range h.Transactions {
+		n := strconv.Itoa(h.Page*historyPageSize + i + 1)
+		row = append(row, markup.Data(n, b.historyTxBtn.Unique, page, tx.ID))
+		if len(row) == historyColumns {
+			rows, row = append(rows, row), nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	var nav tb.Row
+	if h.Page > 0 {
+		nav = append(nav, markup.Data("◀", b.historyBtn.Unique, h.Category.ID, strconv.Itoa(h.Page-1)))
+	}
+	if h.Page < h.pages()-1 {
+		nav = append(nav, markup.Data("▶", b.historyBtn.Unique, h.Category.ID, strconv.Itoa(h.Page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	markup.Inline(rows...)
+
+	return markup
+}
+
+// parseHistoryData parses callback data "<category id>|<page>".
+func parseHistoryData(data string) (categoryID string, page int, ok bool) {
+	parts := strings.Split(data, "|")
+	if len(parts) != 2 || parts[0] == "" { //nolint: gomnd // category and page
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(parts[1])
+	if err != nil || page < 0 {
+		return "", 0, false
+	}
+
+	return parts[0], page, true
+}
+
+// parseHistoryTransactionData parses callback data "<page>|<transaction id>". Category ID doesn't fit
+// into 64 bytes of callback data along with transaction ID, so it is taken from the transaction.
+func parseHistoryTransactionData(data string) (page int, transactionID string, ok bool) {
+	pageStr, transactionID, found := strings.Cut(data, "|")
+	if !found || transactionID == "" {
+		return 0, "", false
+	}
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 0 {
+		return 0, "", false
+	}
+
+	return page, transactionID, true
+}
+
+func formatHistory(p *i18n.Printer, h historyPage) string {
+	var sb strings.Builder
+	sb.WriteString("<b>" + EscapeHTML(h.Category.Name) + "</b>\n")
+	if h.Total == 0 {
+		sb.WriteString(EscapeHTML(p.T("history.empty")))
+		return sb.String()
+	}
+
+	from := h.Page*historyPageSize + 1
+	sb.WriteString(EscapeHTML(p.T("history.title", from, from+len(h.Transactions)-1, h.Total)) + "\n")
+	for i, tx := range h.Transactions {
+		sb.WriteString(fmt.Sprintf("\n%d. <code>%s</code> %s <b>%s</b>",
+			from+i, shortDate(tx.Date), EscapeHTML(payeeOf(p, tx)), p.Money(tx.Amount)))
+		if tx.Memo != "" {
+			sb.WriteString(" — <i>" + EscapeHTML(tx.Memo) + "</i>")
+		}
+	}
+
+	return sb.String()
+}
+
+func formatTransaction(p *i18n.Printer, tx ynab.Transaction) string {
+	rows := [][2]string{
+		{p.T("history.date"), longDate(tx.Date)},
+		{p.T("history.amount"), p.Money(tx.Amount) + " " + p.T("statistic.currency")},
+		{p.T("history.category"), tx.CategoryName},
+		{p.T("history.account"), tx.AccountName},
+		{p.T("history.status"), p.T("history.cleared." + tx.Cleared)},
+		{p.T("history.memo"), tx.Memo},
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>" + EscapeHTML(payeeOf(p, tx)) + "</b>\n")
+	for _, row := range rows {
+		if row[1] == "" {
+			continue
+		}
+		sb.WriteString("\n" + EscapeHTML(row[0]) + ": " + EscapeHTML(row[1]))
+	}
+	if !tx.Approved {
+		sb.WriteString("\n\n<i>" + EscapeHTML(p.T("history.unapproved")) + "</i>")
+	}
+
+	return sb.String()
+}
+
+func payeeOf(p *i18n.Printer, tx ynab.Transaction) string {
+	if tx.PayeeName == "" {
+		return p.T("history.no_payee")
+	}
+	return tx.PayeeName
+}
+
+// shortDate formats YNAB date as "02.01". Date is returned as is if it can't be parsed.
+func shortDate(date string) string {
+	d, err := time.Parse(ynabDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return d.Format("02.01")
+}
+
+// longDate formats YNAB date as "02.01.2006". Date is returned as is if it can't be parsed.
+func longDate(date string) string {
+	d, err := time.Parse(ynabDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return d.Format("02.01.2006")
+}
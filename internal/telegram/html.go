@@ -0,0 +1,189 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+// EscapeHTML escapes text to be put into message sent with Telegram HTML parse mode.
+func EscapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// StripHTML converts message formatted with Telegram HTML to plain text.
+func StripHTML(s string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			break
+		}
+		sb.WriteString(s[:start])
+		s = s[start+end+1:]
+	}
+	sb.WriteString(s)
+
+	return html.UnescapeString(sb.String())
+}
+
+// HTMLTable formats rows as monospace block with aligned columns. The first column is aligned left, others right.
+// Cells are escaped.
+func HTMLTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<pre>")
+	for r, row := range rows {
+		if r > 0 {
+			sb.WriteString("\n")
+		}
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch {
+			case i == 0:
+				sb.WriteString(EscapeHTML(cell) + pad)
+			default:
+				sb.WriteString("  " + pad + EscapeHTML(cell))
+			}
+		}
+	}
+	sb.WriteString("</pre>")
+
+	return sb.String()
+}
+
+// ValidateHTML checks that message uses only tags and entities supported by Telegram HTML parse mode
+// and that tags are properly nested.
+func ValidateHTML(s string) error {
+	var open []string
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unclosed start tag at byte offset %d", i)
+			}
+			tag := s[i+1 : i+end]
+			name, closing := strings.TrimPrefix(tag, "/"), strings.HasPrefix(tag, "/")
+			if fields := strings.Fields(name); len(fields) > 0 {
+				name = strings.ToLower(fields[0])
+			}
+			if !supportedTag(name) {
+				return fmt.Errorf("unsupported start tag %q at byte offset %d", name, i)
+			}
+			if closing {
+				if len(open) == 0 || open[len(open)-1] != name {
+					return fmt.Errorf("unexpected end tag at byte offset %d", i)
+				}
+				open = open[:len(open)-1]
+			} else {
+				open = append(open, name)
+			}
+			i += end
+		case '&':
+			end := strings.IndexByte(s[i:], ';')
+			if end < 0 || !supportedEntity(s[i+1:i+end]) {
+				return fmt.Errorf("unsupported HTML entity at byte offset %d", i)
+			}
+			i += end
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("can't find end tag corresponding to start tag %q", open[len(open)-1])
+	}
+
+	return nil
+}
+
+func supportedTag(name string) bool {
+	switch name {
+	case "b", "strong", "i", "em", "u", "ins", "s", "strike", "del", "span", "tg-spoiler", "a", "code", "pre",
+		"blockquote", "tg-emoji":
+		return true
+	default:
+		return false
+	}
+}
+
+func supportedEntity(name string) bool {
+	switch name {
+	case "lt", "gt", "amp", "quot":
+		return true
+	}
+
+	digits, ok := strings.CutPrefix(name, "#")
+	if !ok {
+		return false
+	}
+	set := "0123456789"
+	if hex, ok := strings.CutPrefix(strings.ToLower(digits), "x"); ok {
+		digits, set = hex, "0123456789abcdef"
+	}
+
+	return digits != "" && strings.Trim(digits, set) == ""
+}
+
+// isParseError reports whether Telegram rejected entities of the message.
+// telebot doesn't have dedicated error for it, so description is checked.
+func isParseError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't parse entities")
+}
+
+// isNotModified reports whether Telegram rejected edit because it doesn't change the message.
+func isNotModified(err error) bool {
+	return errors.Is(err, tb.ErrSameMessageContent) || errors.Is(err, tb.ErrMessageNotModified)
+}
+
+// sendHTML sends message formatted with Telegram HTML. Message is sent as plain text if Telegram rejects
+// its entities, e.g. because of broken user template.
+func (b *Bot) sendHTML(c tb.Context, msg string, opts ...interface{}) error {
+	err := c.Send(msg, append([]interface{}{tb.ModeHTML}, opts...)...)
+	if isParseError(err) {
+		b.log.Warnw("telegram rejected html, sending plain text", "chatID", c.Chat().ID, "error", err)
+		err = c.Send(StripHTML(msg), opts...)
+	}
+	if err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// editHTML edits message of the callback like sendHTML sends it. Telegram rejects edits which don't change
+// the message, such edits are ignored.
+func (b *Bot) editHTML(c tb.Context, msg string, opts ...interface{}) error {
+	err := c.Edit(msg, append([]interface{}{tb.ModeHTML}, opts...)...)
+	if isParseError(err) {
+		b.log.Warnw("telegram rejected html, editing with plain text", "chatID", c.Chat().ID, "error", err)
+		err = c.Edit(StripHTML(msg), opts...)
+	}
+	if isNotModified(err) {
+		return nil
+	}
+	if err != nil {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package telegram_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+func TestEscapeHTML(t *testing.T) {
+	assert.Equal(t, "Food &amp; drinks &lt;3 &gt; \"'", telegram.EscapeHTML(`Food & drinks <3 > "'`))
+	assert.NoError(t, telegram.ValidateHTML(telegram.EscapeHTML(`<b>&amp;</b>`)))
+}
+
+func TestStripHTML(t *testing.T) {
+	assert.Equal(t, "Groceries\n580.00 & <3", telegram.StripHTML("<b>Groceries</b>\n<pre>580.00 &amp; &lt;3</pre>"))
+	assert.Equal(t, "a < b", telegram.StripHTML("a &lt; b"))
+}
+
+func TestHTMLTable(t *testing.T) {
+	got := telegram.HTMLTable([][]string{
+		{"Balance", "1,580.00", "17 days"},
+		{"Per day & night", "93.00"},
+	})
+	assert.Equal(t, "<pre>Balance          1,580.00  17 days\nPer day &amp; night     93.00</pre>", got)
+}
+
+func TestValidateHTML(t *testing.T) {
+	valid := []string{
+		"plain text",
+		"<b>bold <i>italic</i></b>",
+		`<a href="https://example.com">link</a>`,
+		`<span class="tg-spoiler">spoiler</span>`,
+		"<pre>&lt;&gt;&amp;&quot;&#39;&#x1F600;</pre>",
+	}
+	for _, s := range valid {
+		assert.NoError(t, telegram.ValidateHTML(s), s)
+	}
+
+	invalid := []string{
+		"<b>unclosed",
+		"<b><i>crossed</b></i>",
+		"closed</b>",
+		"<div>unsupported</div>",
+		"a < b",
+		"Food & drinks",
+		"&nbsp;",
+		"&#12a;",
+	}
+	for _, s := range invalid {
+		assert.Error(t, telegram.ValidateHTML(s), s)
+	}
+}
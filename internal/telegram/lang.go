@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+)
+
+const printerContextKey = "printer"
+
+// LanguageStore keeps languages chosen by chats with /lang.
+type LanguageStore interface {
+	Language(chatID int64) (i18n.Lang, bool)
+	SetLanguage(chatID int64, lang i18n.Lang) error
+}
+
+// languageMiddleware stores printer of the chat language in the context.
+func (b *Bot) languageMiddleware(next tb.HandlerFunc) tb.HandlerFunc {
+	return func(c tb.Context) error {
+		var chatID int64
+		if chat := c.Chat(); chat != nil {
+			chatID = chat.ID
+		}
+		var userLang string
+		if s := c.Sender(); s != nil {
+			userLang = s.LanguageCode
+		}
+
+		c.Set(printerContextKey, b.printer(chatID, userLang))
+		return next(c)
+	}
+}
+
+// printer returns printer of the language chosen by the chat. If chat hasn't chosen any,
+// language of the user is used if it is supported, otherwise the default one.
+func (b *Bot) printer(chatID int64, userLang string) *i18n.Printer {
+	if b.languages != nil {
+		if lang, ok := b.languages.Language(chatID); ok {
+			return b.catalog.Printer(lang)
+		}
+	}
+	if lang, ok := i18n.ParseLang(userLang); ok {
+		return b.catalog.Printer(lang)
+	}
+
+	return b.catalog.Printer(b.defaultLang)
+}
+
+// printerFrom returns printer stored by languageMiddleware.
+func (b *Bot) printerFrom(c tb.Context) *i18n.Printer {
+	if p, ok := c.Get(printerContextKey).(*i18n.Printer); ok {
+		return p
+	}
+
+	return b.catalog.Printer(b.defaultLang)
+}
+
+func (b *Bot) langHandler(c tb.Context) error {
+	b.log.Infow("lang handler", "chatID", c.Chat().ID)
+
+	markup := &tb.ReplyMarkup{}
+	rows := make([]tb.Row, 0, len(b.catalog.Languages()))
+	for _, lang := range b.catalog.Languages() {
+		name := b.catalog.Printer(lang).T("lang.name")
+		rows = append(rows, markup.Row(markup.Data(name, b.langBtn.Unique, string(lang))))
+	}
+	markup.Inline(rows...)
+
+	if err := c.Send(b.printerFrom(c).T("lang.choose"), markup); err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bot) langSelectedHandler(c tb.Context) error {
+	b.log.Infow("lang selected handler", "chatID", c.Chat().ID, "lang", c.Data())
+
+	lang, ok := i18n.ParseLang(c.Data())
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: b.printerFrom(c).T("access.invalid_request")})
+	}
+	if err := b.languages.SetLanguage(c.Chat().ID, lang); err != nil {
+		b.log.Errorw("failed to set language", "chatID", c.Chat().ID, "lang", lang, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.printerFrom(c).T("error.unexpected")})
+	}
+
+	p := b.catalog.Printer(lang)
+	c.Set(printerContextKey, p)
+	if err := c.Respond(); err != nil {
+		b.log.Errorw("failed to answer callback", "chatID", c.Chat().ID, "error", err)
+	}
+
+	return b.sendWithErrorLogging(c, p.T("lang.set"))
+}
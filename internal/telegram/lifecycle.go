@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+const handlerTimeout = 1 * time.Minute
+
+// Start registers handlers and starts processing updates in background.
+// Contexts of handlers are derived from ctx, so cancelling it aborts in-flight YNAB requests.
+//
+// bot must be created with Synchronous setting: every update is handled in its own goroutine started by Bot,
+// so Stop knows about all of them. tb.Bot.Start isn't used, because it can't stop receiving updates without
+// cancelling API requests of in-flight handlers and it changes state of the client which handlers read.
+func (b *Bot) Start(ctx context.Context, bot *tb.Bot) {
+	b.bot = bot
+	b.ctx = ctx
+	bot.Use(b.metricsMiddleware, b.languageMiddleware)
+
+	b.registerHandlers(bot)
+
+	b.halt, b.dispatched = make(chan struct{}), make(chan struct{})
+	updates := make(chan tb.Update)
+	go bot.Poller.Poll(bot, updates, b.halt)
+	go b.dispatch(bot, updates)
+}
+
+// Stop stops receiving updates and waits until in-flight handlers are finished or ctx is done.
+// Long polling request in progress is abandoned: updates it returns are not confirmed, so Telegram delivers them
+// again after restart.
+func (b *Bot) Stop(ctx context.Context) error {
+	b.log.Infow("stopping bot")
+	b.haltOnce.Do(func() {
+		close(b.halt)
+	})
+	<-b.dispatched
+
+	drained := make(chan struct{})
+	go func() {
+		b.handlers.Wait()
+		close(drained)
+	}()
+
+	var res error
+	select {
+	case <-drained:
+		b.log.Infow("all handlers are finished")
+	case <-ctx.Done():
+		res = fmt.Errorf("waiting for handlers: %w", ctx.Err())
+	}
+
+	if wp, ok := b.bot.Poller.(*WebhookPoller); ok {
+		if err := wp.Remove(b.bot); err != nil {
+			b.log.Errorw("failed to remove webhook", "error", err)
+		}
+	}
+
+	b.log.Infow("bot is stopped")
+	return res
+}
+
+// dispatch handles updates until receiving is halted. Handlers are counted here rather than in a middleware,
+// so none of them can start after Stop begins waiting.
+func (b *Bot) dispatch(bot *tb.Bot, updates <-chan tb.Update) {
+	defer close(b.dispatched)
+
+	for {
+		select {
+		case u := <-updates:
+			b.handlers.Add(1)
+			go func() {
+				defer b.handlers.Done()
+				bot.ProcessUpdate(u)
+			}()
+		case <-b.halt:
+			return
+		}
+	}
+}
+
+// requestContext returns context for requests made by handler.
+func (b *Bot) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(b.ctx, handlerTimeout)
+}
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+// internalCategoryGroup holds YNAB system categories which can't be budgeted.
+const internalCategoryGroup = "Internal Master Category"
+
+// LinkedTenantID returns ID of the tenant of the chat linked with /link.
+func LinkedTenantID(chatID int64) string {
+	return "chat:" + strconv.FormatInt(chatID, 10)
+}
+
+// linkHandler links the chat to YNAB account of the user. Chats of configured or approved tenants can't be linked,
+// only chats without tenant or linked before.
+func (b *Bot) linkHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("link handler", "chatID", c.Chat().ID)
+
+	if t, ok := b.tenants.ByChat(c.Chat().ID); ok {
+		if t.ID != LinkedTenantID(c.Chat().ID) {
+			return b.sendWithErrorLogging(c, p.T("link.configured"))
+		}
+		c.Set(tenantContextKey, t)
+		if roleOf(b.roles, c) < RoleAdmin {
+			return b.sendWithErrorLogging(c, p.T("access.forbidden"))
+		}
+	}
+
+	url, err := b.linker.AuthURL(c.Chat().ID, senderID(c))
+	if err != nil {
+		b.log.Errorw("failed to create auth url", "chatID", c.Chat().ID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	markup := &tb.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.URL(p.T("button.connect_ynab"), url)))
+	if err = c.Send(p.T("link.open"), markup); err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Linked notifies the chat that YNAB account was linked and asks to pick a category if none is selected yet.
+func (b *Bot) Linked(chatID int64, categoryID string) {
+	p := b.printer(chatID, "")
+	if categoryID != "" {
+		if err := b.notify(chatID, "linked", p.T("link.category_saved"), b.stateMarkup(p)); err != nil {
+			b.log.Errorw("failed to send message", "chatID", chatID, "error", err)
+		}
+		return
+	}
+
+	t, ok := b.tenants.ByChat(chatID)
+	if !ok {
+		b.log.Warnw("linked chat has no tenant", "chatID", chatID)
+		return
+	}
+
+	markup, err := b.categoriesMarkup(t, b.categoryBtn)
+	if err != nil {
+		b.log.Errorw("failed to get categories", "chatID", chatID, "tenantID", t.ID, "error", err)
+		return
+	}
+	if err = b.notify(chatID, "linked", p.T("link.linked"), markup); err != nil {
+		b.log.Errorw("failed to send message", "chatID", chatID, "error", err)
+	}
+}
+
+func (b *Bot) categoryHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("category handler", "chatID", c.Chat().ID, "tenantID", t.ID)
+
+	markup, err := b.categoriesMarkup(t, b.categoryBtn)
+	if err != nil {
+		b.log.Errorw("failed to get categories", "chatID", c.Chat().ID, "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	if err = c.Send(p.T("link.choose_category"), markup); err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bot) categorySelectedHandler(c tb.Context) error {
+	b.log.Infow("category selected handler", "chatID", c.Chat().ID, "categoryID", c.Data())
+
+	if err := b.linker.SetCategory(c.Chat().ID, c.Data()); err != nil {
+		b.log.Errorw("failed to set category", "chatID", c.Chat().ID, "error", err)
+		return b.sendWithErrorLogging(c, b.printerFrom(c).T("link.category_not_linked"))
+	}
+
+	return c.Respond()
+}
+
+func (b *Bot) categoriesMarkup(t *Tenant, btn *tb.Btn) (*tb.ReplyMarkup, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	groups, err := t.Client.GetCategories(ctx, t.BudgetID)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	markup := &tb.ReplyMarkup{}
+	rows := make([]tb.Row, 0)
+	for _, g := range groups {
+		if g.Hidden || g.Deleted || g.Name == internalCategoryGroup {
+			continue
+		}
+		for _, cat := range g.Categories {
+			if cat.Hidden || cat.Deleted {
+				continue
+			}
+			rows = append(rows, markup.Row(markup.Data(cat.Name, btn.Unique, cat.ID)))
+		}
+	}
+	markup.Inline(rows...)
+
+	return markup, nil
+}
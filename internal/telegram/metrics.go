@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+// Metrics records handler invocations and notifications sent by the bot.
+type Metrics interface {
+	ObserveHandler(command string, chatID int64, duration time.Duration, err error)
+	ObserveNotification(kind string, err error)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveHandler(string, int64, time.Duration, error) {}
+
+func (noopMetrics) ObserveNotification(string, error) {}
+
+func (b *Bot) metricsMiddleware(next tb.HandlerFunc) tb.HandlerFunc {
+	return func(c tb.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		var chatID int64
+		if chat := c.Chat(); chat != nil {
+			chatID = chat.ID
+		}
+		b.metrics.ObserveHandler(commandOf(c), chatID, time.Since(start), err)
+
+		return err
+	}
+}
+
+// notify sends message the bot initiates on its own, e.g. to admins or to chat linked with OAuth.
+func (b *Bot) notify(chatID int64, kind string, what interface{}, opts ...interface{}) error {
+	_, err := b.notifyMessage(chatID, kind, what, opts...)
+	return err
+}
+
+// notifyMessage is notify returning the sent message.
+func (b *Bot) notifyMessage(chatID int64, kind string, what interface{}, opts ...interface{}) (*tb.Message, error) {
+	msg, err := b.bot.Send(tb.ChatID(chatID), what, opts...)
+	b.metrics.ObserveNotification(kind, err)
+	if err != nil {
+		return nil, fmt.Errorf("sending %s notification: %w", kind, err)
+	}
+
+	return msg, nil
+}
+
+// commandOf returns command, button or "message" the update is handled as.
+func commandOf(c tb.Context) string {
+	if cb := c.Callback(); cb != nil {
+		if cb.Unique != "" {
+			return cb.Unique
+		}
+		return "callback"
+	}
+
+	text := c.Text()
+	if !strings.HasPrefix(text, "/") {
+		return "message"
+	}
+	command, _, _ := strings.Cut(strings.Fields(text)[0], "@")
+
+	return command
+}
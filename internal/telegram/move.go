@@ -0,0 +1,267 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// moveRequest is money move waiting for categories to be picked or for confirmation in the message with MessageID.
+type moveRequest struct {
+	Amount    int
+	From      *ynab.Category
+	To        *ynab.Category
+	MessageID int
+}
+
+type moveButtons struct {
+	from    *tb.Btn
+	to      *tb.Btn
+	confirm *tb.Btn
+	cancel  *tb.Btn
+}
+
+func newMoveButtons() moveButtons {
+	return moveButtons{
+		from:    &tb.Btn{Unique: "move_from"},
+		to:      &tb.Btn{Unique: "move_to"},
+		confirm: &tb.Btn{Unique: "move_confirm"},
+		cancel:  &tb.Btn{Unique: "move_cancel"},
+	}
+}
+
+// moveHandler handles "/move <amount> [from <category>] [to <category>]". Missing categories are picked with buttons.
+func (b *Bot) moveHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("move handler", "chatID", c.Chat().ID, "tenantID", t.ID, "payload", c.Message().Payload)
+
+	amount, fromName, toName, ok := parseMove(c.Message().Payload)
+	if !ok {
+		return b.sendWithErrorLogging(c, p.T("move.usage"))
+	}
+
+	r := moveRequest{Amount: amount}
+	for _, pick := range []struct {
+		name string
+		dst  **ynab.Category
+	}{{fromName, &r.From}, {toName, &r.To}} {
+		if pick.name == "" {
+			continue
+		}
+		cat, err := b.findCategory(t, pick.name)
+		if err != nil {
+			b.log.Errorw("failed to get categories", "tenantID", t.ID, "error", err)
+			return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+		}
+		if cat == nil {
+			return b.sendWithErrorLogging(c, p.T("category.not_found", pick.name))
+		}
+		*pick.dst = cat
+	}
+	if r.From != nil && r.To != nil && r.From.ID == r.To.ID {
+		return b.sendWithErrorLogging(c, p.T("move.same_category"))
+	}
+
+	text, markup, err := b.moveStep(t, p, r)
+	if err != nil {
+		b.log.Errorw("failed to get categories", "tenantID", t.ID, "error", err)
+		return b.sendWithErrorLogging(c, b.ynabErrorMessage(p, err))
+	}
+	// The older request is dropped before the new one is shown, so its buttons don't confirm it meanwhile.
+	b.moves.remove(c.Chat().ID)
+	msg, err := c.Bot().Send(c.Recipient(), text, markup)
+	if err != nil {
+		b.log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	r.MessageID = msg.ID
+	b.moves.set(c.Chat().ID, r)
+
+	return nil
+}
+
+// moveCategoryHandler sets category picked with button and shows the next step.
+func (b *Bot) moveCategoryHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("move category handler", "chatID", c.Chat().ID, "tenantID", t.ID, "categoryID", c.Data())
+
+	r, ok := b.moves.get(c.Chat().ID)
+	if !ok || !isMessageOf(c, r.MessageID) {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("move.expired")})
+	}
+
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	cat, err := t.Client.GetCategory(ctx, t.BudgetID, c.Data())
+	if err != nil || cat == nil {
+		b.log.Errorw("failed to get category", "tenantID", t.ID, "categoryID", c.Data(), "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	if c.Callback().Unique == b.moveBtns.from.Unique {
+		r.From = cat
+	} else {
+		r.To = cat
+	}
+	if r.From != nil && r.To != nil && r.From.ID == r.To.ID {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("move.same_category")})
+	}
+	b.moves.set(c.Chat().ID, r)
+
+	text, markup, err := b.moveStep(t, p, r)
+	if err != nil {
+		b.log.Errorw("failed to get categories", "tenantID", t.ID, "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	if err = c.Edit(text, markup); err != nil {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	return c.Respond()
+}
+
+// moveConfirmHandler moves money of the pending request and shows statistic of both categories.
+func (b *Bot) moveConfirmHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("move confirm handler", "chatID", c.Chat().ID, "tenantID", t.ID)
+
+	r, ok := b.moves.take(c.Chat().ID, func(r moveRequest) bool {
+		return isMessageOf(c, r.MessageID) && r.From != nil && r.To != nil
+	})
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("move.expired")})
+	}
+
+	from, to, err := b.moveMoney(t, r)
+	if err != nil {
+		b.log.Errorw("failed to move money", "chatID", c.Chat().ID, "tenantID", t.ID, "error", err)
+		if editErr := c.Edit(p.T("move.failed")); editErr != nil {
+			b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", editErr)
+		}
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	b.log.Infow("money moved", "chatID", c.Chat().ID, "tenantID", t.ID,
+		"from", r.From.ID, "to", r.To.ID, "amount", r.Amount)
+
+	parts := []string{EscapeHTML(p.T("move.done", p.Money(r.Amount), p.T("statistic.currency"), from.Name, to.Name))}
+	for _, cat := range []*ynab.Category{from, to} {
+		msg, err := b.msgFormatter(StatisticMessage{
+			ChatID:    c.Chat().ID,
+			Command:   "move",
+			Category:  cat.Name,
+			Printer:   p,
+			Statistic: budget.CalculateStatistic(*cat),
+		})
+		if err != nil {
+			b.log.Errorw("failed to format message", "chatID", c.Chat().ID, "error", err)
+			continue
+		}
+		parts = append(parts, strings.TrimRight(msg, "\n"))
+	}
+
+	if err = b.editHTML(c, strings.Join(parts, "\n\n"), b.stateMarkup(p)); err != nil {
+		return err
+	}
+	return c.Respond()
+}
+
+func (b *Bot) moveCancelHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("move cancel handler", "chatID", c.Chat().ID)
+
+	b.moves.take(c.Chat().ID, func(r moveRequest) bool {
+		return isMessageOf(c, r.MessageID)
+	})
+	if err := c.Edit(p.T("move.cancelled")); err != nil {
+		b.log.Errorw("failed to edit message", "chatID", c.Chat().ID, "error", err)
+		return err
+	}
+	return c.Respond()
+}
+
+// moveMoney decreases amount budgeted for the source category and increases it for the target one in the current
+// month. Budgeted amounts are read right before the change. Source category is restored if target can't be changed.
+func (b *Bot) moveMoney(t *Tenant, r moveRequest) (*ynab.Category, *ynab.Category, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	from, err := t.Client.GetCurrentMonthCategory(ctx, t.BudgetID, r.From.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting source category: %w", err)
+	}
+	to, err := t.Client.GetCurrentMonthCategory(ctx, t.BudgetID, r.To.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting target category: %w", err)
+	}
+
+	updatedFrom, err := t.Client.SetCurrentMonthBudgeted(ctx, t.BudgetID, from.ID, from.Budgeted-r.Amount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("updating source category: %w", err)
+	}
+	updatedTo, err := t.Client.SetCurrentMonthBudgeted(ctx, t.BudgetID, to.ID, to.Budgeted+r.Amount)
+	if err != nil {
+		if _, restoreErr := t.Client.SetCurrentMonthBudgeted(ctx, t.BudgetID, from.ID, from.Budgeted); restoreErr != nil {
+			b.log.Errorw("failed to restore source category", "tenantID", t.ID, "categoryID", from.ID,
+				"budgeted", from.Budgeted, "error", restoreErr)
+		}
+		return nil, nil, fmt.Errorf("updating target category: %w", err)
+	}
+
+	return updatedFrom, updatedTo, nil
+}
+
+// moveStep returns message and buttons of the next step of the request: picking source category,
+// picking target category or confirmation.
+func (b *Bot) moveStep(t *Tenant, p *i18n.Printer, r moveRequest) (string, *tb.ReplyMarkup, error) {
+	amount := p.Money(r.Amount) + " " + p.T("statistic.currency")
+	switch {
+	case r.From == nil:
+		markup, err := b.categoriesMarkup(t, b.moveBtns.from)
+		return p.T("move.choose_from", amount), markup, err
+	case r.To == nil:
+		markup, err := b.categoriesMarkup(t, b.moveBtns.to)
+		return p.T("move.choose_to", amount, r.From.Name), markup, err
+	default:
+		markup := &tb.ReplyMarkup{}
+		markup.Inline(markup.Row(
+			markup.Data(p.T("button.move"), b.moveBtns.confirm.Unique),
+			markup.Data(p.T("button.cancel"), b.moveBtns.cancel.Unique),
+		))
+		return p.T("move.confirm", amount, r.From.Name, r.To.Name), markup, nil
+	}
+}
+
+// parseMove parses "<amount> [from <category>] [to <category>]". Amount may have decimal point or comma.
+func parseMove(payload string) (amount int, from, to string, ok bool) {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return 0, "", "", false
+	}
+	amount, ok = expense.ParseAmount(fields[0])
+	if !ok {
+		return 0, "", "", false
+	}
+
+	var dst *string
+	for _, f := range fields[1:] {
+		switch strings.ToLower(f) {
+		case "from":
+			dst = &from
+			continue
+		case "to":
+			dst = &to
+			continue
+		}
+		if dst == nil {
+			return 0, "", "", false
+		}
+		*dst = strings.TrimSpace(*dst + " " + f)
+	}
+
+	return amount, from, to, true
+}
@@ -0,0 +1,65 @@
+package telegram
+
+import "sync"
+
+// pending keeps requests which wait for input from chats, one request per chat.
+// Callback data is too short for most of the state, so it is kept here until the request is done.
+type pending[T any] struct {
+	mu       sync.Mutex
+	requests map[int64]T
+}
+
+func (p *pending[T]) get(chatID int64) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.requests[chatID]
+	return r, ok
+}
+
+func (p *pending[T]) set(chatID int64, r T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.requests == nil {
+		p.requests = make(map[int64]T)
+	}
+	p.requests[chatID] = r
+}
+
+// take removes and returns request of the chat if match accepts it. Only one of concurrent callers gets
+// the request, so it isn't handled twice when a button is tapped twice.
+func (p *pending[T]) take(chatID int64, match func(r T) bool) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.requests[chatID]
+	if !ok || !match(r) {
+		var zero T
+		return zero, false
+	}
+	delete(p.requests, chatID)
+
+	return r, true
+}
+
+// restore sets taken request of the chat back unless a newer one is set already.
+func (p *pending[T]) restore(chatID int64, r T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.requests[chatID]; ok {
+		return
+	}
+	if p.requests == nil {
+		p.requests = make(map[int64]T)
+	}
+	p.requests[chatID] = r
+}
+
+func (p *pending[T]) remove(chatID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.requests, chatID)
+}
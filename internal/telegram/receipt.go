@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/expense"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+// receiptPhotoTTL is how long receipt photo without amount waits for the expense text.
+const receiptPhotoTTL = 10 * time.Minute
+
+// ReceiptStore keeps photos of receipts by budget and transaction ID.
+type ReceiptStore interface {
+	Save(budgetID, txID string, r io.Reader) error
+	// Path returns path of the receipt file if receipt of the transaction of the budget is stored.
+	Path(budgetID, txID string) (string, bool)
+}
+
+// pendingPhoto is receipt photo waiting for the expense text of the user who sent it.
+type pendingPhoto struct {
+	FileID string
+	UserID int64
+	SentAt time.Time
+}
+
+// photoHandler turns receipt photo into draft transaction. Amount and payee are parsed from the caption like
+// free text expenses are. Without them the photo waits for the next expense text of the chat.
+func (b *Bot) photoHandler(c tb.Context) error {
+	t, ok := b.memberTenant(c)
+	if !ok || c.Message().Photo == nil {
+		return nil
+	}
+	photoID := c.Message().Photo.FileID
+
+	if e, ok := expense.Parse(strings.TrimSpace(c.Message().Caption), time.Now()); ok {
+		b.photos.take(c.Chat().ID, func(ph pendingPhoto) bool {
+			return ph.UserID == senderID(c)
+		})
+		return b.sendDraft(c, t, e, photoID)
+	}
+
+	b.log.Infow("receipt waits for amount", "chatID", c.Chat().ID, "tenantID", t.ID)
+	b.photos.set(c.Chat().ID, pendingPhoto{FileID: photoID, UserID: senderID(c), SentAt: time.Now()})
+	return b.sendWithErrorLogging(c, b.printerFrom(c).T("receipt.ask_amount"))
+}
+
+// historyReceiptHandler sends receipt of the transaction shown in history. The transaction must belong to the budget
+// of the tenant, so the chat can't get receipts of other budgets with crafted callback data.
+func (b *Bot) historyReceiptHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("history receipt handler", "chatID", c.Chat().ID, "tenantID", t.ID, "transactionID", c.Data())
+
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+	tx, err := t.Client.GetTransaction(ctx, t.BudgetID, c.Data())
+	switch {
+	case errors.Is(err, ynab.ErrNotFound) || err == nil && (tx == nil || tx.Deleted):
+		return c.Respond(&tb.CallbackResponse{Text: p.T("history.transaction_not_found")})
+	case err != nil:
+		b.log.Errorw("failed to get transaction", "tenantID", t.ID, "transactionID", c.Data(), "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+
+	path, ok := b.receipts.Path(t.BudgetID, tx.ID)
+	if !ok {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("history.transaction_not_found")})
+	}
+	if err := c.Send(&tb.Photo{File: tb.FromDisk(path)}); err != nil {
+		b.log.Errorw("failed to send receipt", "chatID", c.Chat().ID, "transactionID", c.Data(), "error", err)
+		return c.Respond(&tb.CallbackResponse{Text: p.T("error.unexpected")})
+	}
+	return c.Respond()
+}
+
+// saveReceipt downloads the photo from Telegram and stores it as receipt of the transaction of the budget.
+func (b *Bot) saveReceipt(bot *tb.Bot, budgetID, txID, photoID string) error {
+	if b.receipts == nil {
+		return nil
+	}
+
+	r, err := bot.File(&tb.File{FileID: photoID})
+	if err != nil {
+		return fmt.Errorf("downloading photo: %w", err)
+	}
+	defer r.Close()
+
+	if err = b.receipts.Save(budgetID, txID, r); err != nil {
+		return fmt.Errorf("saving receipt: %w", err)
+	}
+
+	return nil
+}
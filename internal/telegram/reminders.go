@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/suggest"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	// reminderSuggestions is how many suggested categories a reminder offers.
+	reminderSuggestions = 3
+	// reminderTTL is how long buttons of a reminder work.
+	reminderTTL = 7 * 24 * time.Hour
+)
+
+type reminderButtons struct {
+	category *tb.Btn
+	skip     *tb.Btn
+}
+
+func newReminderButtons() reminderButtons {
+	return reminderButtons{
+		category: &tb.Btn{Unique: "rem_category"},
+		skip:     &tb.Btn{Unique: "rem_skip"},
+	}
+}
+
+// reminder asks to pick category of the transaction. Category IDs don't fit callback data together with
+// transaction ID, so data of the buttons is index of the suggestion.
+type reminder struct {
+	BudgetKey     string
+	TransactionID string
+	Suggestions   []suggest.Suggestion
+	SentAt        time.Time
+}
+
+type reminderKey struct {
+	chatID    int64
+	messageID int
+}
+
+// reminders keeps reminders by their messages until category is picked or they expire.
+type reminders struct {
+	mu      sync.Mutex
+	entries map[reminderKey]reminder
+}
+
+func (r *reminders) add(chatID int64, messageID int, rem reminder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.entries == nil {
+		r.entries = make(map[reminderKey]reminder)
+	}
+	for key, e := range r.entries {
+		if time.Since(e.SentAt) >= reminderTTL {
+			delete(r.entries, key)
+		}
+	}
+	r.entries[reminderKey{chatID: chatID, messageID: messageID}] = rem
+}
+
+// take removes and returns reminder of the message unless it is expired.
+func (r *reminders) take(chatID int64, messageID int) (reminder, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reminderKey{chatID: chatID, messageID: messageID}
+	rem, ok := r.entries[key]
+	delete(r.entries, key)
+
+	return rem, ok && time.Since(rem.SentAt) < reminderTTL
+}
+
+// uncategorized returns new outflows without category, except the ones in duplicate pairs which are asked about
+// separately. Transfers don't need category.
+func uncategorized(fresh []ynab.Transaction, paired map[string]bool) []ynab.Transaction {
+	var res []ynab.Transaction
+	for _, tx := range fresh {
+		if tx.CategoryID != "" || tx.Deleted || tx.Amount >= 0 || paired[tx.ID] || tx.IsTransfer() {
+			continue
+		}
+		res = append(res, tx)
+	}
+
+	return res
+}
+
+// remind asks the chat to pick category of the transaction from the suggestions.
+func (b *Bot) remind(chatID int64, t *Tenant, tx ynab.Transaction, suggestions []suggest.Suggestion) error {
+	p := b.printer(chatID, "")
+	if len(suggestions) > reminderSuggestions {
+		suggestions = suggestions[:reminderSuggestions]
+	}
+
+	text := p.T("reminder.uncategorized", p.Money(-tx.Amount)+" "+p.T("statistic.currency")+", "+
+		payeeOf(p, tx)+", "+shortDate(tx.Date)+", "+tx.AccountName) + "\n\n"
+	if len(suggestions) == 0 {
+		return b.notify(chatID, "reminder", text+p.T("reminder.no_suggestions"))
+	}
+
+	markup := &tb.ReplyMarkup{}
+	rows := make([]tb.Row, 0, len(suggestions)+1)
+	for i, s := range suggestions {
+		percent := int(math.Round(s.Confidence * 100)) //nolint: gomnd // percents
+		label := fmt.Sprintf("%s %d%%", s.CategoryName, percent)
+		rows = append(rows, markup.Row(markup.Data(label, b.reminderBtns.category.Unique, strconv.Itoa(i))))
+	}
+	rows = append(rows, markup.Row(markup.Data(p.T("button.skip"), b.reminderBtns.skip.Unique)))
+	markup.Inline(rows...)
+
+	msg, err := b.notifyMessage(chatID, "reminder", text+p.T("reminder.pick"), markup)
+	if err != nil {
+		return err
+	}
+	b.reminders.add(chatID, msg.ID, reminder{
+		BudgetKey:     budgetKey(t),
+		TransactionID: tx.ID,
+		Suggestions:   suggestions,
+		SentAt:        time.Now(),
+	})
+
+	return nil
+}
+
+// reminderCategoryHandler sets the picked category to the transaction of the reminder if it is still uncategorized.
+func (b *Bot) reminderCategoryHandler(c tb.Context) error {
+	t, p := tenantFrom(c), b.printerFrom(c)
+	b.log.Infow("reminder category handler", "chatID", c.Chat().ID, "tenantID", t.ID, "data", c.Data())
+
+	if c.Message() == nil {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("reminder.expired")})
+	}
+	rem, ok := b.reminders.take(c.Chat().ID, c.Message().ID)
+	i, err := strconv.Atoi(c.Data())
+	if !ok || rem.BudgetKey != budgetKey(t) || err != nil || i < 0 || i >= len(rem.Suggestions) {
+		return c.Respond(&tb.CallbackResponse{Text: p.T("reminder.expired")})
+	}
+	s := rem.Suggestions[i]
+
+	changed, err := b.categorize(t, rem.TransactionID, s.CategoryID)
+	if err != nil {
+		b.log.Errorw("failed to categorize transaction", "tenantID", t.ID, "transactionID", rem.TransactionID,
+			"error", err)
+		b.reminders.add(c.Chat().ID, c.Message().ID, rem)
+		return c.Respond(&tb.CallbackResponse{Text: b.ynabErrorMessage(p, err)})
+	}
+	if !changed {
+		return b.resolveQuestion(c, p.T("reminder.changed"))
+	}
+	b.log.Infow("transaction categorized", "chatID", c.Chat().ID, "tenantID", t.ID,
+		"transactionID", rem.TransactionID, "categoryID", s.CategoryID)
+
+	return b.resolveQuestion(c, p.T("reminder.categorized", s.CategoryName))
+}
+
+func (b *Bot) reminderSkipHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("reminder skip handler", "chatID", c.Chat().ID)
+
+	if c.Message() != nil {
+		b.reminders.take(c.Chat().ID, c.Message().ID)
+	}
+	return b.resolveQuestion(c, p.T("reminder.skipped"))
+}
+
+// categorize sets category of the transaction. It reports false without changing anything if the transaction
+// is deleted or has category already.
+func (b *Bot) categorize(t *Tenant, transactionID, categoryID string) (bool, error) {
+	ctx, cancelFunc := b.requestContext()
+	defer cancelFunc()
+
+	tx, err := t.Client.GetTransaction(ctx, t.BudgetID, transactionID)
+	if errors.Is(err, ynab.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting transaction: %w", err)
+	}
+	if tx == nil || tx.Deleted || tx.CategoryID != "" {
+		return false, nil
+	}
+
+	_, err = t.Client.UpdateTransactionFields(ctx, t.BudgetID, tx.ID, ynab.TransactionUpdate{CategoryID: categoryID})
+	b.forgetResolver(t)
+	if err != nil {
+		return false, fmt.Errorf("updating transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// remindUncategorized asks chats of the tenant to pick categories of new uncategorized transactions.
+func (b *Bot) remindUncategorized(t *Tenant, chatIDs []int64, txs []ynab.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+
+	var suggester *suggest.Suggester
+	if r, err := b.expenseResolver(t); err != nil {
+		b.log.Warnw("failed to load suggestions", "tenantID", t.ID, "error", err)
+	} else {
+		suggester = r.Suggester
+	}
+	for _, tx := range txs {
+		var suggestions []suggest.Suggestion
+		if suggester != nil {
+			suggestions = suggester.Suggest(tx.PayeeName, tx.Memo)
+		}
+		for _, chatID := range chatIDs {
+			if err := b.remind(chatID, t, tx, suggestions); err != nil {
+				b.log.Errorw("failed to send reminder", "chatID", chatID, "tenantID", t.ID, "error", err)
+			}
+		}
+	}
+}
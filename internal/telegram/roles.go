@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+)
+
+// Role defines what user can do with the bot. Every role includes permissions of the previous ones.
+type Role int
+
+const (
+	// RoleNone can't use the bot.
+	RoleNone Role = iota
+	// RoleViewer can only see statistic.
+	RoleViewer
+	// RoleMember can also create and change transactions.
+	RoleMember
+	// RoleAdmin can also change settings and manage access.
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleNone:
+		return "none"
+	case RoleViewer:
+		return "viewer"
+	case RoleMember:
+		return "member"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "none":
+		return RoleNone, nil
+	case "viewer":
+		return RoleViewer, nil
+	case "member":
+		return RoleMember, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role %q", s)
+	}
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(text []byte) error {
+	role, err := ParseRole(string(text))
+	if err != nil {
+		return err
+	}
+	*r = role
+
+	return nil
+}
+
+// RoleManager resolves role of the user in the chat. Role is resolved by userID, so members of group chats
+// have their own permissions rather than permissions of the chat.
+type RoleManager interface {
+	Role(tenantID string, chatID, userID int64) Role
+	SetRole(tenantID string, id int64, role Role) error
+}
+
+// RoleMiddleware lets through only users having at least required role. It must run after TenantMiddleware.
+// Rejection message is translated with printer returned by printerOf.
+func RoleMiddleware(
+	roles RoleManager, required Role, printerOf func(c tb.Context) *i18n.Printer, log Logger,
+) func(next tb.HandlerFunc) tb.HandlerFunc {
+	return func(next tb.HandlerFunc) tb.HandlerFunc {
+		return func(c tb.Context) error {
+			if role := roleOf(roles, c); role < required {
+				log.Warnw("not enough permissions",
+					"chatID", c.Chat().ID, "userID", senderID(c), "role", role, "required", required,
+				)
+				msg := printerOf(c).T("access.forbidden")
+				if c.Callback() != nil {
+					return c.Respond(&tb.CallbackResponse{Text: msg})
+				}
+				if err := c.Send(msg); err != nil {
+					log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+				}
+
+				return nil
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (b *Bot) require(role Role) tb.MiddlewareFunc {
+	return RoleMiddleware(b.roles, role, b.printerFrom, b.log)
+}
+
+func (b *Bot) roleHandler(c tb.Context) error {
+	p := b.printerFrom(c)
+	b.log.Infow("role handler", "chatID", c.Chat().ID, "args", c.Args())
+
+	usage := p.T("role.usage")
+	args := c.Args()
+	if len(args) != 2 { //nolint: gomnd // id and role
+		return b.sendWithErrorLogging(c, usage)
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return b.sendWithErrorLogging(c, usage)
+	}
+	role, err := ParseRole(args[1])
+	if err != nil {
+		return b.sendWithErrorLogging(c, usage)
+	}
+
+	t := tenantFrom(c)
+	if err = b.roles.SetRole(t.ID, id, role); err != nil {
+		b.log.Errorw("failed to set role", "tenantID", t.ID, "id", id, "role", role, "error", err)
+		return b.sendWithErrorLogging(c, p.T("error.unexpected"))
+	}
+
+	return b.sendWithErrorLogging(c, p.T("role.set", id, role))
+}
+
+func roleOf(roles RoleManager, c tb.Context) Role {
+	t := tenantFrom(c)
+	if t == nil {
+		return RoleNone
+	}
+
+	return roles.Role(t.ID, c.Chat().ID, senderID(c))
+}
+
+// senderID returns ID of the user who sent the update or chat ID if sender is unknown (e.g. channel posts).
+func senderID(c tb.Context) int64 {
+	if s := c.Sender(); s != nil {
+		return s.ID
+	}
+
+	return c.Chat().ID
+}
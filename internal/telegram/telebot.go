@@ -6,9 +6,20 @@ import (
 	tb "gopkg.in/telebot.v3"
 )
 
-func NewTelebot(token string) (*tb.Bot, error) {
+// NewTelebot creates bot receiving updates with long polling or with webhook if webhook config is provided.
+// Bot is synchronous as Bot.Start requires.
+func NewTelebot(token string, webhook *WebhookConfig, log Logger) (*tb.Bot, error) {
+	var poller tb.Poller = &tb.LongPoller{Timeout: 60 * time.Second} //nolint: gomnd // 60 seconds
+	if webhook != nil {
+		var err error
+		if poller, err = NewWebhookPoller(*webhook, log); err != nil {
+			return nil, err
+		}
+	}
+
 	return tb.NewBot(tb.Settings{
-		Token:  token,
-		Poller: &tb.LongPoller{Timeout: 60 * time.Second}, //nolint: gomnd // 60 seconds
+		Token:       token,
+		Poller:      poller,
+		Synchronous: true,
 	})
 }
@@ -0,0 +1,574 @@
+// Package telegramtest provides a fake Telegram Bot API server for end-to-end tests of the bot.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+const (
+	// Token is the bot token accepted by Server.
+	Token = "test-token"
+
+	maxPollWait  = 100 * time.Millisecond
+	maxMemory    = 10 << 20
+	waitTimeout  = 5 * time.Second
+	waitInterval = 10 * time.Millisecond
+)
+
+// Call is a Bot API method called by the bot.
+type Call struct {
+	Method string
+	Params map[string]string
+	// Files are names of uploaded files by their fields, e.g. "photo".
+	Files map[string]string
+}
+
+// Message is a message sent by the bot.
+type Message struct {
+	ID          int
+	ChatID      int64
+	Text        string
+	ParseMode   string
+	ReplyMarkup *tb.ReplyMarkup
+	// Photo is the name of uploaded photo, Text is its caption then.
+	Photo  string
+	Edited bool
+	Pinned bool
+}
+
+// Server is a fake Telegram Bot API. Tests inject updates with SendText and Press,
+// the bot receives them with long polling and its replies are recorded as messages.
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	updates       []tb.Update
+	nextUpdateID  int
+	nextMessageID int
+	nextCallback  int
+	arrived       chan struct{}
+	messages      []*Message
+	calls         []Call
+	failures      map[string]apiError
+}
+
+type apiError struct {
+	Code        int
+	Description string
+}
+
+// NewServer starts fake Bot API which is closed when test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		arrived:  make(chan struct{}),
+		failures: make(map[string]apiError),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// NewBot creates bot connected to the server. Updates are received with long polling.
+func (s *Server) NewBot() (*tb.Bot, error) {
+	bot, err := tb.NewBot(tb.Settings{
+		URL:         s.server.URL,
+		Token:       Token,
+		Poller:      &tb.LongPoller{Timeout: time.Second},
+		Synchronous: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating bot: %w", err)
+	}
+
+	return bot, nil
+}
+
+// URL returns base URL of the server to be used as tb.Settings.URL.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Fail makes calls of the method fail with Bot API error, e.g. 403 "Forbidden: bot was blocked by the user".
+func (s *Server) Fail(method string, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = apiError{Code: code, Description: description}
+}
+
+// SendText injects text message sent by the user to private chat with the bot. Chat ID is the user ID.
+func (s *Server) SendText(chatID int64, text string) {
+	s.SendTextFrom(chatID, chatID, text)
+}
+
+// SendTextFrom injects text message sent by the user to the chat. Chat is a group if its ID is not the user ID.
+func (s *Server) SendTextFrom(chatID, userID int64, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMessageID++
+	msg := &tb.Message{
+		ID:       s.nextMessageID,
+		Sender:   &tb.User{ID: userID, FirstName: "User"},
+		Chat:     chatOf(chatID, userID),
+		Unixtime: time.Now().Unix(),
+		Text:     text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command := strings.Fields(text)[0]
+		msg.Entities = tb.Entities{{Type: tb.EntityCommand, Offset: 0, Length: len(command)}}
+	}
+	s.push(tb.Update{Message: msg})
+}
+
+// SendPhoto injects photo with the caption sent by the user to private chat with the bot.
+// Content of the photo file is PhotoContent(fileID).
+func (s *Server) SendPhoto(chatID int64, fileID, caption string) {
+	s.SendPhotoFrom(chatID, chatID, fileID, caption)
+}
+
+// SendPhotoFrom injects photo with the caption sent by the user to the chat like SendTextFrom does.
+func (s *Server) SendPhotoFrom(chatID, userID int64, fileID, caption string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMessageID++
+	s.push(tb.Update{Message: &tb.Message{
+		ID:       s.nextMessageID,
+		Sender:   &tb.User{ID: userID, FirstName: "User"},
+		Chat:     chatOf(chatID, userID),
+		Unixtime: time.Now().Unix(),
+		Photo:    &tb.Photo{File: tb.File{FileID: fileID, UniqueID: fileID}, Width: 1, Height: 1},
+		Caption:  caption,
+	}})
+}
+
+// chatOf returns private chat of the user or group chat.
+func chatOf(chatID, userID int64) *tb.Chat {
+	if chatID == userID {
+		return &tb.Chat{ID: chatID, Type: tb.ChatPrivate, FirstName: "User"}
+	}
+	return &tb.Chat{ID: chatID, Type: tb.ChatGroup, Title: "Group"}
+}
+
+// PhotoContent returns content of the photo file sent with SendPhoto.
+func PhotoContent(fileID string) string {
+	return "photo " + fileID
+}
+
+// Press injects tap on the button of the message by the user of private chat. Inline buttons send callback query,
+// reply keyboard buttons send their text.
+func (s *Server) Press(msg Message, button string) error {
+	return s.PressFrom(msg, msg.ChatID, button)
+}
+
+// PressFrom injects tap on the button of the message by the user like Press does.
+// Chat is a group if its ID is not the user ID.
+func (s *Server) PressFrom(msg Message, userID int64, button string) error {
+	if msg.ReplyMarkup == nil {
+		return fmt.Errorf("message %d has no buttons", msg.ID)
+	}
+
+	for _, row := range msg.ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Text != button {
+				continue
+			}
+			s.mu.Lock()
+			s.nextCallback++
+			s.push(tb.Update{Callback: &tb.Callback{
+				ID:     strconv.Itoa(s.nextCallback),
+				Sender: &tb.User{ID: userID, FirstName: "User"},
+				Message: &tb.Message{
+					ID:          msg.ID,
+					Chat:        chatOf(msg.ChatID, userID),
+					Text:        plainText(msg),
+					ReplyMarkup: msg.ReplyMarkup,
+				},
+				Data: btn.Data,
+			}})
+			s.mu.Unlock()
+			return nil
+		}
+	}
+	for _, row := range msg.ReplyMarkup.ReplyKeyboard {
+		for _, btn := range row {
+			if btn.Text == button {
+				s.SendTextFrom(msg.ChatID, userID, button)
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("message %d has no button %q", msg.ID, button)
+}
+
+// Delete deletes message sent by the bot as users do, so it can't be edited anymore.
+func (s *Server) Delete(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, m := range s.messages {
+		if m.ChatID == chatID && m.ID == messageID {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return
+		}
+	}
+}
+
+// Messages returns messages sent to the chat in the order they were sent. Edited messages keep their place.
+func (s *Server) Messages(chatID int64) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Message
+	for _, m := range s.messages {
+		if m.ChatID == chatID {
+			res = append(res, *m)
+		}
+	}
+
+	return res
+}
+
+// WaitMessages waits until at least n messages are sent to the chat and returns all of them.
+func (s *Server) WaitMessages(t testing.TB, chatID int64, n int) []Message {
+	t.Helper()
+
+	var res []Message
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		if res = s.Messages(chatID); len(res) >= n {
+			return res
+		}
+		time.Sleep(waitInterval)
+	}
+	t.Fatalf("expected %d messages in chat %d, got %d: %+v", n, chatID, len(res), res)
+
+	return nil
+}
+
+// Calls returns calls of the method, or all calls if method is empty.
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Call
+	for _, c := range s.calls {
+		if method == "" || c.Method == method {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+// push adds update and wakes up pending getUpdates. It must be called with mu locked.
+func (s *Server) push(u tb.Update) {
+	s.nextUpdateID++
+	u.ID = s.nextUpdateID
+	s.updates = append(s.updates, u)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if filePath := strings.TrimPrefix(r.URL.Path, "/file/bot"+Token+"/photos/"); filePath != r.URL.Path {
+		_, _ = io.WriteString(w, PhotoContent(filePath))
+		return
+	}
+	prefix := "/bot" + Token + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeError(w, apiError{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, prefix)
+
+	call, err := parseCall(method, r)
+	if err != nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: " + err.Error()})
+		return
+	}
+
+	if method == "getUpdates" {
+		s.getUpdates(w, r, call)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+	if e, ok := s.failures[method]; ok {
+		writeError(w, e)
+		return
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, map[string]interface{}{"id": 1, "is_bot": true, "first_name": "Test", "username": "test_bot"})
+	case "sendMessage", "sendPhoto", "sendDocument":
+		s.send(w, call)
+	case "editMessageText", "editMessageCaption", "editMessageReplyMarkup":
+		s.edit(w, call)
+	case "getFile":
+		writeResult(w, map[string]interface{}{
+			"file_id":   call.Params["file_id"],
+			"file_path": "photos/" + call.Params["file_id"],
+		})
+	case "pinChatMessage", "unpinChatMessage":
+		s.pin(w, call, method == "pinChatMessage")
+	default:
+		writeResult(w, true)
+	}
+}
+
+// getUpdates returns updates not confirmed with offset yet, waiting for new ones up to timeout like long polling does.
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request, call Call) {
+	offset, _ := strconv.Atoi(call.Params["offset"])
+	timeout, _ := strconv.Atoi(call.Params["timeout"])
+	wait := time.Duration(timeout) * time.Second
+	if wait == 0 || wait > maxPollWait {
+		wait = maxPollWait
+	}
+
+	deadline := time.After(wait)
+	for {
+		s.mu.Lock()
+		// Updates before offset are confirmed by the bot.
+		for len(s.updates) > 0 && s.updates[0].ID < offset {
+			s.updates = s.updates[1:]
+		}
+		updates := append([]tb.Update(nil), s.updates...)
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		if len(updates) > 0 {
+			writeResult(w, updates)
+			return
+		}
+
+		select {
+		case <-arrived:
+		case <-deadline:
+			writeResult(w, []tb.Update{})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) send(w http.ResponseWriter, call Call) {
+	chatID, err := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+	if err != nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: chat not found"})
+		return
+	}
+
+	s.nextMessageID++
+	msg := &Message{
+		ID:        s.nextMessageID,
+		ChatID:    chatID,
+		Text:      call.Params["text"],
+		ParseMode: call.Params["parse_mode"],
+	}
+	if call.Method == "sendPhoto" {
+		msg.Text = call.Params["caption"]
+		msg.Photo = call.Files["photo"]
+		if msg.Photo == "" {
+			msg.Photo = call.Params["photo"]
+		}
+	}
+	if err = checkEntities(msg.Text, msg.ParseMode); err != nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: can't parse entities: " + err.Error()})
+		return
+	}
+	if msg.ReplyMarkup, err = parseMarkup(call.Params["reply_markup"]); err != nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: can't parse reply keyboard markup"})
+		return
+	}
+	s.messages = append(s.messages, msg)
+
+	writeResult(w, messageResult(msg))
+}
+
+func (s *Server) edit(w http.ResponseWriter, call Call) {
+	msg := s.message(call)
+	if msg == nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: message to edit not found"})
+		return
+	}
+
+	text, ok := call.Params["text"]
+	if call.Method == "editMessageCaption" {
+		text, ok = call.Params["caption"]
+	}
+	markup, err := parseMarkup(call.Params["reply_markup"])
+	if err != nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: can't parse reply keyboard markup"})
+		return
+	}
+	sameText := !ok || text == msg.Text && call.Params["parse_mode"] == msg.ParseMode
+	if sameText && reflect.DeepEqual(markup, msg.ReplyMarkup) {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: tb.ErrSameMessageContent.Description})
+		return
+	}
+	if ok {
+		if err = checkEntities(text, call.Params["parse_mode"]); err != nil {
+			writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: can't parse entities: " + err.Error()})
+			return
+		}
+		msg.Text, msg.ParseMode = text, call.Params["parse_mode"]
+	}
+	msg.ReplyMarkup = markup
+	msg.Edited = true
+
+	writeResult(w, messageResult(msg))
+}
+
+// plainText returns text of the message as Telegram sends it to bots, i.e. without HTML markup.
+func plainText(msg Message) string {
+	if msg.ParseMode != tb.ModeHTML {
+		return msg.Text
+	}
+
+	return strings.TrimSpace(telegram.StripHTML(msg.Text))
+}
+
+// checkEntities rejects messages with HTML Telegram can't parse. Other parse modes are not checked.
+func checkEntities(text, parseMode string) error {
+	if parseMode != tb.ModeHTML {
+		return nil
+	}
+
+	return telegram.ValidateHTML(text)
+}
+
+func (s *Server) pin(w http.ResponseWriter, call Call, pinned bool) {
+	msg := s.message(call)
+	if msg == nil {
+		writeError(w, apiError{Code: http.StatusBadRequest, Description: "Bad Request: message to pin not found"})
+		return
+	}
+	msg.Pinned = pinned
+
+	writeResult(w, true)
+}
+
+// message returns message by chat_id and message_id params. It must be called with mu locked.
+func (s *Server) message(call Call) *Message {
+	chatID, _ := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+	messageID, _ := strconv.Atoi(call.Params["message_id"])
+	for _, m := range s.messages {
+		if m.ChatID == chatID && m.ID == messageID {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// parseCall reads params of the call. Telebot sends them as JSON object of strings or as multipart form with files.
+func parseCall(method string, r *http.Request) (Call, error) {
+	call := Call{Method: method, Params: make(map[string]string), Files: make(map[string]string)}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return call, fmt.Errorf("parsing multipart form: %w", err)
+		}
+		for k, v := range r.MultipartForm.Value {
+			call.Params[k] = v[0]
+		}
+		for k, v := range r.MultipartForm.File {
+			call.Files[k] = v[0].Filename
+		}
+		return call, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return call, fmt.Errorf("reading body: %w", err)
+	}
+	var params map[string]interface{}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &params); err != nil {
+			return call, fmt.Errorf("decoding params: %w", err)
+		}
+	}
+	for k, v := range params {
+		if str, ok := v.(string); ok {
+			call.Params[k] = str
+			continue
+		}
+		encoded, _ := json.Marshal(v)
+		call.Params[k] = string(encoded)
+	}
+
+	return call, nil
+}
+
+func parseMarkup(data string) (*tb.ReplyMarkup, error) {
+	if data == "" {
+		return nil, nil //nolint: nilnil // message without markup
+	}
+
+	var markup tb.ReplyMarkup
+	if err := json.Unmarshal([]byte(data), &markup); err != nil {
+		return nil, fmt.Errorf("decoding reply markup: %w", err)
+	}
+
+	return &markup, nil
+}
+
+func messageResult(m *Message) map[string]interface{} {
+	res := map[string]interface{}{
+		"message_id": m.ID,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]interface{}{"id": m.ChatID, "type": tb.ChatPrivate},
+		"text":       m.Text,
+	}
+	if m.Photo != "" {
+		// Telegram returns caption and sizes of the photo, telebot fails without them.
+		delete(res, "text")
+		res["caption"] = m.Text
+		res["photo"] = []map[string]interface{}{{"file_id": m.Photo, "file_unique_id": m.Photo, "width": 1, "height": 1}}
+	}
+	if m.ReplyMarkup != nil {
+		res["reply_markup"] = m.ReplyMarkup
+	}
+
+	return res
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, e apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":          false,
+		"error_code":  e.Code,
+		"description": e.Description,
+	})
+}
@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+)
+
+const tenantContextKey = "tenant"
+
+// Tenant is a group of chats sharing the same YNAB access token and budget.
+type Tenant struct {
+	ID         string
+	BudgetID   string
+	CategoryID string
+	Client     YNABClient
+}
+
+// TenantChat is a chat linked to tenant.
+type TenantChat struct {
+	ID    int64
+	Title string
+	// Approved is true for chats which joined the tenant by access request.
+	Approved bool
+}
+
+type TenantResolver interface {
+	ByChat(chatID int64) (*Tenant, bool)
+	// Tenants returns all tenants.
+	Tenants() []*Tenant
+}
+
+// TenantMiddleware resolves the tenant the chat belongs to and stores it in the context.
+// Chats that are not linked to any tenant are rejected with message translated by printer returned by printerOf.
+// Markup returned by rejectMarkup is attached to rejection message if rejectMarkup is not nil.
+func TenantMiddleware(
+	tenants TenantResolver,
+	printerOf func(c tb.Context) *i18n.Printer,
+	rejectMarkup func(p *i18n.Printer) *tb.ReplyMarkup,
+	log Logger,
+) func(next tb.HandlerFunc) tb.HandlerFunc {
+	return func(next tb.HandlerFunc) tb.HandlerFunc {
+		return func(c tb.Context) error {
+			t, ok := tenants.ByChat(c.Chat().ID)
+			if !ok {
+				log.Warnw("chat is not allowed", "chatID", c.Chat().ID)
+				p := printerOf(c)
+				opts := make([]interface{}, 0, 1)
+				if rejectMarkup != nil {
+					opts = append(opts, rejectMarkup(p))
+				}
+				if err := c.Send(p.T("access.not_allowed"), opts...); err != nil {
+					log.Errorw("failed to send message", "chatID", c.Chat().ID, "error", err)
+				}
+
+				return nil
+			}
+
+			c.Set(tenantContextKey, t)
+			return next(c)
+		}
+	}
+}
+
+func tenantFrom(c tb.Context) *Tenant {
+	t, _ := c.Get(tenantContextKey).(*Tenant)
+	return t
+}
+
+// budgetKey identifies budget of the tenant in state kept by budget.
+func budgetKey(t *Tenant) string {
+	return t.ID + "/" + t.BudgetID
+}
@@ -0,0 +1,168 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token" //nolint: gosec // header name, not a credential
+	secretTokenBytes  = 32
+	webhookTimeout    = 10 * time.Second
+	webhookRetryDelay = 30 * time.Second
+)
+
+// WebhookConfig configures receiving updates with webhook instead of long polling.
+type WebhookConfig struct {
+	// Listen is the address of embedded HTTP server, e.g. ":8443".
+	Listen string
+	// PublicURL is the URL Telegram sends updates to. Only its path is served by the embedded server.
+	PublicURL string
+	// SecretToken is sent by Telegram in X-Telegram-Bot-Api-Secret-Token header. Random one is generated if empty.
+	SecretToken string
+	// TLSCert and TLSKey are paths to certificate files. Plain HTTP is served if empty,
+	// which requires TLS termination in front of the bot.
+	TLSCert string
+	TLSKey  string
+}
+
+// WebhookPoller is a tb.Poller which registers webhook on start and serves updates over HTTP(S).
+// Webhook is removed by Bot.Stop with Remove.
+type WebhookPoller struct {
+	cfg  WebhookConfig
+	path string
+	log  Logger
+}
+
+func NewWebhookPoller(cfg WebhookConfig, log Logger) (*WebhookPoller, error) {
+	u, err := url.Parse(cfg.PublicURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing webhook public url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook public url must be https, got %q", cfg.PublicURL)
+	}
+
+	if cfg.SecretToken == "" {
+		b := make([]byte, secretTokenBytes)
+		if _, err = rand.Read(b); err != nil {
+			return nil, fmt.Errorf("generating webhook secret token: %w", err)
+		}
+		cfg.SecretToken = hex.EncodeToString(b)
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return &WebhookPoller{cfg: cfg, path: path, log: log}, nil
+}
+
+func (p *WebhookPoller) Poll(b *tb.Bot, dest chan tb.Update, stop chan struct{}) {
+	if !p.setWebhook(b, stop) {
+		return
+	}
+
+	server := &http.Server{
+		Addr:              p.cfg.Listen,
+		Handler:           p.Handler(dest),
+		ReadHeaderTimeout: webhookTimeout,
+	}
+	go func() {
+		var serveErr error
+		if p.cfg.TLSCert != "" {
+			serveErr = server.ListenAndServeTLS(p.cfg.TLSCert, p.cfg.TLSKey)
+		} else {
+			serveErr = server.ListenAndServe()
+		}
+		if !errors.Is(serveErr, http.ErrServerClosed) {
+			p.log.Errorw("webhook server failed", "addr", p.cfg.Listen, "error", serveErr)
+		}
+	}()
+
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		p.log.Errorw("failed to shutdown webhook server", "error", err)
+	}
+}
+
+// Remove deletes webhook, so Telegram keeps updates until the bot is started again.
+// It is called by Bot.Stop once handlers are finished.
+func (p *WebhookPoller) Remove(b *tb.Bot) error {
+	if err := b.RemoveWebhook(); err != nil {
+		return fmt.Errorf("removing webhook: %w", err)
+	}
+	p.log.Infow("webhook is removed")
+
+	return nil
+}
+
+// setWebhook registers webhook retrying until it succeeds. It returns false if poller was stopped before that.
+func (p *WebhookPoller) setWebhook(b *tb.Bot, stop chan struct{}) bool {
+	for {
+		err := b.SetWebhook(&tb.Webhook{
+			SecretToken: p.cfg.SecretToken,
+			Endpoint:    &tb.WebhookEndpoint{PublicURL: p.cfg.PublicURL},
+		})
+		if err == nil {
+			p.log.Infow("webhook is set", "url", p.cfg.PublicURL)
+			return true
+		}
+		p.log.Errorw("failed to set webhook", "url", p.cfg.PublicURL, "error", err)
+
+		select {
+		case <-stop:
+			return false
+		case <-time.After(webhookRetryDelay):
+		}
+	}
+}
+
+// Handler returns HTTP handler which verifies secret token and passes updates to dest.
+func (p *WebhookPoller) Handler(dest chan<- tb.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != p.path {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(p.cfg.SecretToken)) != 1 {
+			p.log.Warnw("invalid webhook secret token", "remoteAddr", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var update tb.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			p.log.Warnw("failed to decode update", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case dest <- update:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
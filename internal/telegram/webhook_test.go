@@ -0,0 +1,51 @@
+package telegram_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+func TestWebhookPoller_Handler(t *testing.T) {
+	p, err := telegram.NewWebhookPoller(telegram.WebhookConfig{
+		PublicURL:   "https://bot.example.com/telegram/updates",
+		SecretToken: "secret",
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	dest := make(chan tb.Update, 1)
+	h := p.Handler(dest)
+
+	send := func(path, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, send("/other", "secret", `{"update_id": 1}`))
+	assert.Equal(t, http.StatusUnauthorized, send("/telegram/updates", "", `{"update_id": 1}`))
+	assert.Equal(t, http.StatusUnauthorized, send("/telegram/updates", "wrong", `{"update_id": 1}`))
+	assert.Equal(t, http.StatusBadRequest, send("/telegram/updates", "secret", `not json`))
+	assert.Empty(t, dest)
+
+	assert.Equal(t, http.StatusOK, send("/telegram/updates", "secret", `{"update_id": 7}`))
+	require.Len(t, dest, 1)
+	assert.Equal(t, 7, (<-dest).ID)
+}
+
+func TestNewWebhookPoller(t *testing.T) {
+	_, err := telegram.NewWebhookPoller(telegram.WebhookConfig{PublicURL: "http://bot.example.com"}, zap.NewNop().Sugar())
+	assert.Error(t, err, "telegram requires https")
+}
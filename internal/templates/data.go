@@ -0,0 +1,142 @@
+package templates
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+const (
+	barFilled = "▓"
+	barEmpty  = "░"
+)
+
+// Data is the dot of templates. Amounts are in YNAB milliunits, use money helper to print them.
+type Data struct {
+	Lang   i18n.Lang
+	ChatID int64
+	// Command is the command or button the message is sent for without leading slash, e.g. "state".
+	Command string
+	// Category is the name of the watched category escaped for Telegram HTML.
+	Category string
+
+	Budgeted int
+	// Activity is negative when money is spent.
+	Activity int
+	Balance  int
+	// Spent is the amount spent this month, i.e. -Activity.
+	Spent int
+	// AvgSpent is the amount spent per day so far.
+	AvgSpent int
+	// AvgSpentLeft is the amount which can be spent per day till the end of the month.
+	AvgSpentLeft int
+	// DaysLeft includes today.
+	DaysLeft int
+}
+
+func newData(msg telegram.StatisticMessage) Data {
+	s := msg.Statistic
+	return Data{
+		Lang:         msg.Printer.Lang(),
+		ChatID:       msg.ChatID,
+		Command:      msg.Command,
+		Category:     telegram.EscapeHTML(msg.Category),
+		Budgeted:     s.Budgeted,
+		Activity:     s.Activity,
+		Balance:      s.Balance,
+		Spent:        -s.Activity,
+		AvgSpent:     s.AvgSpent,
+		AvgSpentLeft: s.AvgSpentLeft,
+		DaysLeft:     s.DaysLeft,
+	}
+}
+
+// sampleMessage is rendered by every template on load to validate it.
+func sampleMessage(p *i18n.Printer) telegram.StatisticMessage {
+	return telegram.StatisticMessage{
+		ChatID:   1,
+		Command:  "state",
+		Category: "Groceries",
+		Printer:  p,
+		Statistic: budget.GeneralCategoryStatistic{
+			Budgeted:     10000000,
+			Activity:     -4200000,
+			Balance:      5800000,
+			AvgSpent:     300000,
+			AvgSpentLeft: 341176,
+			DaysLeft:     17,
+		},
+	}
+}
+
+// funcs returns helpers available in templates. They print values in the language of p escaped for Telegram HTML.
+//
+//	money 1234560                   - "1,234.56", amount in milliunits
+//	number 1234                     - "1,234"
+//	percent .Spent .Budgeted        - "42%", 0% if total is 0
+//	pluralize .DaysLeft "день" "дні" "днів" - form for the number by CLDR rules of the language,
+//	                                  forms are one, few, many, other; the last one is used for missing forms
+//	plural "days" .DaysLeft         - "17 days", plural message from the catalog
+//	bar .Spent .Budgeted 10         - "▓▓▓▓░░░░░░", progress bar of the width
+//	t "statistic.balance"           - message from the catalog
+//	escape "<text>"                 - "&lt;text&gt;"
+func funcs(p *i18n.Printer) template.FuncMap {
+	return template.FuncMap{
+		"money":  p.Money,
+		"number": p.Number,
+		"percent": func(part, total int) string {
+			return strconv.Itoa(percent(part, total)) + "%"
+		},
+		"pluralize": func(n int, forms ...string) string {
+			if len(forms) == 0 {
+				return ""
+			}
+			i := int(i18n.PluralFormOf(p.Lang(), n))
+			// PluralForm values are ordered other, one, few, many while forms are one, few, many, other.
+			if i == int(i18n.FormOther) {
+				i = len(forms)
+			}
+			return telegram.EscapeHTML(forms[min(i, len(forms))-1])
+		},
+		"plural": p.N,
+		"bar": func(value, total, width int) string {
+			filled := 0
+			if total > 0 {
+				filled = int(math.Round(float64(value) / float64(total) * float64(width)))
+			}
+			filled = max(0, min(filled, width))
+			return strings.Repeat(barFilled, filled) + strings.Repeat(barEmpty, width-filled)
+		},
+		"t": func(key string, args ...interface{}) string {
+			return telegram.EscapeHTML(p.T(key, args...))
+		},
+		"escape": telegram.EscapeHTML,
+	}
+}
+
+func percent(part, total int) int {
+	if total == 0 {
+		return 0
+	}
+
+	return int(math.Round(float64(part) / float64(total) * 100)) //nolint: gomnd // percent
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
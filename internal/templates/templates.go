@@ -0,0 +1,221 @@
+// Package templates formats statistic messages with templates operators put into a directory.
+//
+// Templates are text/template files with .tmpl extension producing Telegram HTML.
+// Template for a message is looked up by chat and command:
+//
+//	chats/<chat id>/<command>.tmpl
+//	chats/<chat id>/default.tmpl
+//	<command>.tmpl
+//	default.tmpl
+//
+// Built-in formatter is used if none of them exists. See Data for the dot of templates and funcs for helpers.
+package templates
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+const (
+	// DefaultReloadInterval is how often directory is checked for changed templates.
+	DefaultReloadInterval = 5 * time.Second
+
+	ext         = ".tmpl"
+	defaultName = "default"
+)
+
+type Logger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Set holds templates loaded from a directory.
+type Set struct {
+	dir     string
+	catalog *i18n.Catalog
+	log     Logger
+
+	mu          sync.RWMutex
+	templates   map[string]*template.Template
+	fingerprint string
+}
+
+// Load loads templates from dir and validates them by rendering against sample data in every language.
+// Rendered messages must be valid Telegram HTML.
+func Load(dir string, catalog *i18n.Catalog, log Logger) (*Set, error) {
+	s := &Set{dir: dir, catalog: catalog, log: log}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload loads templates again. Previously loaded templates are kept if any template is invalid.
+func (s *Set) Reload() error {
+	fingerprint, err := s.scan()
+	if err != nil {
+		return err
+	}
+
+	templates := make(map[string]*template.Template)
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ext {
+			return err
+		}
+
+		name, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return fmt.Errorf("resolving name of %s: %w", path, err)
+		}
+		name = filepath.ToSlash(strings.TrimSuffix(name, ext))
+		if templates[name], err = s.parse(name, path); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("loading templates from %s: %w", s.dir, err)
+	}
+
+	s.mu.Lock()
+	s.templates, s.fingerprint = templates, fingerprint
+	s.mu.Unlock()
+	s.log.Infow("templates are loaded", "dir", s.dir, "count", len(templates))
+
+	return nil
+}
+
+func (s *Set) parse(name, path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	// Helpers are replaced with ones printing in the chat language on every render.
+	t, err := template.New(name).Option("missingkey=error").Funcs(funcs(s.catalog.Printer(""))).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, lang := range s.catalog.Languages() {
+		if _, err = render(t, sampleMessage(s.catalog.Printer(lang))); err != nil {
+			return nil, fmt.Errorf("validating %s in %s: %w", path, lang, err)
+		}
+	}
+
+	return t, nil
+}
+
+// scan returns fingerprint of template files which changes when any of them is added, removed or modified.
+func (s *Set) scan() (string, error) {
+	var sb strings.Builder
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ext {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("reading info of %s: %w", path, err)
+		}
+		sb.WriteString(fmt.Sprintf("%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("scanning templates in %s: %w", s.dir, err)
+	}
+
+	return sb.String(), nil
+}
+
+// Watch reloads templates when files in the directory change until ctx is done.
+// Invalid templates are logged and previous ones are kept.
+func (s *Set) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := s.scan()
+		if err != nil {
+			s.log.Errorw("failed to scan templates", "dir", s.dir, "error", err)
+			continue
+		}
+		s.mu.RLock()
+		changed := fingerprint != s.fingerprint
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err = s.Reload(); err != nil {
+			s.log.Errorw("failed to reload templates, previous ones are kept", "dir", s.dir, "error", err)
+			s.mu.Lock()
+			s.fingerprint = fingerprint // don't retry until files change again
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Formatter returns formatter rendering template of the chat and command. fallback formats messages without template.
+func (s *Set) Formatter(fallback telegram.StatisticMessageFormatter) telegram.StatisticMessageFormatter {
+	return func(msg telegram.StatisticMessage) (string, error) {
+		t, ok := s.lookup(msg.ChatID, msg.Command)
+		if !ok {
+			return fallback(msg)
+		}
+
+		return render(t, msg)
+	}
+}
+
+func (s *Set) lookup(chatID int64, command string) (*template.Template, bool) {
+	chat := "chats/" + strconv.FormatInt(chatID, 10) + "/"
+	names := []string{chat + command, chat + defaultName, command, defaultName}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range names {
+		if t, ok := s.templates[name]; ok {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+func render(t *template.Template, msg telegram.StatisticMessage) (string, error) {
+	clone, err := t.Clone()
+	if err != nil {
+		return "", fmt.Errorf("cloning template %s: %w", t.Name(), err)
+	}
+
+	var buf bytes.Buffer
+	if err = clone.Funcs(funcs(msg.Printer)).Execute(&buf, newData(msg)); err != nil {
+		return "", fmt.Errorf("executing template %s: %w", t.Name(), err)
+	}
+	if strings.TrimSpace(buf.String()) == "" {
+		return "", fmt.Errorf("template %s renders empty message", t.Name())
+	}
+	if err = telegram.ValidateHTML(buf.String()); err != nil {
+		return "", fmt.Errorf("template %s renders invalid html: %w", t.Name(), err)
+	}
+
+	return buf.String(), nil
+}
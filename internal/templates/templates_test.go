@@ -0,0 +1,143 @@
+package templates_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/budget"
+	"github.com/Roma7-7-7/ynab-notifier/internal/i18n"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/internal/templates"
+)
+
+func writeTemplate(t *testing.T, dir, name, text string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+}
+
+func message(chatID int64, command string, lang i18n.Lang) telegram.StatisticMessage {
+	return telegram.StatisticMessage{
+		ChatID:   chatID,
+		Command:  command,
+		Category: "Food & drinks",
+		Printer:  i18n.NewCatalog().Printer(lang),
+		Statistic: budget.GeneralCategoryStatistic{
+			Budgeted:     1000000,
+			Activity:     -420000,
+			Balance:      580000,
+			AvgSpent:     30000,
+			AvgSpentLeft: 34117,
+			DaysLeft:     21,
+		},
+	}
+}
+
+func fallback(telegram.StatisticMessage) (string, error) {
+	return "fallback", nil
+}
+
+func TestSet_Formatter(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "state.tmpl", "{{.Category}}: {{money .Balance}}")
+	writeTemplate(t, dir, "chats/100/default.tmpl", "chat {{.ChatID}} {{.Command}}")
+	writeTemplate(t, dir, "chats/100/start.tmpl", "welcome")
+	writeTemplate(t, dir, "notes.txt", "not a template")
+
+	set, err := templates.Load(dir, i18n.NewCatalog(), zap.NewNop().Sugar())
+	require.NoError(t, err)
+	format := set.Formatter(fallback)
+
+	tests := []struct {
+		chatID  int64
+		command string
+		want    string
+	}{
+		{chatID: 100, command: "start", want: "welcome"},
+		{chatID: 100, command: "state", want: "chat 100 state"},
+		{chatID: 200, command: "state", want: "Food &amp; drinks: 580.00"},
+		{chatID: 200, command: "start", want: "fallback"},
+	}
+	for _, tt := range tests {
+		got, err := format(message(tt.chatID, tt.command, i18n.English))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%d %s", tt.chatID, tt.command)
+	}
+}
+
+func TestSet_Helpers(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "default.tmpl",
+		`{{t "statistic.balance"}}: {{money .Balance}} | {{percent .Spent .Budgeted}} {{bar .Spent .Budgeted 10}} | `+
+			`{{.DaysLeft}} {{pluralize .DaysLeft "день" "дні" "днів"}} | {{plural "days" 5}} | {{number 12345}}`)
+
+	set, err := templates.Load(dir, i18n.NewCatalog(), zap.NewNop().Sugar())
+	require.NoError(t, err)
+	format := set.Formatter(fallback)
+
+	got, err := format(message(1, "state", i18n.Ukrainian))
+	require.NoError(t, err)
+	assert.Equal(t, "Залишок: 580,00 | 42% ▓▓▓▓░░░░░░ | 21 день | 5 днів | 12\u00a0345", got)
+
+	got, err = format(message(1, "state", i18n.English))
+	require.NoError(t, err)
+	assert.Equal(t, "Balance: 580.00 | 42% ▓▓▓▓░░░░░░ | 21 днів | 5 days | 12,345", got,
+		"forms are picked by English rules")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax":        "{{.Balance",
+		"unknown_field": "{{.Missing}}",
+		"unknown_func":  "{{unknown .Balance}}",
+		"empty":         "{{if false}}never{{end}}",
+		"invalid_html":  "<b>{{.Balance}}",
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, "state.tmpl", text)
+
+			_, err := templates.Load(dir, i18n.NewCatalog(), zap.NewNop().Sugar())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSet_Watch(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "state.tmpl", "v1")
+
+	set, err := templates.Load(dir, i18n.NewCatalog(), zap.NewNop().Sugar())
+	require.NoError(t, err)
+	format := set.Formatter(fallback)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go set.Watch(ctx, 10*time.Millisecond)
+
+	render := func() string {
+		got, err := format(message(1, "state", i18n.English))
+		require.NoError(t, err)
+		return got
+	}
+
+	writeTemplate(t, dir, "state.tmpl", "version 2")
+	assert.Eventually(t, func() bool { return render() == "version 2" }, 5*time.Second, 10*time.Millisecond)
+
+	writeTemplate(t, dir, "state.tmpl", "{{.Broken")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "version 2", render(), "invalid template is not loaded")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "state.tmpl")))
+	assert.Eventually(t, func() bool { return render() == "fallback" }, 5*time.Second, 10*time.Millisecond)
+}
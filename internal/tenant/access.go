@@ -0,0 +1,196 @@
+package tenant
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+// ApprovedChat is a chat that joined tenant after admin approval.
+type ApprovedChat struct {
+	TenantID string `json:"tenant_id"`
+	Title    string `json:"title"`
+}
+
+// Access manages chats that join tenants by requesting access from admins.
+// Approved chats join the tenant of the chat where request was approved.
+type Access struct {
+	registry *Registry
+	approved *store.JSONFile[map[int64]ApprovedChat]
+	admins   map[int64]struct{}
+
+	mu      sync.Mutex
+	pending map[int64]string
+}
+
+// NewAccess creates access manager and links previously approved chats to their tenants.
+func NewAccess(registry *Registry, approved *store.JSONFile[map[int64]ApprovedChat], adminChatIDs []int64) (*Access, error) {
+	admins := make(map[int64]struct{}, len(adminChatIDs))
+	for _, chatID := range adminChatIDs {
+		admins[chatID] = struct{}{}
+	}
+
+	var err error
+	approved.View(func(chats map[int64]ApprovedChat) {
+		for chatID, c := range chats {
+			if err = registry.LinkChat(c.TenantID, chatID); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("restoring approved chats: %w", err)
+	}
+
+	return &Access{
+		registry: registry,
+		approved: approved,
+		admins:   admins,
+		pending:  make(map[int64]string),
+	}, nil
+}
+
+// Admins returns IDs of chats which approve access requests.
+func (a *Access) Admins() []int64 {
+	res := make([]int64, 0, len(a.admins))
+	for chatID := range a.admins {
+		res = append(res, chatID)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+// Request registers access request of the chat. It returns false if request is already pending.
+func (a *Access) Request(chatID int64, title string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.pending[chatID]; ok {
+		return false
+	}
+	a.pending[chatID] = title
+
+	return true
+}
+
+// Approve links requesting chat to the tenant of the admin chat.
+func (a *Access) Approve(adminChatID, chatID int64) error {
+	t, err := a.adminTenant(adminChatID)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	title, ok := a.pending[chatID]
+	delete(a.pending, chatID)
+	a.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending request from chat %d", chatID)
+	}
+
+	err = a.approved.Update(func(chats *map[int64]ApprovedChat) error {
+		if *chats == nil {
+			*chats = make(map[int64]ApprovedChat)
+		}
+		(*chats)[chatID] = ApprovedChat{TenantID: t.ID, Title: title}
+		return nil
+	})
+	if err != nil {
+		a.restoreRequest(chatID, title)
+		return fmt.Errorf("approving chat %d: %w", chatID, err)
+	}
+
+	// Chat is linked only once approval is persisted, so it doesn't stay linked in memory only.
+	if err = a.registry.LinkChat(t.ID, chatID); err != nil {
+		if rollbackErr := a.approved.Update(func(chats *map[int64]ApprovedChat) error {
+			delete(*chats, chatID)
+			return nil
+		}); rollbackErr != nil {
+			return fmt.Errorf("linking chat %d: %w (rolling back approval: %v)", chatID, err, rollbackErr)
+		}
+		a.restoreRequest(chatID, title)
+		return fmt.Errorf("linking chat %d: %w", chatID, err)
+	}
+
+	return nil
+}
+
+// restoreRequest makes request which failed to be approved pending again unless the chat requested access again.
+func (a *Access) restoreRequest(chatID int64, title string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.pending[chatID]; !ok {
+		a.pending[chatID] = title
+	}
+}
+
+// Deny drops pending access request.
+func (a *Access) Deny(_, chatID int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.pending[chatID]; !ok {
+		return fmt.Errorf("no pending request from chat %d", chatID)
+	}
+	delete(a.pending, chatID)
+
+	return nil
+}
+
+// Revoke removes approved chat from the tenant of the admin chat.
+// Chats configured statically can't be revoked.
+func (a *Access) Revoke(adminChatID, chatID int64) error {
+	t, err := a.adminTenant(adminChatID)
+	if err != nil {
+		return err
+	}
+
+	err = a.approved.Update(func(chats *map[int64]ApprovedChat) error {
+		c, ok := (*chats)[chatID]
+		if !ok || c.TenantID != t.ID {
+			return fmt.Errorf("chat %d was not approved for tenant %q", chatID, t.ID)
+		}
+		delete(*chats, chatID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.registry.UnlinkChat(chatID)
+
+	return nil
+}
+
+// Chats returns all chats of the admin's tenant. Approved chats are returned with their titles.
+func (a *Access) Chats(adminChatID int64) ([]telegram.TenantChat, error) {
+	t, err := a.adminTenant(adminChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	chatIDs := a.registry.Chats(t.ID)
+	res := make([]telegram.TenantChat, 0, len(chatIDs))
+	a.approved.View(func(chats map[int64]ApprovedChat) {
+		for _, chatID := range chatIDs {
+			c, ok := chats[chatID]
+			res = append(res, telegram.TenantChat{ID: chatID, Title: c.Title, Approved: ok})
+		}
+	})
+
+	return res, nil
+}
+
+// adminTenant returns tenant of the admin chat. Permissions are checked by the bot.
+func (a *Access) adminTenant(adminChatID int64) (*telegram.Tenant, error) {
+	t, ok := a.registry.ByChat(adminChatID)
+	if !ok {
+		return nil, fmt.Errorf("admin chat %d is not linked to any tenant", adminChatID)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+// Alerts keeps sensitivity of anomaly alerts of chats which turned them on.
+type Alerts struct {
+	store *store.JSONFile[map[int64]anomaly.Sensitivity]
+}
+
+func NewAlerts(s *store.JSONFile[map[int64]anomaly.Sensitivity]) *Alerts {
+	return &Alerts{store: s}
+}
+
+// Alerts returns sensitivity of alerts by IDs of chats.
+func (a *Alerts) Alerts() map[int64]anomaly.Sensitivity {
+	res := make(map[int64]anomaly.Sensitivity)
+	a.store.View(func(alerts map[int64]anomaly.Sensitivity) {
+		for chatID, s := range alerts {
+			res[chatID] = s
+		}
+	})
+
+	return res
+}
+
+func (a *Alerts) SetAlerts(chatID int64, s anomaly.Sensitivity) error {
+	return a.store.Update(func(alerts *map[int64]anomaly.Sensitivity) error {
+		if *alerts == nil {
+			*alerts = make(map[int64]anomaly.Sensitivity)
+		}
+		(*alerts)[chatID] = s
+		return nil
+	})
+}
+
+func (a *Alerts) RemoveAlerts(chatID int64) error {
+	return a.store.Update(func(alerts *map[int64]anomaly.Sensitivity) error {
+		delete(*alerts, chatID)
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+type Config struct {
+	Tenants []TenantConfig `json:"tenants"`
+}
+
+type TenantConfig struct {
+	ID         string  `json:"id"`
+	YNABToken  string  `json:"ynab_token"`
+	BudgetID   string  `json:"budget_id"`
+	CategoryID string  `json:"category_id"`
+	ChatIDs    []int64 `json:"chat_ids"`
+	// DefaultRole is used for users without explicit role. Member is used if not set.
+	DefaultRole *telegram.Role `json:"default_role,omitempty"`
+	// Roles maps Telegram user or chat IDs to their roles.
+	Roles map[int64]telegram.Role `json:"roles,omitempty"`
+}
+
+// LoadConfig reads tenants configuration from JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tenants config: %w", err)
+	}
+
+	var res Config
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("parsing tenants config: %w", err)
+	}
+
+	if err = res.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Validate checks that tenants have token, well-formed budget and category IDs and chats, and unique IDs.
+func (c *Config) Validate() error {
+	if len(c.Tenants) == 0 {
+		return fmt.Errorf("no tenants configured")
+	}
+
+	ids := make(map[string]struct{}, len(c.Tenants))
+	for _, t := range c.Tenants {
+		if err := t.validate(); err != nil {
+			return err
+		}
+		if _, ok := ids[t.ID]; ok {
+			return fmt.Errorf("duplicate tenant id %q", t.ID)
+		}
+		ids[t.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+func (c TenantConfig) validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("tenant id is empty")
+	}
+	if c.YNABToken == "" {
+		return fmt.Errorf("tenant %q: ynab_token is empty", c.ID)
+	}
+	if c.BudgetID == "" {
+		return fmt.Errorf("tenant %q: budget_id is empty", c.ID)
+	}
+	if !isYNABID(c.BudgetID) {
+		return fmt.Errorf("tenant %q: budget_id %q is malformed", c.ID, c.BudgetID)
+	}
+	if c.CategoryID == "" {
+		return fmt.Errorf("tenant %q: category_id is empty", c.ID)
+	}
+	if !isYNABID(c.CategoryID) {
+		return fmt.Errorf("tenant %q: category_id %q is malformed", c.ID, c.CategoryID)
+	}
+	if len(c.ChatIDs) == 0 {
+		return fmt.Errorf("tenant %q: chat_ids is empty", c.ID)
+	}
+
+	return nil
+}
+
+// isYNABID reports whether id looks like ID of YNAB budget or category. They are UUIDs, budget ID may also be
+// "last-used". IDs are put into URL paths, so anything else is a typo.
+func isYNABID(id string) bool {
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+
+	return id != ""
+}
@@ -0,0 +1,43 @@
+package tenant
+
+import (
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+// Dashboards keeps IDs of pinned dashboard messages of chats.
+type Dashboards struct {
+	store *store.JSONFile[map[int64]int]
+}
+
+func NewDashboards(s *store.JSONFile[map[int64]int]) *Dashboards {
+	return &Dashboards{store: s}
+}
+
+// Dashboards returns IDs of dashboard messages by IDs of their chats.
+func (d *Dashboards) Dashboards() map[int64]int {
+	res := make(map[int64]int)
+	d.store.View(func(messages map[int64]int) {
+		for chatID, messageID := range messages {
+			res[chatID] = messageID
+		}
+	})
+
+	return res
+}
+
+func (d *Dashboards) SetDashboard(chatID int64, messageID int) error {
+	return d.store.Update(func(messages *map[int64]int) error {
+		if *messages == nil {
+			*messages = make(map[int64]int)
+		}
+		(*messages)[chatID] = messageID
+		return nil
+	})
+}
+
+func (d *Dashboards) RemoveDashboard(chatID int64) error {
+	return d.store.Update(func(messages *map[int64]int) error {
+		delete(*messages, chatID)
+		return nil
+	})
+}
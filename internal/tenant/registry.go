@@ -0,0 +1,153 @@
+package tenant
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+// ClientFactory creates YNAB client of the tenant for the given access token.
+// Every tenant gets its own client, so rate limits are not shared between tenants.
+type ClientFactory func(tenantID, token string) telegram.YNABClient
+
+type Registry struct {
+	mu      sync.RWMutex
+	tenants map[string]*telegram.Tenant
+	chats   map[int64]*telegram.Tenant
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		tenants: make(map[string]*telegram.Tenant),
+		chats:   make(map[int64]*telegram.Tenant),
+	}
+}
+
+// NewRegistryFromConfig creates registry with all tenants from config.
+func NewRegistryFromConfig(cfg *Config, newClient ClientFactory) (*Registry, error) {
+	r := NewRegistry()
+	for _, tc := range cfg.Tenants {
+		t := &telegram.Tenant{
+			ID:         tc.ID,
+			BudgetID:   tc.BudgetID,
+			CategoryID: tc.CategoryID,
+			Client:     newClient(tc.ID, tc.YNABToken),
+		}
+		if err := r.Add(t, tc.ChatIDs...); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Add registers tenant and links given chats to it.
+func (r *Registry) Add(t *telegram.Tenant, chatIDs ...int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[t.ID]; ok {
+		return fmt.Errorf("tenant %q already exists", t.ID)
+	}
+	for _, chatID := range chatIDs {
+		if other, ok := r.chats[chatID]; ok {
+			return fmt.Errorf("chat %d is already linked to tenant %q", chatID, other.ID)
+		}
+	}
+
+	r.tenants[t.ID] = t
+	for _, chatID := range chatIDs {
+		r.chats[chatID] = t
+	}
+
+	return nil
+}
+
+// Set registers tenant replacing the existing one with the same ID along with its chats.
+func (r *Registry) Set(t *telegram.Tenant, chatIDs ...int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, chatID := range chatIDs {
+		if other, ok := r.chats[chatID]; ok && other.ID != t.ID {
+			return fmt.Errorf("chat %d is already linked to tenant %q", chatID, other.ID)
+		}
+	}
+
+	for chatID, other := range r.chats {
+		if other.ID == t.ID {
+			delete(r.chats, chatID)
+		}
+	}
+	r.tenants[t.ID] = t
+	for _, chatID := range chatIDs {
+		r.chats[chatID] = t
+	}
+
+	return nil
+}
+
+func (r *Registry) ByChat(chatID int64) (*telegram.Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.chats[chatID]
+	return t, ok
+}
+
+// Tenants returns all tenants sorted by ID.
+func (r *Registry) Tenants() []*telegram.Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]*telegram.Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+
+	return res
+}
+
+// LinkChat links chat to existing tenant.
+func (r *Registry) LinkChat(tenantID string, chatID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[tenantID]
+	if !ok {
+		return fmt.Errorf("tenant %q not found", tenantID)
+	}
+	if other, ok := r.chats[chatID]; ok && other.ID != tenantID {
+		return fmt.Errorf("chat %d is already linked to tenant %q", chatID, other.ID)
+	}
+	r.chats[chatID] = t
+
+	return nil
+}
+
+// UnlinkChat removes chat from its tenant.
+func (r *Registry) UnlinkChat(chatID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.chats, chatID)
+}
+
+// Chats returns IDs of all chats linked to tenant.
+func (r *Registry) Chats(tenantID string) []int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]int64, 0)
+	for chatID, t := range r.chats {
+		if t.ID == tenantID {
+			res = append(res, chatID)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
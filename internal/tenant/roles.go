@@ -0,0 +1,99 @@
+package tenant
+
+import (
+	"sync"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+)
+
+// Roles resolves roles from tenants config and roles assigned with /role command.
+//
+// Lookup order is: admin users, owner of the tenant, assigned role of the user, configured role of the user,
+// tenant default role. Chat ID is the user ID in private chats only, so roles of chats and admin chats apply
+// there and never to members of group chats. Tenants missing in config (e.g. created by /link) are owned by
+// the user who linked them, everyone else is a viewer there by default.
+type Roles struct {
+	admins     map[int64]struct{}
+	defaults   map[string]telegram.Role
+	configured map[string]map[int64]telegram.Role
+	assigned   *store.JSONFile[map[string]map[int64]telegram.Role]
+
+	mu     sync.RWMutex
+	owners map[string]int64
+}
+
+func NewRoles(cfg *Config, adminChatIDs []int64, assigned *store.JSONFile[map[string]map[int64]telegram.Role]) *Roles {
+	r := &Roles{
+		admins:     make(map[int64]struct{}, len(adminChatIDs)),
+		defaults:   make(map[string]telegram.Role),
+		configured: make(map[string]map[int64]telegram.Role),
+		assigned:   assigned,
+		owners:     make(map[string]int64),
+	}
+	for _, chatID := range adminChatIDs {
+		r.admins[chatID] = struct{}{}
+	}
+	if cfg != nil {
+		for _, t := range cfg.Tenants {
+			r.defaults[t.ID] = telegram.RoleMember
+			if t.DefaultRole != nil {
+				r.defaults[t.ID] = *t.DefaultRole
+			}
+			r.configured[t.ID] = t.Roles
+		}
+	}
+
+	return r
+}
+
+// SetOwner makes the user admin of the tenant missing in config.
+func (r *Roles) SetOwner(tenantID string, userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.owners[tenantID] = userID
+}
+
+func (r *Roles) Role(tenantID string, _, userID int64) telegram.Role {
+	if _, ok := r.admins[userID]; ok {
+		return telegram.RoleAdmin
+	}
+	r.mu.RLock()
+	owner, owned := r.owners[tenantID]
+	r.mu.RUnlock()
+	if owned && owner == userID {
+		return telegram.RoleAdmin
+	}
+
+	res, found := telegram.RoleNone, false
+	r.assigned.View(func(v map[string]map[int64]telegram.Role) {
+		if res, found = v[tenantID][userID]; found {
+			return
+		}
+		res, found = r.configured[tenantID][userID]
+	})
+	if found {
+		return res
+	}
+
+	if role, ok := r.defaults[tenantID]; ok {
+		return role
+	}
+
+	return telegram.RoleViewer
+}
+
+// SetRole assigns role to the user or chat within tenant.
+func (r *Roles) SetRole(tenantID string, id int64, role telegram.Role) error {
+	return r.assigned.Update(func(v *map[string]map[int64]telegram.Role) error {
+		if *v == nil {
+			*v = make(map[string]map[int64]telegram.Role)
+		}
+		if (*v)[tenantID] == nil {
+			(*v)[tenantID] = make(map[int64]telegram.Role)
+		}
+		(*v)[tenantID][id] = role
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package tenant
+
+import (
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+)
+
+// SeenTransactions keeps dates of transactions checked by alerts by their IDs and keys of budgets.
+type SeenTransactions struct {
+	store *store.JSONFile[map[string]map[string]string]
+}
+
+func NewSeenTransactions(s *store.JSONFile[map[string]map[string]string]) *SeenTransactions {
+	return &SeenTransactions{store: s}
+}
+
+// Seen returns dates of checked transactions of the budget by their IDs and false if the budget was never checked.
+func (s *SeenTransactions) Seen(budgetKey string) (map[string]string, bool) {
+	var (
+		res map[string]string
+		ok  bool
+	)
+	s.store.View(func(seen map[string]map[string]string) {
+		var txs map[string]string
+		txs, ok = seen[budgetKey]
+		res = make(map[string]string, len(txs))
+		for id, date := range txs {
+			res[id] = date
+		}
+	})
+
+	return res, ok
+}
+
+func (s *SeenTransactions) SetSeen(budgetKey string, txs map[string]string) error {
+	return s.store.Update(func(seen *map[string]map[string]string) error {
+		if *seen == nil {
+			*seen = make(map[string]map[string]string)
+		}
+		if txs == nil {
+			txs = make(map[string]string)
+		}
+		(*seen)[budgetKey] = txs
+		return nil
+	})
+}
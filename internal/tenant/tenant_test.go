@@ -0,0 +1,295 @@
+package tenant_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Roma7-7-7/ynab-notifier/internal/anomaly"
+	"github.com/Roma7-7-7/ynab-notifier/internal/store"
+	"github.com/Roma7-7-7/ynab-notifier/internal/telegram"
+	"github.com/Roma7-7-7/ynab-notifier/internal/tenant"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *tenant.Config
+		wantErr string
+	}{
+		{
+			name: "ok",
+			content: `{"tenants": [
+				{"id": "a", "ynab_token": "ta", "budget_id": "ba", "category_id": "ca", "chat_ids": [1, 2]},
+				{"id": "b", "ynab_token": "tb", "budget_id": "bb", "category_id": "cb", "chat_ids": [3],
+				 "default_role": "viewer", "roles": {"100": "admin"}}
+			]}`,
+			want: &tenant.Config{
+				Tenants: []tenant.TenantConfig{
+					{ID: "a", YNABToken: "ta", BudgetID: "ba", CategoryID: "ca", ChatIDs: []int64{1, 2}},
+					{
+						ID: "b", YNABToken: "tb", BudgetID: "bb", CategoryID: "cb", ChatIDs: []int64{3},
+						DefaultRole: rolePtr(telegram.RoleViewer), Roles: map[int64]telegram.Role{100: telegram.RoleAdmin},
+					},
+				},
+			},
+		},
+		{
+			name:    "empty",
+			content: `{"tenants": []}`,
+			wantErr: "no tenants configured",
+		},
+		{
+			name: "duplicate",
+			content: `{"tenants": [
+				{"id": "a", "ynab_token": "ta", "budget_id": "ba", "category_id": "ca", "chat_ids": [1]},
+				{"id": "a", "ynab_token": "tb", "budget_id": "bb", "category_id": "cb", "chat_ids": [3]}
+			]}`,
+			wantErr: `duplicate tenant id "a"`,
+		},
+		{
+			name:    "missing_token",
+			content: `{"tenants": [{"id": "a", "budget_id": "ba", "category_id": "ca", "chat_ids": [1]}]}`,
+			wantErr: `tenant "a": ynab_token is empty`,
+		},
+		{
+			name: "malformed_budget",
+			content: `{"tenants": [
+				{"id": "a", "ynab_token": "ta", "budget_id": " ba", "category_id": "ca", "chat_ids": [1]}
+			]}`,
+			wantErr: `tenant "a": budget_id " ba" is malformed`,
+		},
+		{
+			name: "malformed_category",
+			content: `{"tenants": [
+				{"id": "a", "ynab_token": "ta", "budget_id": "ba", "category_id": "ca/cb", "chat_ids": [1]}
+			]}`,
+			wantErr: `tenant "a": category_id "ca/cb" is malformed`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tenants.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := tenant.LoadConfig(path)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func rolePtr(r telegram.Role) *telegram.Role {
+	return &r
+}
+
+func TestRegistry(t *testing.T) {
+	cfg := &tenant.Config{
+		Tenants: []tenant.TenantConfig{
+			{ID: "a", YNABToken: "ta", BudgetID: "ba", CategoryID: "ca", ChatIDs: []int64{1, 2}},
+			{ID: "b", YNABToken: "tb", BudgetID: "bb", CategoryID: "cb", ChatIDs: []int64{3}},
+		},
+	}
+	tokens := make(map[string]string)
+	r, err := tenant.NewRegistryFromConfig(cfg, func(tenantID, token string) telegram.YNABClient {
+		tokens[tenantID] = token
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "ta", "b": "tb"}, tokens)
+
+	got, ok := r.ByChat(2)
+	require.True(t, ok)
+	assert.Equal(t, "a", got.ID)
+	got, ok = r.ByChat(3)
+	require.True(t, ok)
+	assert.Equal(t, "bb", got.BudgetID)
+	_, ok = r.ByChat(4)
+	assert.False(t, ok)
+
+	err = r.Add(&telegram.Tenant{ID: "c"}, 5, 1)
+	assert.EqualError(t, err, `chat 1 is already linked to tenant "a"`)
+	_, ok = r.ByChat(5)
+	assert.False(t, ok)
+
+	tenants := r.Tenants()
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "a", tenants[0].ID)
+	assert.Equal(t, "b", tenants[1].ID)
+}
+
+func TestAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approved.json")
+	newAccess := func() (*tenant.Registry, *tenant.Access) {
+		r := tenant.NewRegistry()
+		require.NoError(t, r.Add(&telegram.Tenant{ID: "a"}, 1))
+		require.NoError(t, r.Add(&telegram.Tenant{ID: "b"}, 2))
+		approved, err := store.OpenJSONFile[map[int64]tenant.ApprovedChat](path)
+		require.NoError(t, err)
+		a, err := tenant.NewAccess(r, approved, []int64{1})
+		require.NoError(t, err)
+		return r, a
+	}
+
+	r, a := newAccess()
+	assert.Equal(t, []int64{1}, a.Admins())
+
+	assert.Error(t, a.Approve(1, 10), "no pending request")
+	assert.True(t, a.Request(10, "Family"))
+	assert.False(t, a.Request(10, "Family"))
+	assert.Error(t, a.Approve(3, 10), "chat without tenant")
+	assert.False(t, a.Request(10, "Family"), "request is still pending")
+	require.NoError(t, a.Approve(1, 10))
+
+	got, ok := r.ByChat(10)
+	require.True(t, ok)
+	assert.Equal(t, "a", got.ID)
+
+	chats, err := a.Chats(1)
+	require.NoError(t, err)
+	assert.Equal(t, []telegram.TenantChat{{ID: 1}, {ID: 10, Title: "Family", Approved: true}}, chats)
+
+	r, a = newAccess()
+	_, ok = r.ByChat(10)
+	assert.True(t, ok, "approved chat is restored")
+
+	assert.Error(t, a.Revoke(1, 1), "configured chat can't be revoked")
+	require.NoError(t, a.Revoke(1, 10))
+	_, ok = r.ByChat(10)
+	assert.False(t, ok)
+
+	assert.True(t, a.Request(11, "Other"))
+	require.NoError(t, a.Deny(1, 11))
+	assert.Error(t, a.Approve(1, 11))
+
+	assert.True(t, a.Request(2, "Other tenant"))
+	assert.Error(t, a.Approve(1, 2), "chat of another tenant")
+	assert.False(t, a.Request(2, "Other tenant"), "request is pending after failed approval")
+	chats, err = a.Chats(1)
+	require.NoError(t, err)
+	assert.Equal(t, []telegram.TenantChat{{ID: 1}}, chats)
+	r, _ = newAccess()
+	got, ok = r.ByChat(2)
+	require.True(t, ok)
+	assert.Equal(t, "b", got.ID, "failed approval is not persisted")
+}
+
+func TestAccess_ApproveWriteFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approved.json")
+	r := tenant.NewRegistry()
+	require.NoError(t, r.Add(&telegram.Tenant{ID: "a"}, 1))
+	approved, err := store.OpenJSONFile[map[int64]tenant.ApprovedChat](path)
+	require.NoError(t, err)
+	a, err := tenant.NewAccess(r, approved, []int64{1})
+	require.NoError(t, err)
+
+	// Temporary file can't be written while a directory takes its name.
+	require.NoError(t, os.Mkdir(path+".tmp", 0o700))
+	assert.True(t, a.Request(10, "Family"))
+	assert.Error(t, a.Approve(1, 10))
+	_, ok := r.ByChat(10)
+	assert.False(t, ok)
+
+	require.NoError(t, os.Remove(path+".tmp"))
+	require.NoError(t, a.Approve(1, 10), "request can be approved again")
+	_, ok = r.ByChat(10)
+	assert.True(t, ok)
+}
+
+func TestRoles(t *testing.T) {
+	viewer, member := telegram.RoleViewer, telegram.RoleMember
+	cfg := &tenant.Config{
+		Tenants: []tenant.TenantConfig{
+			{ID: "a", ChatIDs: []int64{1, 2}, Roles: map[int64]telegram.Role{100: telegram.RoleAdmin, 2: viewer}},
+			{ID: "b", ChatIDs: []int64{3}, DefaultRole: &viewer, Roles: map[int64]telegram.Role{101: member}},
+		},
+	}
+	assigned, err := store.OpenJSONFile[map[string]map[int64]telegram.Role](filepath.Join(t.TempDir(), "roles.json"))
+	require.NoError(t, err)
+	r := tenant.NewRoles(cfg, []int64{9, -50}, assigned)
+
+	assert.Equal(t, telegram.RoleMember, r.Role("a", 1, 200), "default role")
+	assert.Equal(t, telegram.RoleAdmin, r.Role("a", 1, 100), "user role")
+	assert.Equal(t, telegram.RoleViewer, r.Role("a", 2, 2), "chat role in private chat")
+	assert.Equal(t, telegram.RoleMember, r.Role("a", 2, 200), "chat role is not inherited by group members")
+	assert.Equal(t, telegram.RoleAdmin, r.Role("a", 2, 100))
+	assert.Equal(t, telegram.RoleViewer, r.Role("b", 3, 200), "configured default role")
+	assert.Equal(t, telegram.RoleMember, r.Role("b", 3, 101))
+	assert.Equal(t, telegram.RoleAdmin, r.Role("b", 9, 9), "admin chat")
+	assert.Equal(t, telegram.RoleAdmin, r.Role("b", -50, 9), "admin user in group chat")
+	assert.Equal(t, telegram.RoleViewer, r.Role("b", -50, 200), "members of admin group chat are not admins")
+
+	r.SetOwner("chat:-5", 5)
+	assert.Equal(t, telegram.RoleAdmin, r.Role("chat:-5", -5, 5), "linked tenant is owned by the user who linked it")
+	assert.Equal(t, telegram.RoleViewer, r.Role("chat:-5", -5, 6), "other users of linked tenant are viewers")
+
+	require.NoError(t, r.SetRole("a", 100, telegram.RoleViewer))
+	require.NoError(t, r.SetRole("a", 200, telegram.RoleNone))
+	assert.Equal(t, telegram.RoleViewer, r.Role("a", 1, 100), "assigned role wins over configured")
+	assert.Equal(t, telegram.RoleNone, r.Role("a", 1, 200))
+	assert.Equal(t, telegram.RoleMember, r.Role("b", 3, 101), "roles are isolated by tenant")
+}
+
+func TestDashboards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboards.json")
+	messages, err := store.OpenJSONFile[map[int64]int](path)
+	require.NoError(t, err)
+	d := tenant.NewDashboards(messages)
+	assert.Empty(t, d.Dashboards())
+
+	require.NoError(t, d.SetDashboard(100, 1))
+	require.NoError(t, d.SetDashboard(200, 2))
+	require.NoError(t, d.RemoveDashboard(200))
+
+	messages, err = store.OpenJSONFile[map[int64]int](path)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{100: 1}, tenant.NewDashboards(messages).Dashboards(), "dashboards are persisted")
+}
+
+func TestAlerts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	alerts, err := store.OpenJSONFile[map[int64]anomaly.Sensitivity](path)
+	require.NoError(t, err)
+	a := tenant.NewAlerts(alerts)
+	assert.Empty(t, a.Alerts())
+
+	require.NoError(t, a.SetAlerts(100, anomaly.High))
+	require.NoError(t, a.SetAlerts(200, anomaly.Low))
+	require.NoError(t, a.RemoveAlerts(200))
+
+	alerts, err = store.OpenJSONFile[map[int64]anomaly.Sensitivity](path)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]anomaly.Sensitivity{100: anomaly.High}, tenant.NewAlerts(alerts).Alerts(),
+		"alerts are persisted")
+}
+
+func TestSeenTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen_transactions.json")
+	seen, err := store.OpenJSONFile[map[string]map[string]string](path)
+	require.NoError(t, err)
+	s := tenant.NewSeenTransactions(seen)
+	txs, ok := s.Seen("tenant/budget")
+	assert.False(t, ok)
+	assert.Empty(t, txs)
+
+	require.NoError(t, s.SetSeen("tenant/budget", map[string]string{"tx-1": "2024-05-07"}))
+	require.NoError(t, s.SetSeen("tenant/empty", nil))
+
+	seen, err = store.OpenJSONFile[map[string]map[string]string](path)
+	require.NoError(t, err)
+	s = tenant.NewSeenTransactions(seen)
+	txs, ok = s.Seen("tenant/budget")
+	assert.True(t, ok, "seen transactions are persisted")
+	assert.Equal(t, map[string]string{"tx-1": "2024-05-07"}, txs)
+	txs, ok = s.Seen("tenant/empty")
+	assert.True(t, ok, "budget without transactions is checked")
+	assert.Empty(t, txs)
+}
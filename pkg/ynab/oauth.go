@@ -0,0 +1,162 @@
+package ynab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOAuthURL is the base URL of YNAB OAuth authorization server.
+const DefaultOAuthURL = "https://app.ynab.com"
+
+// tokenExpiryDelta is how long before actual expiry the token is considered expired.
+const tokenExpiryDelta = time.Minute
+
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+func (t OAuthToken) expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && now.Add(tokenExpiryDelta).After(t.Expiry)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// OAuth implements YNAB OAuth authorization code grant.
+type OAuth struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+	log          Logger
+}
+
+func NewOAuth(baseURL, clientID, clientSecret, redirectURL string, log Logger) *OAuth {
+	return &OAuth{
+		baseURL:      baseURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		client:       &http.Client{},
+		log:          log,
+	}
+}
+
+// AuthCodeURL returns URL of the page where user grants access to the budget.
+func (o *OAuth) AuthCodeURL(state string) string {
+	v := url.Values{}
+	v.Set("client_id", o.clientID)
+	v.Set("redirect_uri", o.redirectURL)
+	v.Set("response_type", "code")
+	v.Set("state", state)
+
+	return o.baseURL + "/oauth/authorize?" + v.Encode()
+}
+
+// Exchange converts authorization code into token.
+func (o *OAuth) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", o.redirectURL)
+
+	return o.token(ctx, v)
+}
+
+// Refresh obtains new token using refresh token.
+func (o *OAuth) Refresh(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	v := url.Values{}
+	v.Set("grant_type", "refresh_token")
+	v.Set("refresh_token", refreshToken)
+
+	return o.token(ctx, v)
+}
+
+func (o *OAuth) token(ctx context.Context, v url.Values) (*OAuthToken, error) {
+	v.Set("client_id", o.clientID)
+	v.Set("client_secret", o.clientSecret)
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, o.baseURL+"/oauth/token", strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		o.log.Errorw("can't do token request", "grantType", v.Get("grant_type"), "error", err)
+		return nil, fmt.Errorf("can't do request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		o.log.Warnw("unexpected token response status", "grantType", v.Get("grant_type"), "statusCode", resp.StatusCode)
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrUnauthorized
+		}
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var res tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("can't decode response: %w", err)
+	}
+
+	return &OAuthToken{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// RefreshingTokenSource is a TokenSource that refreshes OAuth token when it is about to expire.
+// onRefresh is called with every new token, so it can be persisted.
+type RefreshingTokenSource struct {
+	mu        sync.Mutex
+	oauth     *OAuth
+	token     OAuthToken
+	onRefresh func(OAuthToken)
+}
+
+func NewRefreshingTokenSource(oauth *OAuth, token OAuthToken, onRefresh func(OAuthToken)) *RefreshingTokenSource {
+	return &RefreshingTokenSource{
+		oauth:     oauth,
+		token:     token,
+		onRefresh: onRefresh,
+	}
+}
+
+func (s *RefreshingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.token.expired(time.Now()) {
+		return s.token.AccessToken, nil
+	}
+
+	token, err := s.oauth.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("refreshing token: %w", err)
+	}
+	s.token = *token
+	if s.onRefresh != nil {
+		s.onRefresh(*token)
+	}
+
+	return s.token.AccessToken, nil
+}
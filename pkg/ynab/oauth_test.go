@@ -0,0 +1,75 @@
+package ynab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestOAuth_AuthCodeURL(t *testing.T) {
+	o := ynab.NewOAuth("https://app.ynab.com", "id", "secret", "https://bot.example.com/oauth/callback", zap.NewNop().Sugar())
+
+	assert.Equal(t,
+		"https://app.ynab.com/oauth/authorize?client_id=id&redirect_uri=https%3A%2F%2Fbot.example.com%2Foauth%2Fcallback&response_type=code&state=xyz",
+		o.AuthCodeURL("xyz"),
+	)
+}
+
+func TestOAuth_ExchangeAndRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.URL.Path != "/oauth/token" || r.Form.Get("client_id") != "id" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "code" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token": "access1", "refresh_token": "refresh1", "expires_in": 0}`))
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token": "access2", "refresh_token": "refresh2", "expires_in": 7200}`))
+		}
+	}))
+	defer server.Close()
+
+	o := ynab.NewOAuth(server.URL, "id", "secret", "https://bot.example.com/oauth/callback", zap.NewNop().Sugar())
+
+	_, err := o.Exchange(context.Background(), "wrong")
+	assert.ErrorIs(t, err, ynab.ErrUnauthorized)
+
+	token, err := o.Exchange(context.Background(), "code")
+	require.NoError(t, err)
+	assert.Equal(t, "access1", token.AccessToken)
+	assert.Equal(t, "refresh1", token.RefreshToken)
+
+	var refreshed ynab.OAuthToken
+	ts := ynab.NewRefreshingTokenSource(o, *token, func(t ynab.OAuthToken) {
+		refreshed = t
+	})
+
+	got, err := ts.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access2", got)
+	assert.Equal(t, "refresh2", refreshed.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), refreshed.Expiry, time.Minute)
+
+	got, err = ts.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access2", got)
+}
@@ -0,0 +1,64 @@
+package ynab
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRateLimit is the number of requests YNAB allows per access token within DefaultRateLimitWindow.
+const DefaultRateLimit = 200
+
+// DefaultRateLimitWindow is the rolling window YNAB applies DefaultRateLimit to.
+const DefaultRateLimitWindow = time.Hour
+
+// RateLimiter is a rolling window limiter that mirrors YNAB's per-token request budget,
+// so a single noisy token can't exhaust the API quota before YNAB starts responding with 429.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	calls  []time.Time
+	now    func() time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		calls:  make([]time.Time, 0, limit),
+		now:    time.Now,
+	}
+}
+
+// Allow reserves a request slot if there is one left in the current window.
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evict(now)
+	if len(l.calls) >= l.limit {
+		return false
+	}
+	l.calls = append(l.calls, now)
+
+	return true
+}
+
+// Remaining returns how many requests can still be made in the current window.
+func (l *RateLimiter) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evict(l.now())
+	return l.limit - len(l.calls)
+}
+
+func (l *RateLimiter) evict(now time.Time) {
+	threshold := now.Add(-l.window)
+	i := 0
+	for i < len(l.calls) && !l.calls[i].After(threshold) {
+		i++
+	}
+	l.calls = l.calls[i:]
+}
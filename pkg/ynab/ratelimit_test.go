@@ -0,0 +1,26 @@
+package ynab_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := ynab.NewRateLimiter(2, 50*time.Millisecond)
+
+	assert.Equal(t, 2, l.Remaining())
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+	assert.Equal(t, 0, l.Remaining())
+
+	time.Sleep(60 * time.Millisecond)
+
+	assert.Equal(t, 2, l.Remaining())
+	assert.True(t, l.Allow())
+	assert.Equal(t, 1, l.Remaining())
+}
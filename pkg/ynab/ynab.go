@@ -1,19 +1,39 @@
 package ynab
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 )
 
 var (
 	ErrNotFound     = fmt.Errorf("not found")
 	ErrUnauthorized = fmt.Errorf("unauthorized")
 	ErrForbidden    = fmt.Errorf("forbidden")
+	ErrRateLimited  = fmt.Errorf("rate limited")
+	ErrConflict     = fmt.Errorf("conflict")
 )
 
-const getCategoryURL = "%s/v1/budgets/%s/categories/%s"
+const (
+	getCategoryURL     = "%s/v1/budgets/%s/categories/%s"
+	getCategoriesURL   = "%s/v1/budgets/%s/categories"
+	getCategoriesDelta = "%s/v1/budgets/%s/categories?last_knowledge_of_server=%d"
+	monthCategoryURL   = "%s/v1/budgets/%s/months/%s/categories/%s"
+	getCategoryTxsURL  = "%s/v1/budgets/%s/categories/%s/transactions?since_date=%s"
+	getTransactionsURL = "%s/v1/budgets/%s/transactions?since_date=%s"
+	getTxsDeltaURL     = "%s/v1/budgets/%s/transactions?since_date=%s&last_knowledge_of_server=%d"
+	transactionsURL    = "%s/v1/budgets/%s/transactions"
+	transactionURL     = "%s/v1/budgets/%s/transactions/%s"
+	getAccountsURL     = "%s/v1/budgets/%s/accounts"
+
+	monthLayout = "2006-01-02"
+	// currentMonth is resolved by YNAB to the current month in the time zone of the budget.
+	currentMonth = "current"
+)
 
 type Logger interface {
 	Debugw(msg string, keysAndValues ...interface{})
@@ -21,90 +41,513 @@ type Logger interface {
 	Errorw(msg string, keysAndValues ...interface{})
 }
 
+// TokenSource provides access token for every request made by Client.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource for personal access tokens.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Observer is notified about every request made by Client.
+// statusCode is 0 if request wasn't sent (e.g. it was rate limited locally or failed on network level).
+type Observer interface {
+	ObserveRequest(endpoint string, statusCode int, duration time.Duration, err error)
+}
+
 type Category struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
+	Hidden   bool   `json:"hidden"`
+	Deleted  bool   `json:"deleted"`
 	Budgeted int    `json:"budgeted"`
 	Activity int    `json:"activity"`
 	Balance  int    `json:"balance"`
 }
 
+type CategoryGroup struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hidden     bool       `json:"hidden"`
+	Deleted    bool       `json:"deleted"`
+	Categories []Category `json:"categories"`
+}
+
+// Transaction amount is negative for outflows. Date is formatted as "2006-01-02".
+type Transaction struct {
+	ID           string `json:"id"`
+	Date         string `json:"date"`
+	Amount       int    `json:"amount"`
+	Memo         string `json:"memo"`
+	Cleared      string `json:"cleared"`
+	Approved     bool   `json:"approved"`
+	AccountID    string `json:"account_id"`
+	AccountName  string `json:"account_name"`
+	PayeeID      string `json:"payee_id"`
+	PayeeName    string `json:"payee_name"`
+	CategoryID   string `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	// ImportID is set for transactions imported from bank and empty for ones entered manually.
+	ImportID string `json:"import_id"`
+	// TransferAccountID is the account on the other side of transfer and empty for other transactions.
+	TransferAccountID string `json:"transfer_account_id"`
+	Deleted           bool   `json:"deleted"`
+}
+
+// IsTransfer reports whether transaction moves money between budget accounts.
+func (t Transaction) IsTransfer() bool {
+	return t.TransferAccountID != ""
+}
+
+// NewTransaction is a transaction to be created or saved over existing one. Amount is negative for outflows. Date is formatted as "2006-01-02".
+// Payee is created by YNAB if there is no payee with PayeeName.
+type NewTransaction struct {
+	AccountID  string `json:"account_id"`
+	Date       string `json:"date"`
+	Amount     int    `json:"amount"`
+	PayeeName  string `json:"payee_name,omitempty"`
+	CategoryID string `json:"category_id,omitempty"`
+	Memo       string `json:"memo,omitempty"`
+	Cleared    string `json:"cleared,omitempty"`
+	Approved   bool   `json:"approved"`
+	// ImportID makes creation idempotent: YNAB rejects transaction with ErrConflict if the account already has one
+	// with the same import ID. It can't be changed once transaction is created.
+	ImportID string `json:"import_id,omitempty"`
+}
+
+// TransactionUpdate lists fields to change in existing transaction. Empty fields are not sent, so YNAB keeps them.
+type TransactionUpdate struct {
+	CategoryID string `json:"category_id,omitempty"`
+	Memo       string `json:"memo,omitempty"`
+}
+
+type Account struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	OnBudget bool   `json:"on_budget"`
+	Closed   bool   `json:"closed"`
+	Deleted  bool   `json:"deleted"`
+}
+
 type categoryResponse struct {
 	Data struct {
 		Category Category `json:"category"`
 	} `json:"data"`
 }
 
+type categoriesResponse struct {
+	Data struct {
+		CategoryGroups  []CategoryGroup `json:"category_groups"`
+		ServerKnowledge int64           `json:"server_knowledge"`
+	} `json:"data"`
+}
+
+type transactionsResponse struct {
+	Data struct {
+		Transactions    []Transaction `json:"transactions"`
+		ServerKnowledge int64         `json:"server_knowledge"`
+	} `json:"data"`
+}
+
+type transactionResponse struct {
+	Data struct {
+		Transaction Transaction `json:"transaction"`
+	} `json:"data"`
+}
+
+type accountsResponse struct {
+	Data struct {
+		Accounts []Account `json:"accounts"`
+	} `json:"data"`
+}
+
 type Client struct {
-	baseULR string
-	token   string
-	client  *http.Client
-	log     Logger
+	baseULR   string
+	tokens    TokenSource
+	client    *http.Client
+	limiter   *RateLimiter
+	observers []Observer
+	log       Logger
 }
 
-func NewClient(baseURL, token string, log Logger) *Client {
+func NewClient(baseURL string, tokens TokenSource, log Logger) *Client {
 	return &Client{
 		baseULR: baseURL,
-		token:   token,
+		tokens:  tokens,
 		client:  &http.Client{},
+		limiter: NewRateLimiter(DefaultRateLimit, DefaultRateLimitWindow),
 		log:     log,
 	}
 }
 
+// WithObserver adds observer of requests made by the client.
+func (c *Client) WithObserver(o Observer) *Client {
+	c.observers = append(c.observers, o)
+	return c
+}
+
+// RateLimitRemaining returns how many requests the client can still make before hitting YNAB's rate limit.
+func (c *Client) RateLimitRemaining() int {
+	return c.limiter.Remaining()
+}
+
 func (c *Client) GetCategory(ctx context.Context, budgetID, categoryID string) (*Category, error) {
 	c.log.Debugw("getting categoryID", "budgetID", budgetID, "categoryID", categoryID)
 
-	req, err := http.NewRequestWithContext(
-		ctx, http.MethodGet, fmt.Sprintf(getCategoryURL, c.baseULR, budgetID, categoryID), nil)
+	var res categoryResponse
+	err := c.do(ctx, "GetCategory", http.MethodGet, fmt.Sprintf(getCategoryURL, c.baseULR, budgetID, categoryID),
+		nil, &res, "budgetID", budgetID, "categoryID", categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got category", "budgetID", budgetID, "categoryID", categoryID)
+	return &res.Data.Category, nil
+}
+
+// GetMonthCategory returns category with budgeted, activity and balance of the month the date belongs to.
+func (c *Client) GetMonthCategory(ctx context.Context, budgetID string, date time.Time, categoryID string) (*Category, error) {
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).Format(monthLayout)
+	return c.getMonthCategory(ctx, budgetID, month, categoryID)
+}
+
+// GetCurrentMonthCategory returns category with budgeted, activity and balance of the current month.
+func (c *Client) GetCurrentMonthCategory(ctx context.Context, budgetID, categoryID string) (*Category, error) {
+	return c.getMonthCategory(ctx, budgetID, currentMonth, categoryID)
+}
+
+func (c *Client) getMonthCategory(ctx context.Context, budgetID, month, categoryID string) (*Category, error) {
+	c.log.Debugw("getting month category", "budgetID", budgetID, "month", month, "categoryID", categoryID)
+
+	var res categoryResponse
+	err := c.do(ctx, "GetMonthCategory", http.MethodGet,
+		fmt.Sprintf(monthCategoryURL, c.baseULR, budgetID, month, categoryID),
+		nil, &res, "budgetID", budgetID, "month", month, "categoryID", categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got month category", "budgetID", budgetID, "month", month, "categoryID", categoryID)
+	return &res.Data.Category, nil
+}
+
+// SetCurrentMonthBudgeted sets amount budgeted for the category in the current month and returns updated category.
+func (c *Client) SetCurrentMonthBudgeted(
+	ctx context.Context, budgetID, categoryID string, budgeted int,
+) (*Category, error) {
+	c.log.Debugw("setting budgeted", "budgetID", budgetID, "categoryID", categoryID, "budgeted", budgeted)
+
+	body := map[string]interface{}{"category": map[string]int{"budgeted": budgeted}}
+	var res categoryResponse
+	err := c.do(ctx, "SetMonthCategoryBudgeted", http.MethodPatch,
+		fmt.Sprintf(monthCategoryURL, c.baseULR, budgetID, currentMonth, categoryID),
+		body, &res, "budgetID", budgetID, "categoryID", categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("set budgeted", "budgetID", budgetID, "categoryID", categoryID, "budgeted", budgeted)
+	return &res.Data.Category, nil
+}
+
+// GetCategories returns all category groups of the budget with their categories.
+func (c *Client) GetCategories(ctx context.Context, budgetID string) ([]CategoryGroup, error) {
+	c.log.Debugw("getting categories", "budgetID", budgetID)
+
+	var res categoriesResponse
+	err := c.do(ctx, "GetCategories", http.MethodGet, fmt.Sprintf(getCategoriesURL, c.baseULR, budgetID),
+		nil, &res, "budgetID", budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got categories", "budgetID", budgetID, "groups", len(res.Data.CategoryGroups))
+	return res.Data.CategoryGroups, nil
+}
+
+// GetCategoriesDelta returns category groups with categories changed after lastKnowledge of server and current
+// knowledge of server to be passed with the next request. All categories are returned if lastKnowledge is 0.
+func (c *Client) GetCategoriesDelta(
+	ctx context.Context, budgetID string, lastKnowledge int64,
+) ([]CategoryGroup, int64, error) {
+	c.log.Debugw("getting categories delta", "budgetID", budgetID, "lastKnowledge", lastKnowledge)
+
+	var res categoriesResponse
+	err := c.do(ctx, "GetCategoriesDelta", http.MethodGet,
+		fmt.Sprintf(getCategoriesDelta, c.baseULR, budgetID, lastKnowledge),
+		nil, &res, "budgetID", budgetID, "lastKnowledge", lastKnowledge)
 	if err != nil {
-		c.log.Errorw("can't create request", "budgetID", budgetID, "categoryID", categoryID, "error", err)
-		return nil, fmt.Errorf("can't create request: %w", err)
+		return nil, 0, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	c.log.Debugw("got categories delta",
+		"budgetID", budgetID, "groups", len(res.Data.CategoryGroups), "knowledge", res.Data.ServerKnowledge)
+	return res.Data.CategoryGroups, res.Data.ServerKnowledge, nil
+}
+
+// GetCategoryTransactions returns transactions of the category made on or after since date.
+func (c *Client) GetCategoryTransactions(
+	ctx context.Context, budgetID, categoryID string, since time.Time,
+) ([]Transaction, error) {
+	sinceDate := since.Format(monthLayout)
+	c.log.Debugw("getting category transactions", "budgetID", budgetID, "categoryID", categoryID, "since", sinceDate)
+
+	var res transactionsResponse
+	err := c.do(ctx, "GetCategoryTransactions", http.MethodGet,
+		fmt.Sprintf(getCategoryTxsURL, c.baseULR, budgetID, categoryID, sinceDate),
+		nil, &res, "budgetID", budgetID, "categoryID", categoryID, "since", sinceDate)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got category transactions",
+		"budgetID", budgetID, "categoryID", categoryID, "transactions", len(res.Data.Transactions))
+	return res.Data.Transactions, nil
+}
+
+// GetTransactions returns transactions of all accounts of the budget made on or after since date.
+func (c *Client) GetTransactions(ctx context.Context, budgetID string, since time.Time) ([]Transaction, error) {
+	sinceDate := since.Format(monthLayout)
+	c.log.Debugw("getting transactions", "budgetID", budgetID, "since", sinceDate)
+
+	var res transactionsResponse
+	err := c.do(ctx, "GetTransactions", http.MethodGet,
+		fmt.Sprintf(getTransactionsURL, c.baseULR, budgetID, sinceDate),
+		nil, &res, "budgetID", budgetID, "since", sinceDate)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got transactions", "budgetID", budgetID, "transactions", len(res.Data.Transactions))
+	return res.Data.Transactions, nil
+}
+
+// GetTransactionsDelta returns transactions of all accounts of the budget made on or after since date which changed
+// after lastKnowledge of server, and current knowledge of server to be passed with the next request.
+// Deleted transactions are returned with Deleted set. All transactions are returned if lastKnowledge is 0.
+func (c *Client) GetTransactionsDelta(
+	ctx context.Context, budgetID string, since time.Time, lastKnowledge int64,
+) ([]Transaction, int64, error) {
+	sinceDate := since.Format(monthLayout)
+	c.log.Debugw("getting transactions delta", "budgetID", budgetID, "since", sinceDate, "lastKnowledge", lastKnowledge)
+
+	var res transactionsResponse
+	err := c.do(ctx, "GetTransactionsDelta", http.MethodGet,
+		fmt.Sprintf(getTxsDeltaURL, c.baseULR, budgetID, sinceDate, lastKnowledge),
+		nil, &res, "budgetID", budgetID, "since", sinceDate, "lastKnowledge", lastKnowledge)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.log.Debugw("got transactions delta",
+		"budgetID", budgetID, "transactions", len(res.Data.Transactions), "knowledge", res.Data.ServerKnowledge)
+	return res.Data.Transactions, res.Data.ServerKnowledge, nil
+}
+
+// GetTransaction returns the transaction by ID. Deleted transaction is returned with Deleted set.
+func (c *Client) GetTransaction(ctx context.Context, budgetID, transactionID string) (*Transaction, error) {
+	c.log.Debugw("getting transaction", "budgetID", budgetID, "transactionID", transactionID)
+
+	var res transactionResponse
+	err := c.do(ctx, "GetTransaction", http.MethodGet,
+		fmt.Sprintf(transactionURL, c.baseULR, budgetID, transactionID),
+		nil, &res, "budgetID", budgetID, "transactionID", transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got transaction", "budgetID", budgetID, "transactionID", transactionID)
+	return &res.Data.Transaction, nil
+}
+
+// CreateTransaction creates transaction and returns it as saved by YNAB.
+func (c *Client) CreateTransaction(ctx context.Context, budgetID string, tx NewTransaction) (*Transaction, error) {
+	c.log.Debugw("creating transaction", "budgetID", budgetID, "accountID", tx.AccountID, "amount", tx.Amount)
+
+	body := map[string]interface{}{"transaction": tx}
+	var res transactionResponse
+	err := c.do(ctx, "CreateTransaction", http.MethodPost, fmt.Sprintf(transactionsURL, c.baseULR, budgetID),
+		body, &res, "budgetID", budgetID, "accountID", tx.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("created transaction", "budgetID", budgetID, "transactionID", res.Data.Transaction.ID)
+	return &res.Data.Transaction, nil
+}
+
+// UpdateTransaction replaces the transaction with tx and returns it as saved by YNAB.
+func (c *Client) UpdateTransaction(
+	ctx context.Context, budgetID, transactionID string, tx NewTransaction,
+) (*Transaction, error) {
+	c.log.Debugw("updating transaction", "budgetID", budgetID, "transactionID", transactionID)
+
+	body := map[string]interface{}{"transaction": tx}
+	var res transactionResponse
+	err := c.do(ctx, "UpdateTransaction", http.MethodPut,
+		fmt.Sprintf(transactionURL, c.baseULR, budgetID, transactionID),
+		body, &res, "budgetID", budgetID, "transactionID", transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("updated transaction", "budgetID", budgetID, "transactionID", transactionID)
+	return &res.Data.Transaction, nil
+}
+
+// UpdateTransactionFields changes only the fields set in upd and returns the transaction as saved by YNAB.
+func (c *Client) UpdateTransactionFields(
+	ctx context.Context, budgetID, transactionID string, upd TransactionUpdate,
+) (*Transaction, error) {
+	c.log.Debugw("updating transaction fields", "budgetID", budgetID, "transactionID", transactionID)
+
+	body := map[string]interface{}{"transaction": upd}
+	var res transactionResponse
+	err := c.do(ctx, "UpdateTransactionFields", http.MethodPut,
+		fmt.Sprintf(transactionURL, c.baseULR, budgetID, transactionID),
+		body, &res, "budgetID", budgetID, "transactionID", transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("updated transaction fields", "budgetID", budgetID, "transactionID", transactionID)
+	return &res.Data.Transaction, nil
+}
+
+// DeleteTransaction deletes the transaction.
+func (c *Client) DeleteTransaction(ctx context.Context, budgetID, transactionID string) error {
+	c.log.Debugw("deleting transaction", "budgetID", budgetID, "transactionID", transactionID)
+
+	var res transactionResponse
+	err := c.do(ctx, "DeleteTransaction", http.MethodDelete,
+		fmt.Sprintf(transactionURL, c.baseULR, budgetID, transactionID),
+		nil, &res, "budgetID", budgetID, "transactionID", transactionID)
+	if err != nil {
+		return err
+	}
+
+	c.log.Debugw("deleted transaction", "budgetID", budgetID, "transactionID", transactionID)
+	return nil
+}
+
+// GetAccounts returns all accounts of the budget including closed ones.
+func (c *Client) GetAccounts(ctx context.Context, budgetID string) ([]Account, error) {
+	c.log.Debugw("getting accounts", "budgetID", budgetID)
+
+	var res accountsResponse
+	err := c.do(ctx, "GetAccounts", http.MethodGet, fmt.Sprintf(getAccountsURL, c.baseULR, budgetID),
+		nil, &res, "budgetID", budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debugw("got accounts", "budgetID", budgetID, "accounts", len(res.Data.Accounts))
+	return res.Data.Accounts, nil
+}
+
+// do sends request with JSON encoded body (if any) and decodes JSON response into res.
+// endpoint names the request for observers. keysAndValues are only used for logging.
+func (c *Client) do(
+	ctx context.Context, endpoint, method, url string, body, res interface{}, keysAndValues ...interface{},
+) error {
+	started := time.Now()
+	statusCode, err := c.send(ctx, method, url, body, res, keysAndValues...)
+	for _, o := range c.observers {
+		o.ObserveRequest(endpoint, statusCode, time.Since(started), err)
+	}
+
+	return err
+}
+
+func (c *Client) send(
+	ctx context.Context, method, url string, body, res interface{}, keysAndValues ...interface{},
+) (int, error) {
+	if !c.limiter.Allow() {
+		c.log.Warnw("rate limit exceeded", keysAndValues...)
+		return 0, ErrRateLimited
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.log.Errorw("can't encode request", append(keysAndValues, "error", err)...)
+			return 0, fmt.Errorf("can't encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		c.log.Errorw("can't create request", append(keysAndValues, "error", err)...)
+		return 0, fmt.Errorf("can't create request: %w", err)
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		c.log.Errorw("can't get access token", append(keysAndValues, "error", err)...)
+		return 0, fmt.Errorf("can't get access token: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		c.log.Errorw("can't do request", "budgetID", budgetID, "categoryID", categoryID, "error", err)
-		return nil, fmt.Errorf("can't do request: %w", err)
+		c.log.Errorw("can't do request", append(keysAndValues, "error", err)...)
+		return 0, fmt.Errorf("can't do request: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return c.handleErrorResponse(resp, budgetID, categoryID)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, c.handleErrorResponse(resp, keysAndValues...)
 	}
 
-	var res categoryResponse
-
-	err = json.NewDecoder(resp.Body).Decode(&res)
-	if err != nil {
-		c.log.Errorw("can't decode response", "budgetID", budgetID, "categoryID", categoryID, "error", err)
-		return nil, fmt.Errorf("can't decode response: %w", err)
+	if res == nil {
+		return resp.StatusCode, nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		c.log.Errorw("can't decode response", append(keysAndValues, "error", err)...)
+		return resp.StatusCode, fmt.Errorf("can't decode response: %w", err)
 	}
 
-	c.log.Debugw("got category", "budgetID", budgetID, "categoryID", categoryID)
-	return &res.Data.Category, nil
+	return resp.StatusCode, nil
 }
 
-func (c *Client) handleErrorResponse(resp *http.Response, budgetID string, categoryID string) (*Category, error) {
+func (c *Client) handleErrorResponse(resp *http.Response, keysAndValues ...interface{}) error {
 	if resp.StatusCode == http.StatusNotFound {
-		c.log.Debugw("categoryID not found", "budgetID", budgetID, "categoryID", categoryID)
-		return nil, ErrNotFound
+		c.log.Debugw("not found", keysAndValues...)
+		return ErrNotFound
 	}
 	if resp.StatusCode == http.StatusUnauthorized {
-		c.log.Debugw("unauthorized", "budgetID", budgetID, "categoryID", categoryID)
-		return nil, ErrUnauthorized
+		c.log.Debugw("unauthorized", keysAndValues...)
+		return ErrUnauthorized
 	}
 	if resp.StatusCode == http.StatusForbidden {
-		c.log.Debugw("forbidden", "budgetID", budgetID, "categoryID", categoryID)
-		return nil, ErrForbidden
+		c.log.Debugw("forbidden", keysAndValues...)
+		return ErrForbidden
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.log.Warnw("rate limited by YNAB", keysAndValues...)
+		return ErrRateLimited
+	}
+	if resp.StatusCode == http.StatusConflict {
+		c.log.Debugw("conflict", keysAndValues...)
+		return ErrConflict
 	}
 
+	payload, _ := io.ReadAll(resp.Body)
 	c.log.Warnw("unexpected status code",
-		"budgetID", budgetID, "categoryID", categoryID, "statusCode", resp.StatusCode, "payload", resp.Body,
+		append(keysAndValues, "statusCode", resp.StatusCode, "payload", string(payload))...,
 	)
 
-	return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 }
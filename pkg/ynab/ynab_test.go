@@ -7,12 +7,14 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab/ynabtest"
 )
 
 func TestClient_GetCategory(t *testing.T) {
@@ -98,13 +100,30 @@ func TestClient_GetCategory(t *testing.T) {
 				return assert.ErrorIsf(t, err, ynab.ErrNotFound, "GetCategory(ctx, %s, %s)", "1234", "5678")
 			},
 		},
+		{
+			name: "too_many_requests",
+			fields: fields{
+				token: "token",
+				handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+			},
+			args: args{
+				budgetID:   "1234",
+				categoryID: "5678",
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIsf(t, err, ynab.ErrRateLimited, "GetCategory(ctx, %s, %s)", "1234", "5678")
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server := httptest.NewServer(tt.fields.handlerFunc)
 			defer server.Close()
 
-			c := ynab.NewClient(server.URL, tt.fields.token, zap.NewNop().Sugar())
+			c := ynab.NewClient(server.URL, ynab.StaticToken(tt.fields.token), zap.NewNop().Sugar())
 			got, err := c.GetCategory(context.Background(), tt.args.budgetID, tt.args.categoryID)
 			if !tt.wantErr(t, err, fmt.Sprintf("GetCategory(ctx, %s, %s)", tt.args.budgetID, tt.args.categoryID)) {
 				return
@@ -114,11 +133,251 @@ func TestClient_GetCategory(t *testing.T) {
 	}
 }
 
+func TestClient_GetCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/budgets/1234/categories" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"category_groups": [
+			{"id": "g1", "name": "Everyday", "categories": [
+				{"id": "c1", "name": "Groceries", "budgeted": 100, "activity": -60, "balance": 40},
+				{"id": "c2", "name": "Old", "hidden": true}
+			]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	c := ynab.NewClient(server.URL, ynab.StaticToken("token"), zap.NewNop().Sugar())
+	got, err := c.GetCategories(context.Background(), "1234")
+	require.NoError(t, err)
+	assert.Equal(t, []ynab.CategoryGroup{
+		{
+			ID:   "g1",
+			Name: "Everyday",
+			Categories: []ynab.Category{
+				{ID: "c1", Name: "Groceries", Budgeted: 100, Activity: -60, Balance: 40},
+				{ID: "c2", Name: "Old", Hidden: true},
+			},
+		},
+	}, got)
+}
+
+func TestClient_GetCategoriesDelta(t *testing.T) {
+	server := ynabtest.NewServer(ynabtest.DefaultFixture())
+	c := ynab.NewClient(ynabtest.Start(t, server), ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	groups, knowledge, err := c.GetCategoriesDelta(context.Background(), "budget-1", 0)
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, server.Knowledge(), knowledge)
+
+	groups, same, err := c.GetCategoriesDelta(context.Background(), "budget-1", knowledge)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	assert.Equal(t, knowledge, same)
+
+	require.NoError(t, server.SetCategory("budget-1", ynab.Category{
+		ID: "category-transport", Name: "Transport", Budgeted: 200000, Activity: -70000, Balance: 130000,
+	}))
+	groups, changed, err := c.GetCategoriesDelta(context.Background(), "budget-1", knowledge)
+	require.NoError(t, err)
+	assert.Greater(t, changed, knowledge)
+	require.Len(t, groups, 1)
+	assert.Equal(t, []ynab.Category{
+		{ID: "category-transport", Name: "Transport", Budgeted: 200000, Activity: -70000, Balance: 130000},
+	}, groups[0].Categories)
+}
+
+func TestClient_GetMonthCategory(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	got, err := c.GetMonthCategory(context.Background(), "budget-1",
+		time.Date(2024, time.April, 17, 0, 0, 0, 0, time.UTC), "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, &ynab.Category{
+		ID: "category-groceries", Name: "Groceries", Budgeted: 1000000, Activity: -950000, Balance: 50000,
+	}, got)
+
+	_, err = c.GetMonthCategory(context.Background(), "budget-1", time.Now().AddDate(-10, 0, 0), "category-groceries")
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+}
+
+func TestClient_SetCurrentMonthBudgeted(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	got, err := c.SetCurrentMonthBudgeted(ctx, "budget-1", "category-groceries", 1200000)
+	require.NoError(t, err)
+	assert.Equal(t, &ynab.Category{
+		ID: "category-groceries", Name: "Groceries", Budgeted: 1200000, Activity: -420000, Balance: 780000,
+	}, got)
+
+	got, err = c.GetCurrentMonthCategory(ctx, "budget-1", "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, 1200000, got.Budgeted)
+	cat, err := c.GetCategory(ctx, "budget-1", "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, 780000, cat.Balance, "category of the current month is updated")
+
+	_, err = c.SetCurrentMonthBudgeted(ctx, "budget-1", "category-unknown", 1)
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+}
+
+func TestClient_GetCategoryTransactions(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	got, err := c.GetCategoryTransactions(context.Background(), "budget-1", "category-groceries",
+		time.Date(2024, time.May, 3, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []ynab.Transaction{{
+		ID: "tx-3", Date: "2024-05-05", Amount: -170000, Cleared: "uncleared", Approved: true,
+		AccountID: "account-card", AccountName: "Card", PayeeID: "payee-silpo", PayeeName: "Silpo",
+		CategoryID: "category-groceries", CategoryName: "Groceries",
+	}}, got)
+
+	_, err = c.GetCategoryTransactions(context.Background(), "budget-1", "missing", time.Now())
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+}
+
+func TestClient_CreateTransaction(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	got, err := c.CreateTransaction(ctx, "budget-1", ynab.NewTransaction{
+		AccountID: "account-cash", Date: "2024-05-07", Amount: -65000, PayeeName: "Coffee",
+		CategoryID: "category-restaurants", Approved: true,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, got.ID)
+	assert.Equal(t, "Cash", got.AccountName)
+	assert.Equal(t, "Restaurants", got.CategoryName)
+
+	txs, err := c.GetTransactions(ctx, "budget-1", time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, "tx-4", txs[0].ID)
+	assert.Equal(t, got.ID, txs[1].ID)
+
+	_, err = c.CreateTransaction(ctx, "budget-1", ynab.NewTransaction{Date: "2024-05-07", Amount: -1})
+	assert.Error(t, err, "account is required")
+
+	tx := ynab.NewTransaction{AccountID: "account-cash", Date: "2024-05-07", Amount: -1000, ImportID: "bot:1:2"}
+	got, err = c.CreateTransaction(ctx, "budget-1", tx)
+	require.NoError(t, err)
+	assert.Equal(t, "bot:1:2", got.ImportID)
+	_, err = c.CreateTransaction(ctx, "budget-1", tx)
+	assert.ErrorIs(t, err, ynab.ErrConflict)
+}
+
+func TestClient_UpdateTransaction(t *testing.T) {
+	server := ynabtest.NewServer(ynabtest.DefaultFixture())
+	c := ynab.NewClient(ynabtest.Start(t, server), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+	imported, err := server.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: "2024-05-07", Amount: -65000, AccountID: "account-card", PayeeName: "COFFEE HOUSE",
+		ImportID: "YNAB:-65000:2024-05-07:1",
+	})
+	require.NoError(t, err)
+
+	got, err := c.UpdateTransaction(ctx, "budget-1", imported.ID, ynab.NewTransaction{
+		AccountID: "account-card", Date: "2024-05-07", Amount: -65000, PayeeName: "COFFEE HOUSE",
+		CategoryID: "category-restaurants", Memo: "latte", Approved: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ynab.Transaction{
+		ID: imported.ID, Date: "2024-05-07", Amount: -65000, Memo: "latte", Cleared: "uncleared", Approved: true,
+		AccountID: "account-card", AccountName: "Card", PayeeName: "COFFEE HOUSE",
+		CategoryID: "category-restaurants", CategoryName: "Restaurants", ImportID: "YNAB:-65000:2024-05-07:1",
+	}, *got)
+
+	_, err = c.UpdateTransaction(ctx, "budget-1", "missing", ynab.NewTransaction{AccountID: "account-card", Date: "2024-05-07"})
+	assert.Error(t, err)
+}
+
+func TestClient_UpdateTransactionFields(t *testing.T) {
+	server := ynabtest.NewServer(ynabtest.DefaultFixture())
+	c := ynab.NewClient(ynabtest.Start(t, server), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+	imported, err := server.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: "2024-05-07", Amount: -65000, Memo: "latte", Cleared: "cleared", Approved: true,
+		AccountID: "account-card", PayeeName: "COFFEE HOUSE", ImportID: "YNAB:-65000:2024-05-07:1",
+	})
+	require.NoError(t, err)
+
+	got, err := c.UpdateTransactionFields(ctx, "budget-1", imported.ID,
+		ynab.TransactionUpdate{CategoryID: "category-restaurants"})
+	require.NoError(t, err)
+	assert.Equal(t, ynab.Transaction{
+		ID: imported.ID, Date: "2024-05-07", Amount: -65000, Memo: "latte", Cleared: "cleared", Approved: true,
+		AccountID: "account-card", AccountName: "Card", PayeeName: "COFFEE HOUSE",
+		CategoryID: "category-restaurants", CategoryName: "Restaurants", ImportID: "YNAB:-65000:2024-05-07:1",
+	}, *got, "fields missing in the update are kept")
+
+	_, err = c.UpdateTransactionFields(ctx, "budget-1", "missing", ynab.TransactionUpdate{Memo: "latte"})
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+}
+
+func TestClient_GetTransaction(t *testing.T) {
+	server := ynabtest.NewServer(ynabtest.DefaultFixture())
+	c := ynab.NewClient(ynabtest.Start(t, server), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	got, err := c.GetTransaction(ctx, "budget-1", "tx-4")
+	require.NoError(t, err)
+	assert.Equal(t, "tx-4", got.ID)
+	assert.False(t, got.IsTransfer())
+
+	transfer, err := server.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: "2024-05-07", Amount: -100000, AccountID: "account-card", PayeeName: "Transfer : Cash",
+		TransferAccountID: "account-cash",
+	})
+	require.NoError(t, err)
+	got, err = c.GetTransaction(ctx, "budget-1", transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "account-cash", got.TransferAccountID)
+	assert.True(t, got.IsTransfer())
+
+	_, err = c.GetTransaction(ctx, "budget-1", "unknown")
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+}
+
+func TestClient_DeleteTransaction(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	require.NoError(t, c.DeleteTransaction(ctx, "budget-1", "tx-4"))
+	txs, err := c.GetTransactions(ctx, "budget-1", time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, txs, "deleted transactions are not listed")
+
+	assert.Error(t, c.DeleteTransaction(ctx, "budget-1", "tx-4"), "transaction is already deleted")
+}
+
+func TestClient_GetAccounts(t *testing.T) {
+	c := ynab.NewClient(ynabtest.Start(t, ynabtest.NewServer(ynabtest.DefaultFixture())),
+		ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	got, err := c.GetAccounts(context.Background(), "budget-1")
+	require.NoError(t, err)
+	assert.Equal(t, []ynab.Account{
+		{ID: "account-card", Name: "Card", Type: "checking", OnBudget: true},
+		{ID: "account-cash", Name: "Cash", Type: "cash", OnBudget: true},
+		{ID: "account-savings", Name: "Savings", Type: "savings", OnBudget: true, Closed: true},
+	}, got)
+}
+
 func TestClient_Manual(t *testing.T) {
 	t.Skipf("for manual run only")
 
 	log, _ := zap.NewDevelopment()
-	c := ynab.NewClient("https://api.ynab.com", os.Getenv("YNAB_ACCESS_TOKEN"), log.Sugar())
+	c := ynab.NewClient("https://api.ynab.com", ynab.StaticToken(os.Getenv("YNAB_ACCESS_TOKEN")), log.Sugar())
 
 	t.Run("GetCategory", func(t *testing.T) {
 		res, err := c.GetCategory(context.Background(), os.Getenv("YNAB_BUDGET_ID"), os.Getenv("YNAB_CATEGORY_ID"))
@@ -126,3 +385,34 @@ func TestClient_Manual(t *testing.T) {
 		t.Logf("%+v", res)
 	})
 }
+
+func TestClient_GetTransactionsDelta(t *testing.T) {
+	server := ynabtest.NewServer(ynabtest.DefaultFixture())
+	c := ynab.NewClient(ynabtest.Start(t, server), ynab.StaticToken("token"), zap.NewNop().Sugar())
+	ctx := context.Background()
+	since := time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)
+
+	txs, knowledge, err := c.GetTransactionsDelta(ctx, "budget-1", since, 0)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "tx-4", txs[0].ID)
+	assert.Equal(t, server.Knowledge(), knowledge)
+
+	txs, same, err := c.GetTransactionsDelta(ctx, "budget-1", since, knowledge)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+	assert.Equal(t, knowledge, same)
+
+	added, err := server.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: "2024-05-07", Amount: -65000, AccountID: "account-card", PayeeName: "COFFEE HOUSE",
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.DeleteTransaction(ctx, "budget-1", "tx-4"))
+	txs, changed, err := c.GetTransactionsDelta(ctx, "budget-1", since, knowledge)
+	require.NoError(t, err)
+	assert.Greater(t, changed, knowledge)
+	require.Len(t, txs, 2)
+	assert.Equal(t, "tx-4", txs[0].ID)
+	assert.True(t, txs[0].Deleted, "deleted transactions are returned by delta requests")
+	assert.Equal(t, added.ID, txs[1].ID)
+}
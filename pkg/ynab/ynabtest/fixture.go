@@ -0,0 +1,82 @@
+package ynabtest
+
+import (
+	_ "embed" // default fixture
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+//go:embed fixture.json
+var defaultFixture []byte
+
+// Fixture is the data served by Server.
+type Fixture struct {
+	Budgets []Budget `json:"budgets"`
+}
+
+type Budget struct {
+	ID             string               `json:"id"`
+	Name           string               `json:"name"`
+	CategoryGroups []ynab.CategoryGroup `json:"category_groups"`
+	Accounts       []ynab.Account       `json:"accounts"`
+	Months         []Month              `json:"months"`
+	Transactions   []Transaction        `json:"transactions"`
+}
+
+// Month is a budget month. Month is formatted as "2006-01-02" and is always the first day of the month.
+type Month struct {
+	Month        string          `json:"month"`
+	Income       int             `json:"income"`
+	Budgeted     int             `json:"budgeted"`
+	Activity     int             `json:"activity"`
+	ToBeBudgeted int             `json:"to_be_budgeted"`
+	Deleted      bool            `json:"deleted"`
+	Categories   []ynab.Category `json:"categories,omitempty"`
+}
+
+type Transaction struct {
+	ID           string `json:"id"`
+	Date         string `json:"date"`
+	Amount       int    `json:"amount"`
+	Memo         string `json:"memo,omitempty"`
+	Cleared      string `json:"cleared"`
+	Approved     bool   `json:"approved"`
+	AccountID    string `json:"account_id"`
+	AccountName  string `json:"account_name,omitempty"`
+	PayeeID      string `json:"payee_id,omitempty"`
+	PayeeName    string `json:"payee_name,omitempty"`
+	CategoryID   string `json:"category_id,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	ImportID     string `json:"import_id,omitempty"`
+	// TransferAccountID is set for transfers between accounts.
+	TransferAccountID string `json:"transfer_account_id,omitempty"`
+	Deleted           bool   `json:"deleted"`
+}
+
+// DefaultFixture returns a small budget with a few categories, accounts, months and transactions.
+func DefaultFixture() Fixture {
+	var f Fixture
+	if err := json.Unmarshal(defaultFixture, &f); err != nil {
+		panic(fmt.Sprintf("invalid default fixture: %v", err))
+	}
+
+	return f
+}
+
+// LoadFixture reads fixture from JSON file.
+func LoadFixture(path string) (Fixture, error) {
+	var f Fixture
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("reading fixture: %w", err)
+	}
+	if err = json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("decoding fixture: %w", err)
+	}
+
+	return f, nil
+}
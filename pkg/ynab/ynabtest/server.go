@@ -0,0 +1,721 @@
+// Package ynabtest provides a fake YNAB API server for development and tests.
+package ynabtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+)
+
+const (
+	monthLayout = "2006-01-02"
+	lastUsed    = "last-used"
+	current     = "current"
+)
+
+// Fault makes matching requests slow or failing.
+type Fault struct {
+	// Method and Path prefix of requests the fault applies to. Empty values match any request.
+	Method string
+	Path   string
+	// Latency is added before request is handled.
+	Latency time.Duration
+	// Status is returned instead of handling request, e.g. http.StatusTooManyRequests. Zero handles request.
+	Status int
+	// Times is how many requests the fault applies to. Zero applies it to all requests.
+	Times int
+}
+
+// Server serves budgets, categories, months and transactions from Fixture.
+// Writes made through the API or Server methods are kept in memory and increase server knowledge,
+// so delta requests with last_knowledge_of_server return only changed entities.
+type Server struct {
+	mu        sync.Mutex
+	token     string
+	now       func() time.Time
+	budgets   []*budget
+	knowledge int64
+	nextID    int
+	faults    []*Fault
+	requests  []string
+}
+
+type budget struct {
+	Budget
+	// changed is the server knowledge of the last change of the entity by its key.
+	changed map[string]int64
+}
+
+type route struct {
+	method  string
+	pattern []string
+	handle  func(w http.ResponseWriter, r *http.Request, b *budget, params []string)
+}
+
+func NewServer(f Fixture) *Server {
+	s := &Server{now: time.Now, knowledge: 1}
+	for _, b := range f.Budgets {
+		s.budgets = append(s.budgets, &budget{Budget: b, changed: make(map[string]int64)})
+	}
+
+	return s
+}
+
+// Start serves s with httptest.Server which is closed when test finishes. It returns base URL of the API.
+func Start(tb testing.TB, s *Server) string {
+	tb.Helper()
+
+	server := httptest.NewServer(s)
+	tb.Cleanup(server.Close)
+
+	return server.URL
+}
+
+// WithToken makes server reject requests without the access token. Any token is accepted by default.
+func (s *Server) WithToken(token string) *Server {
+	s.token = token
+	return s
+}
+
+// WithClock replaces the clock used to resolve the current month.
+func (s *Server) WithClock(now func() time.Time) *Server {
+	s.now = now
+	return s
+}
+
+// InjectFault adds fault applied to matching requests. Faults are checked in the order they are added.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns method and path of all handled requests, e.g. "GET /v1/budgets".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// Knowledge returns current server knowledge.
+func (s *Server) Knowledge() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.knowledge
+}
+
+// SetCategory replaces category of the budget by its ID.
+func (s *Server) SetCategory(budgetID string, c ynab.Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.budget(budgetID)
+	if b == nil {
+		return fmt.Errorf("budget %q: %w", budgetID, ynab.ErrNotFound)
+	}
+	existing := b.category(c.ID)
+	if existing == nil {
+		return fmt.Errorf("category %q: %w", c.ID, ynab.ErrNotFound)
+	}
+	*existing = c
+	s.change(b, "category:"+c.ID)
+
+	return nil
+}
+
+// AddTransaction adds transaction to the budget and updates activity of its category.
+func (s *Server) AddTransaction(budgetID string, tx Transaction) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.budget(budgetID)
+	if b == nil {
+		return tx, fmt.Errorf("budget %q: %w", budgetID, ynab.ErrNotFound)
+	}
+
+	return s.addTransaction(b, tx), nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fault := s.fault(r)
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault.Status != 0 {
+		writeError(w, fault.Status)
+		return
+	}
+
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" || parts[1] != "budgets" {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed)
+			return
+		}
+		s.listBudgets(w)
+		return
+	}
+
+	b := s.budget(parts[2])
+	if b == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	for _, rt := range s.routes() {
+		params, ok := match(rt.pattern, parts[3:])
+		if ok && rt.method == r.Method {
+			rt.handle(w, r, b, params)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound)
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{http.MethodGet, []string{"categories"}, s.getCategories},
+		{http.MethodGet, []string{"categories", "*"}, s.getCategory},
+		{http.MethodGet, []string{"categories", "*", "transactions"}, s.getCategoryTransactions},
+		{http.MethodGet, []string{"months"}, s.getMonths},
+		{http.MethodGet, []string{"months", "*"}, s.getMonth},
+		{http.MethodGet, []string{"months", "*", "categories", "*"}, s.getMonthCategory},
+		{http.MethodPatch, []string{"months", "*", "categories", "*"}, s.patchMonthCategory},
+		{http.MethodGet, []string{"accounts"}, s.getAccounts},
+		{http.MethodGet, []string{"transactions"}, s.getTransactions},
+		{http.MethodPost, []string{"transactions"}, s.postTransaction},
+		{http.MethodGet, []string{"transactions", "*"}, s.getTransaction},
+		{http.MethodPut, []string{"transactions", "*"}, s.putTransaction},
+		{http.MethodDelete, []string{"transactions", "*"}, s.deleteTransaction},
+	}
+}
+
+// fault records request and returns the first matching fault. Zero fault is returned if none matches.
+func (s *Server) fault(r *http.Request) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != r.Method) || !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		res := *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return res
+	}
+
+	return Fault{}
+}
+
+func (s *Server) listBudgets(w http.ResponseWriter) {
+	type budgetSummary struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		FirstMonth string `json:"first_month,omitempty"`
+		LastMonth  string `json:"last_month,omitempty"`
+	}
+
+	budgets := make([]budgetSummary, 0, len(s.budgets))
+	for _, b := range s.budgets {
+		summary := budgetSummary{ID: b.ID, Name: b.Name}
+		if len(b.Months) > 0 {
+			summary.FirstMonth = b.Months[0].Month
+			summary.LastMonth = b.Months[len(b.Months)-1].Month
+		}
+		budgets = append(budgets, summary)
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"budgets": budgets, "default_budget": nil})
+}
+
+func (s *Server) getCategories(w http.ResponseWriter, r *http.Request, b *budget, _ []string) {
+	since, ok := lastKnowledge(w, r)
+	if !ok {
+		return
+	}
+
+	groups := make([]ynab.CategoryGroup, 0, len(b.CategoryGroups))
+	for _, g := range b.CategoryGroups {
+		categories := make([]ynab.Category, 0, len(g.Categories))
+		for _, c := range g.Categories {
+			if b.changedSince("category:"+c.ID, since) {
+				categories = append(categories, c)
+			}
+		}
+		if len(categories) == 0 && since != 0 {
+			continue
+		}
+		g.Categories = categories
+		groups = append(groups, g)
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"category_groups": groups, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) getCategory(w http.ResponseWriter, _ *http.Request, b *budget, params []string) {
+	c := b.category(params[0])
+	if c == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"category": c})
+}
+
+func (s *Server) getMonths(w http.ResponseWriter, r *http.Request, b *budget, _ []string) {
+	since, ok := lastKnowledge(w, r)
+	if !ok {
+		return
+	}
+
+	months := make([]Month, 0, len(b.Months))
+	for _, m := range b.Months {
+		if b.changedSince("month:"+m.Month, since) {
+			m.Categories = nil
+			months = append(months, m)
+		}
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"months": months, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) getMonth(w http.ResponseWriter, _ *http.Request, b *budget, params []string) {
+	m := b.month(s.resolveMonth(b, params[0]))
+	if m == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"month": m})
+}
+
+func (s *Server) getMonthCategory(w http.ResponseWriter, _ *http.Request, b *budget, params []string) {
+	c := b.monthCategory(s.resolveMonth(b, params[0]), params[1])
+	if c == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"category": c})
+}
+
+// patchMonthCategory updates budgeted amount of the category in the month.
+// Categories of the current month are updated in category groups too.
+func (s *Server) patchMonthCategory(w http.ResponseWriter, r *http.Request, b *budget, params []string) {
+	var req struct {
+		Category struct {
+			Budgeted *int `json:"budgeted"`
+		} `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Category.Budgeted == nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	month := s.resolveMonth(b, params[0])
+	m := b.month(month)
+	c := b.monthCategory(month, params[1])
+	if c == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delta := *req.Category.Budgeted - c.Budgeted
+	c.Budgeted += delta
+	c.Balance += delta
+	m.Budgeted += delta
+	m.ToBeBudgeted -= delta
+	s.change(b, "month:"+month)
+	if month == s.currentMonth(b) {
+		if gc := b.category(c.ID); gc != nil {
+			gc.Budgeted += delta
+			gc.Balance += delta
+			s.change(b, "category:"+c.ID)
+		}
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"category": c, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) getAccounts(w http.ResponseWriter, _ *http.Request, b *budget, _ []string) {
+	accounts := append(make([]ynab.Account, 0, len(b.Accounts)), b.Accounts...)
+
+	writeData(w, http.StatusOK, map[string]interface{}{"accounts": accounts, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) getTransactions(w http.ResponseWriter, r *http.Request, b *budget, _ []string) {
+	s.writeTransactions(w, r, b, func(Transaction) bool { return true })
+}
+
+func (s *Server) getCategoryTransactions(w http.ResponseWriter, r *http.Request, b *budget, params []string) {
+	if c := b.category(params[0]); c == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	s.writeTransactions(w, r, b, func(tx Transaction) bool { return tx.CategoryID == params[0] })
+}
+
+// writeTransactions writes transactions matching filter and since_date, type and last_knowledge_of_server params.
+func (s *Server) writeTransactions(w http.ResponseWriter, r *http.Request, b *budget, filter func(Transaction) bool) {
+	since, ok := lastKnowledge(w, r)
+	if !ok {
+		return
+	}
+	sinceDate := r.URL.Query().Get("since_date")
+	txType := r.URL.Query().Get("type")
+
+	res := make([]Transaction, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		switch {
+		case !filter(tx),
+			!b.changedSince("transaction:"+tx.ID, since),
+			since == 0 && tx.Deleted,
+			tx.Date < sinceDate,
+			txType == "uncategorized" && tx.CategoryID != "",
+			txType == "unapproved" && tx.Approved:
+			continue
+		}
+		res = append(res, tx)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Date < res[j].Date })
+
+	writeData(w, http.StatusOK, map[string]interface{}{"transactions": res, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, _ *http.Request, b *budget, params []string) {
+	i := b.transaction(params[0])
+	if i < 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeData(w, http.StatusOK, map[string]interface{}{"transaction": b.Transactions[i]})
+}
+
+func (s *Server) postTransaction(w http.ResponseWriter, r *http.Request, b *budget, _ []string) {
+	tx, ok := decodeTransaction(w, r, Transaction{})
+	if !ok {
+		return
+	}
+	if tx.ImportID != "" && b.imported(tx.AccountID, tx.ImportID) {
+		writeError(w, http.StatusConflict)
+		return
+	}
+
+	tx = s.addTransaction(b, tx)
+	writeData(w, http.StatusCreated, map[string]interface{}{
+		"transaction_ids":  []string{tx.ID},
+		"transaction":      tx,
+		"server_knowledge": s.knowledge,
+	})
+}
+
+func (s *Server) putTransaction(w http.ResponseWriter, r *http.Request, b *budget, params []string) {
+	i := b.transaction(params[0])
+	if i < 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	tx, ok := decodeTransaction(w, r, b.Transactions[i])
+	if !ok {
+		return
+	}
+
+	s.applyTransaction(b, b.Transactions[i], -1)
+	tx.ID = params[0]
+	tx.ImportID = b.Transactions[i].ImportID // YNAB keeps import ID of imported transactions
+	tx.CategoryName = b.categoryName(tx.CategoryID)
+	tx.AccountName = b.accountName(tx.AccountID)
+	b.Transactions[i] = tx
+	s.applyTransaction(b, tx, 1)
+	s.change(b, "transaction:"+tx.ID)
+
+	writeData(w, http.StatusOK, map[string]interface{}{"transaction": tx, "server_knowledge": s.knowledge})
+}
+
+func (s *Server) deleteTransaction(w http.ResponseWriter, _ *http.Request, b *budget, params []string) {
+	i := b.transaction(params[0])
+	if i < 0 || b.Transactions[i].Deleted {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	s.applyTransaction(b, b.Transactions[i], -1)
+	b.Transactions[i].Deleted = true
+	s.change(b, "transaction:"+params[0])
+
+	writeData(w, http.StatusOK, map[string]interface{}{
+		"transaction": b.Transactions[i], "server_knowledge": s.knowledge,
+	})
+}
+
+func (s *Server) addTransaction(b *budget, tx Transaction) Transaction {
+	if tx.ID == "" {
+		s.nextID++
+		tx.ID = fmt.Sprintf("fake-tx-%d", s.nextID)
+	}
+	if tx.Cleared == "" {
+		tx.Cleared = "uncleared"
+	}
+	tx.CategoryName = b.categoryName(tx.CategoryID)
+	if tx.AccountName == "" {
+		tx.AccountName = b.accountName(tx.AccountID)
+	}
+
+	b.Transactions = append(b.Transactions, tx)
+	s.applyTransaction(b, tx, 1)
+	s.change(b, "transaction:"+tx.ID)
+
+	return tx
+}
+
+// applyTransaction adds (sign 1) or removes (sign -1) transaction amount to activity of its category and month.
+func (s *Server) applyTransaction(b *budget, tx Transaction, sign int) {
+	if tx.Deleted || tx.CategoryID == "" || len(tx.Date) < len(monthLayout) {
+		return
+	}
+
+	amount := sign * tx.Amount
+	month := tx.Date[:len("2006-01")] + "-01"
+	if c := b.monthCategory(month, tx.CategoryID); c != nil {
+		c.Activity += amount
+		c.Balance += amount
+		b.month(month).Activity += amount
+		s.change(b, "month:"+month)
+	}
+	if month == s.currentMonth(b) {
+		if c := b.category(tx.CategoryID); c != nil {
+			c.Activity += amount
+			c.Balance += amount
+			s.change(b, "category:"+tx.CategoryID)
+		}
+	}
+}
+
+func (s *Server) change(b *budget, key string) {
+	s.knowledge++
+	b.changed[key] = s.knowledge
+}
+
+func (s *Server) budget(id string) *budget {
+	if id == lastUsed && len(s.budgets) > 0 {
+		return s.budgets[0]
+	}
+	for _, b := range s.budgets {
+		if b.ID == id {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// currentMonth returns the month of now if budget has it. Otherwise, the latest month of the budget is current,
+// so that fixtures made in the past stay consistent with categories served by categories endpoint.
+func (s *Server) currentMonth(b *budget) string {
+	now := s.now().Format("2006-01") + "-01"
+	if b.month(now) != nil || len(b.Months) == 0 {
+		return now
+	}
+
+	return b.Months[len(b.Months)-1].Month
+}
+
+func (s *Server) resolveMonth(b *budget, month string) string {
+	if month == current {
+		return s.currentMonth(b)
+	}
+
+	return month
+}
+
+func (b *budget) category(id string) *ynab.Category {
+	for i := range b.CategoryGroups {
+		g := &b.CategoryGroups[i]
+		for j := range g.Categories {
+			if g.Categories[j].ID == id {
+				return &g.Categories[j]
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *budget) categoryName(id string) string {
+	if c := b.category(id); c != nil {
+		return c.Name
+	}
+
+	return ""
+}
+
+func (b *budget) accountName(id string) string {
+	for _, a := range b.Accounts {
+		if a.ID == id {
+			return a.Name
+		}
+	}
+
+	return ""
+}
+
+func (b *budget) month(month string) *Month {
+	for i := range b.Months {
+		if b.Months[i].Month == month {
+			return &b.Months[i]
+		}
+	}
+
+	return nil
+}
+
+func (b *budget) monthCategory(month, categoryID string) *ynab.Category {
+	m := b.month(month)
+	if m == nil {
+		return nil
+	}
+	for i := range m.Categories {
+		if m.Categories[i].ID == categoryID {
+			return &m.Categories[i]
+		}
+	}
+
+	return nil
+}
+
+// imported reports whether the account has transaction with the import ID.
+func (b *budget) imported(accountID, importID string) bool {
+	for _, tx := range b.Transactions {
+		if tx.AccountID == accountID && tx.ImportID == importID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *budget) transaction(id string) int {
+	for i := range b.Transactions {
+		if b.Transactions[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// changedSince reports whether entity changed after the knowledge. Everything is changed since zero knowledge.
+func (b *budget) changedSince(key string, knowledge int64) bool {
+	return knowledge == 0 || b.changed[key] > knowledge
+}
+
+func match(pattern, parts []string) ([]string, bool) {
+	if len(pattern) != len(parts) {
+		return nil, false
+	}
+
+	var params []string
+	for i, p := range pattern {
+		switch p {
+		case "*":
+			params = append(params, parts[i])
+		case parts[i]:
+		default:
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func lastKnowledge(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	v := r.URL.Query().Get("last_knowledge_of_server")
+	if v == "" {
+		return 0, true
+	}
+
+	res, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return res, true
+}
+
+// decodeTransaction decodes transaction from request body over base, fields missing in the body are kept as in base.
+func decodeTransaction(w http.ResponseWriter, r *http.Request, base Transaction) (Transaction, bool) {
+	req := struct {
+		Transaction *Transaction `json:"transaction"`
+	}{Transaction: &base}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Transaction == nil ||
+		req.Transaction.AccountID == "" || req.Transaction.Date == "" {
+		writeError(w, http.StatusBadRequest)
+		return Transaction{}, false
+	}
+
+	return *req.Transaction, true
+}
+
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// writeError writes error in the format of YNAB API, e.g. {"error": {"id": "404", "name": "not_found"}}.
+func writeError(w http.ResponseWriter, status int) {
+	text := http.StatusText(status)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"id":     strconv.Itoa(status),
+			"name":   strings.ReplaceAll(strings.ToLower(text), " ", "_"),
+			"detail": text,
+		},
+	})
+}
@@ -0,0 +1,128 @@
+package ynabtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab"
+	"github.com/Roma7-7-7/ynab-notifier/pkg/ynab/ynabtest"
+)
+
+func TestServer_Categories(t *testing.T) {
+	s := ynabtest.NewServer(ynabtest.DefaultFixture()).WithToken("token")
+	url := ynabtest.Start(t, s)
+	client := ynab.NewClient(url, ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	cat, err := client.GetCategory(context.Background(), "last-used", "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, "Groceries", cat.Name)
+	assert.Equal(t, 580000, cat.Balance)
+
+	groups, err := client.GetCategories(context.Background(), "budget-1")
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+
+	_, err = client.GetCategory(context.Background(), "budget-1", "missing")
+	assert.ErrorIs(t, err, ynab.ErrNotFound)
+
+	unauthorized := ynab.NewClient(url, ynab.StaticToken("wrong"), zap.NewNop().Sugar())
+	_, err = unauthorized.GetCategory(context.Background(), "budget-1", "category-groceries")
+	assert.ErrorIs(t, err, ynab.ErrUnauthorized)
+}
+
+func TestServer_Delta(t *testing.T) {
+	s := ynabtest.NewServer(ynabtest.DefaultFixture()).WithClock(func() time.Time {
+		return time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	})
+	url := ynabtest.Start(t, s)
+
+	var txs struct {
+		Data struct {
+			Transactions    []ynabtest.Transaction `json:"transactions"`
+			ServerKnowledge int64                  `json:"server_knowledge"`
+		} `json:"data"`
+	}
+	get(t, url+"/v1/budgets/budget-1/transactions?since_date=2024-05-03", &txs)
+	assert.Len(t, txs.Data.Transactions, 3)
+	knowledge := txs.Data.ServerKnowledge
+
+	tx, err := s.AddTransaction("budget-1", ynabtest.Transaction{
+		Date: "2024-05-09", Amount: -80000, AccountID: "account-card", CategoryID: "category-groceries",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Groceries", tx.CategoryName)
+
+	get(t, fmt.Sprintf("%s/v1/budgets/budget-1/transactions?last_knowledge_of_server=%d", url, knowledge), &txs)
+	require.Len(t, txs.Data.Transactions, 1)
+	assert.Equal(t, tx.ID, txs.Data.Transactions[0].ID)
+	assert.Greater(t, txs.Data.ServerKnowledge, knowledge)
+
+	var categories struct {
+		Data struct {
+			CategoryGroups []ynab.CategoryGroup `json:"category_groups"`
+		} `json:"data"`
+	}
+	get(t, fmt.Sprintf("%s/v1/budgets/budget-1/categories?last_knowledge_of_server=%d", url, knowledge), &categories)
+	require.Len(t, categories.Data.CategoryGroups, 1)
+	require.Len(t, categories.Data.CategoryGroups[0].Categories, 1)
+	assert.Equal(t, 500000, categories.Data.CategoryGroups[0].Categories[0].Balance, "transaction is applied")
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch,
+		url+"/v1/budgets/budget-1/months/current/categories/category-groceries",
+		strings.NewReader(`{"category": {"budgeted": 1200000}}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client := ynab.NewClient(url, ynab.StaticToken("token"), zap.NewNop().Sugar())
+	cat, err := client.GetCategory(context.Background(), "budget-1", "category-groceries")
+	require.NoError(t, err)
+	assert.Equal(t, 1200000, cat.Budgeted)
+	assert.Equal(t, 700000, cat.Balance)
+}
+
+func TestServer_Faults(t *testing.T) {
+	s := ynabtest.NewServer(ynabtest.DefaultFixture())
+	url := ynabtest.Start(t, s)
+	client := ynab.NewClient(url, ynab.StaticToken("token"), zap.NewNop().Sugar())
+
+	s.InjectFault(ynabtest.Fault{Path: "/v1/budgets/budget-1/categories", Status: http.StatusTooManyRequests, Times: 1})
+	_, err := client.GetCategory(context.Background(), "budget-1", "category-groceries")
+	assert.ErrorIs(t, err, ynab.ErrRateLimited)
+	_, err = client.GetCategory(context.Background(), "budget-1", "category-groceries")
+	assert.NoError(t, err, "fault is applied once")
+
+	s.InjectFault(ynabtest.Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetCategory(ctx, "budget-1", "category-groceries")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	s.ClearFaults()
+	_, err = client.GetCategory(context.Background(), "budget-1", "category-groceries")
+	assert.NoError(t, err)
+	assert.Len(t, s.Requests(), 4)
+}
+
+func get(t *testing.T, url string, res interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+}